@@ -0,0 +1,186 @@
+package boltdb_go
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// backupChunkPages 是 WriteTo 每次写入的最大页面数量。
+const backupChunkPages = 256
+
+// WriteTo 将事务看到的数据库快照以文件格式写入 w，返回写入的字节数。
+// 两个 meta 页面都根据事务的快照重新生成，因此并发的提交不会影响输出的一致性。
+// 写事务中有未提交的修改时返回 InvalidArgumentError。
+// 写入过程中会检查事务的 context，context 被取消时返回 ctx.Err()。
+func (t *transaction) WriteTo(w io.Writer) (int64, error) {
+	if len(t.dirtyList) > 0 {
+		return 0, InvalidArgumentError
+	}
+	// 快照包含 meta 记录的所有页面，它们在事务开始时都已位于事务的内存映射中。
+	pages := t.nextPageNumber
+	if pages*t.db.pageSize > min(t.size, len(t.data)) {
+		return 0, PageNotFoundError
+	}
+
+	var written int64
+	buf := make([]byte, 2*t.db.pageSize)
+	for i := 0; i < 2; i++ {
+		p := t.db.page(buf, i)
+		p.id = pgno(i)
+		p.flags = p_meta
+		m := (*meta)(p.data())
+		*m = *t.db.meta()
+		m.free, m.main = t.metaBuckets[freeBucket], t.metaBuckets[mainBucket]
+		m.pgno = pages - 1
+		m.txnid = t.id
+	}
+	n, err := w.Write(buf)
+	written += int64(n)
+	if err != nil {
+		return written, err
+	}
+
+	// 快照中的页面在事务结束之前不会被复用，可以直接从内存映射中读取。
+	for id := 2; id < pages; id += backupChunkPages {
+		if err := t.Context().Err(); err != nil {
+			return written, err
+		}
+		end := min(id+backupChunkPages, pages)
+		n, err := w.Write(t.data[id*t.db.pageSize : end*t.db.pageSize])
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Restore 从 r 读取由 WriteTo 生成的数据库快照，并原子地替换当前数据库文件。
+// 快照先写入同一目录下的临时文件，验证 meta 页面后再通过重命名替换原文件，
+// 最后重新建立内存映射。Restore 期间写事务被阻塞，存在活动的只读事务时返回 DatabaseBusyError。
+// 其他进程不应同时打开该数据库。
+func (db *DB) Restore(r io.Reader) error {
+	if !db.opened {
+		return InvalidArgumentError
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(db.path), filepath.Base(db.path)+".restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	err = validateSnapshot(tmp)
+	tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return db.exclusive(func() error {
+		if err := os.Rename(tmp.Name(), db.path); err != nil {
+			return err
+		}
+		if dir, err := os.Open(filepath.Dir(db.path)); err == nil {
+			dir.Sync()
+			dir.Close()
+		}
+		return db.reopen()
+	})
+}
+
+// exclusive 持有写锁并在没有活动的只读事务时调用 fn，fn 执行期间不能开始新的只读事务。
+// 存在活动的只读事务时返回 DatabaseBusyError。
+func (db *DB) exclusive(fn func() error) error {
+	if err := db.lockWriter(context.Background(), db.timeout); err != nil {
+		return err
+	}
+	defer db.unlockWriter()
+	return db.withoutReaders(fn)
+}
+
+// withoutReaders 在没有活动的只读事务时调用 fn，fn 执行期间不能开始新的只读事务。
+func (db *DB) withoutReaders(fn func() error) error {
+	db.rmutex.Lock()
+	defer db.rmutex.Unlock()
+	for _, rd := range db.readers {
+		if rd.pid != 0 {
+			return DatabaseBusyError
+		}
+	}
+	return fn()
+}
+
+// reopen 关闭并重新打开数据文件，重新建立内存映射。调用方需要持有写锁。
+func (db *DB) reopen() error {
+	db.close()
+	var err error
+	if db.file, err = os.OpenFile(db.path, os.O_RDWR, 0); err != nil {
+		db.opened = false
+		return err
+	}
+	if db.metafile, err = os.OpenFile(db.path, os.O_RDWR|os.O_SYNC, 0); err != nil {
+		db.close()
+		db.opened = false
+		return err
+	}
+	// 旧文件上的写锁随文件关闭而释放，在新文件上重新获取。
+	// 其他进程在替换文件之后打开了数据库时可能获取失败，此时数据库不能继续使用。
+	if err = syscall.Flock(int(db.file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		db.close()
+		db.opened = false
+		if err == syscall.EWOULDBLOCK {
+			return WriterBusyError
+		}
+		return err
+	}
+
+	pageSize, _, errs := readMeta(db.file)
+	if pageSize == 0 {
+		db.close()
+		db.opened = false
+		return &Error{"meta error", errs[0]}
+	}
+	db.pageSize = pageSize
+	if err = db.mmap(); err != nil {
+		db.close()
+		db.opened = false
+		return err
+	}
+	if err = db.openReaders(0666); err != nil {
+		db.close()
+		db.opened = false
+		return err
+	}
+	return nil
+}
+
+// validateSnapshot 检查 f 是否为完整的数据库文件：至少一个 meta 页面有效，
+// 且文件包含有效 meta 中事务ID较大的那一个引用的所有页面。
+func validateSnapshot(f *os.File) error {
+	pageSize, metas, _ := readMeta(f)
+	m := metas[0]
+	if m == nil || metas[1] != nil && metas[1].txnid > m.txnid {
+		m = metas[1]
+	}
+	if m == nil {
+		return InvalidError
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < (int64(m.pgno)+1)*int64(pageSize) {
+		return InvalidError
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package boltdb_go
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 确保 WriteTo 导出的快照可以通过 Restore 恢复，恢复后数据库回到快照时的事务。
+func TestDB_Restore(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		txn, _ := db.Transaction(nil, 0)
+		id := txn.id
+		assert.NoError(t, txn.Commit())
+
+		var buf bytes.Buffer
+		txn, _ = db.Transaction(nil, ReadOnly)
+		n, err := txn.WriteTo(&buf)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(buf.Len()), n)
+		txn.Abort()
+
+		txn, _ = db.Transaction(nil, 0)
+		assert.NoError(t, txn.Commit())
+
+		// 存在活动的只读事务时不能恢复。
+		r, _ := db.Transaction(nil, ReadOnly)
+		assert.Equal(t, DatabaseBusyError, db.Restore(bytes.NewReader(buf.Bytes())))
+		r.Abort()
+
+		assert.NoError(t, db.Restore(&buf))
+		txn, err = db.Transaction(nil, ReadOnly)
+		assert.NoError(t, err)
+		assert.Equal(t, id, txn.id)
+		txn.Abort()
+	})
+}
+
+// 确保无效的快照被拒绝，且数据库保持可用。
+func TestDB_RestoreInvalid(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		assert.Equal(t, InvalidError, db.Restore(bytes.NewReader([]byte("not a database"))))
+
+		txn, err := db.Transaction(nil, 0)
+		assert.NoError(t, err)
+		assert.NoError(t, txn.Commit())
+	})
+}
+
+// 确保只有 meta1 有效的快照也可以恢复，并且恢复后的数据可以读取。
+func TestDB_RestoreMeta1(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		txn, _ := db.Transaction(nil, 0)
+		b, err := txn.Bucket("", 0)
+		assert.NoError(t, err)
+		assert.NoError(t, b.Put([]byte("foo"), []byte("bar"), 0))
+		// 写事务中有未提交的修改时不能导出。
+		_, err = txn.WriteTo(io.Discard)
+		assert.Equal(t, InvalidArgumentError, err)
+		assert.NoError(t, txn.Commit())
+
+		var buf bytes.Buffer
+		txn, _ = db.Transaction(nil, ReadOnly)
+		_, err = txn.WriteTo(&buf)
+		assert.NoError(t, err)
+		txn.Abort()
+
+		// 破坏 meta0 的 magic。
+		snapshot := buf.Bytes()
+		copy(snapshot[pageHeaderSize:pageHeaderSize+4], []byte{0, 0, 0, 0})
+		assert.NoError(t, db.Restore(bytes.NewReader(snapshot)))
+
+		txn, err = db.Transaction(nil, ReadOnly)
+		assert.NoError(t, err)
+		b, err = txn.Bucket("", 0)
+		assert.NoError(t, err)
+		value, err := b.Get([]byte("foo"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("bar"), value)
+		txn.Abort()
+
+		// 下一次提交覆盖无效的 meta0。
+		txn, _ = db.Transaction(nil, 0)
+		assert.NoError(t, txn.Commit())
+		assert.True(t, db.Info().Metas[0].Valid)
+		assert.True(t, db.Info().Metas[1].Valid)
+	})
+}
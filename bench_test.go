@@ -0,0 +1,233 @@
+package boltdb_go
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+)
+
+// benchBatchSize 是写入类基准测试中每个写事务包含的写入数量。
+const benchBatchSize = 1000
+
+func BenchmarkPutSequential(b *testing.B) {
+	withBenchDB(b, 0, func(db *DB) {
+		benchPut(b, db, sequentialKeys(b.N), make([]byte, 100))
+	})
+}
+
+// BenchmarkPutSequentialNoSync 与 BenchmarkPutSequential 相同，但提交时不同步数据文件。
+func BenchmarkPutSequentialNoSync(b *testing.B) {
+	withBenchDB(b, NoSync, func(db *DB) {
+		benchPut(b, db, sequentialKeys(b.N), make([]byte, 100))
+	})
+}
+
+func BenchmarkPutRandom(b *testing.B) {
+	withBenchDB(b, 0, func(db *DB) {
+		benchPut(b, db, randomKeys(b.N), make([]byte, 100))
+	})
+}
+
+func BenchmarkPutLargeValue(b *testing.B) {
+	withBenchDB(b, 0, func(db *DB) {
+		benchPut(b, db, sequentialKeys(b.N), make([]byte, 64<<10))
+	})
+}
+
+func BenchmarkGetSequential(b *testing.B) {
+	withBenchDB(b, 0, func(db *DB) {
+		benchGet(b, db, sequentialKeys(b.N))
+	})
+}
+
+func BenchmarkGetRandom(b *testing.B) {
+	withBenchDB(b, 0, func(db *DB) {
+		benchGet(b, db, randomKeys(b.N))
+	})
+}
+
+// BenchmarkRangeScan 测量遍历存储桶中每个键值对的开销。
+func BenchmarkRangeScan(b *testing.B) {
+	withBenchDB(b, 0, func(db *DB) {
+		fill(b, db, sequentialKeys(b.N), make([]byte, 100))
+		b.ResetTimer()
+		txn, _ := db.Transaction(nil, ReadOnly)
+		defer txn.Abort()
+		main, _ := txn.Bucket("", 0)
+		it := main.Iterator()
+		for range it.All() {
+		}
+		if err := it.Err(); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
+
+// BenchmarkMixed 测量一个写者持续写入时，多个读者并发随机读取的吞吐量。
+func BenchmarkMixed(b *testing.B) {
+	withBenchDB(b, 0, func(db *DB) {
+		keys := sequentialKeys(benchBatchSize)
+		fill(b, db, keys, make([]byte, 100))
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if err := put(db, keys[:10], make([]byte, 100)); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		}()
+
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			r := rand.New(rand.NewSource(rand.Int63()))
+			for pb.Next() {
+				txn, err := db.Transaction(nil, ReadOnly)
+				if err != nil {
+					b.Error(err)
+					return
+				}
+				main, _ := txn.Bucket("", 0)
+				if _, err := main.Get(keys[r.Intn(len(keys))]); err != nil && err != NotFoundError {
+					b.Error(err)
+				}
+				txn.Abort()
+			}
+		})
+		b.StopTimer()
+		close(done)
+		wg.Wait()
+	})
+}
+
+// BenchmarkDupSort 测量向 DupSort 存储桶中的少量键写入大量重复值的开销。
+func BenchmarkDupSort(b *testing.B) {
+	withBenchDB(b, 0, func(db *DB) {
+		keys := sequentialKeys(b.N)
+		txn, _ := db.Transaction(nil, 0)
+		main, _ := txn.Bucket("", 0)
+		if _, err := main.CreateBucket("dups", DupSort); err != nil {
+			b.Fatal(err)
+		}
+		if err := txn.Commit(); err != nil {
+			b.Fatal(err)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i += benchBatchSize {
+			txn, _ := db.Transaction(nil, 0)
+			main, _ := txn.Bucket("", 0)
+			dups, err := main.Bucket("dups")
+			if err != nil {
+				txn.Abort()
+				b.Skip("DupSort buckets are not persisted yet:", err)
+			}
+			for j := i; j < min(i+benchBatchSize, b.N); j++ {
+				if err := dups.Put(keys[j%16], keys[j], 0); err != nil {
+					b.Fatal(err)
+				}
+			}
+			if err := txn.Commit(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// withBenchDB 在临时文件中打开一个数据库，flags 为通过 SetFlags 设置的选项。
+func withBenchDB(b *testing.B, flags int, fn func(db *DB)) {
+	f, err := os.CreateTemp("", "bolt-bench-")
+	if err != nil {
+		b.Fatal(err)
+	}
+	path := f.Name()
+	f.Close()
+	defer os.Remove(path)
+
+	db := NewDB()
+	if err := db.Open(path, 0666); err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+	if flags != 0 {
+		if err := db.SetFlags(flags, true); err != nil {
+			b.Fatal(err)
+		}
+	}
+	fn(db)
+}
+
+// benchPut 以每个写事务 benchBatchSize 次写入的批量写入所有键，并将其计入基准测试的时间。
+func benchPut(b *testing.B, db *DB, keys [][]byte, value []byte) {
+	b.SetBytes(int64(len(value) + 8))
+	b.ResetTimer()
+	fill(b, db, keys, value)
+}
+
+// benchGet 写入所有键之后，在一个只读事务中按 keys 的顺序读取它们。
+func benchGet(b *testing.B, db *DB, keys [][]byte) {
+	fill(b, db, keys, make([]byte, 100))
+	b.ResetTimer()
+	txn, _ := db.Transaction(nil, ReadOnly)
+	defer txn.Abort()
+	main, _ := txn.Bucket("", 0)
+	for _, k := range keys {
+		if _, err := main.Get(k); err != nil && err != NotFoundError {
+			b.Fatal(err)
+		}
+	}
+}
+
+// fill 将所有键写入主存储桶，每个写事务写入 benchBatchSize 个键。
+func fill(b *testing.B, db *DB, keys [][]byte, value []byte) {
+	if err := put(db, keys, value); err != nil {
+		b.Fatal(err)
+	}
+}
+
+// put 是 fill 的实现，可以在基准测试的其他 goroutine 中调用。
+func put(db *DB, keys [][]byte, value []byte) error {
+	for i := 0; i < len(keys); i += benchBatchSize {
+		txn, err := db.Transaction(nil, 0)
+		if err != nil {
+			return err
+		}
+		main, _ := txn.Bucket("", 0)
+		for _, k := range keys[i:min(i+benchBatchSize, len(keys))] {
+			if err := main.Put(k, value, 0); err != nil {
+				txn.Abort()
+				return err
+			}
+		}
+		if err := txn.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sequentialKeys 返回 n 个按升序排列的 8 字节大端序键。
+func sequentialKeys(n int) [][]byte {
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = binary.BigEndian.AppendUint64(nil, uint64(i))
+	}
+	return keys
+}
+
+// randomKeys 返回 n 个随机排列的 8 字节键。
+func randomKeys(n int) [][]byte {
+	keys := sequentialKeys(n)
+	rand.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+	return keys
+}
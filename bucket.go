@@ -1,7 +1,33 @@
 package boltdb_go
 
-// bucket 结构体实现了 Bucket 接口，具体存储桶的实现细节。
-type Bucket struct {
+import (
+	"bytes"
+	"unsafe"
+)
+
+// 每个事务中固定存在的两个存储桶的索引。
+const (
+	// freeBucket 记录空闲页面的存储桶。
+	freeBucket = 0
+	// mainBucket 存储用户数据以及命名存储桶记录的主存储桶。
+	mainBucket = 1
+)
+
+// bucketFlags 是创建存储桶时可以指定、保存在存储桶记录中的标志位。
+const bucketFlags = DupSort | IntegerKey | IntegerDupKey
+
+// keyCompare 返回标志位为 flags 的存储桶中树使用的键比较函数，检查时也用它验证键的顺序。
+// IntegerKey 和 IntegerDupKey 只保存在存储桶记录中，键和重复值都按字节序排列。
+func keyCompare(flags int) func(a, b []byte) int {
+	return bytes.Compare
+}
+
+// bucketHeaderSize 是存储桶记录的大小，内联存储桶的数据页紧跟在记录之后。
+const bucketHeaderSize = int(unsafe.Sizeof(bucket{}))
+
+// bucket 结构体是存储桶在磁盘上的记录格式，仅在包内部使用。
+// 子存储桶的记录保存在父存储桶的叶子节点中，root 为 0 表示该存储桶是内联的。
+type bucket struct {
 	pad       uint32 // 用于内存对齐的填充项
 	flags     uint16 // 标志位，用于表示存储桶的特性或状态
 	depth     uint16 // 存储桶的深度，表示数据在页结构中的层次
@@ -10,4 +36,284 @@ type Bucket struct {
 	overflows pgno   // 超出页的数量，用于处理数据量过大无法容纳在一个页面的情况
 	entries   uint64 // 存储桶中的条目数量，即存储的数据项数量
 	root      pgno   // 根节点的ID，指向存储桶的顶层页面
+	sequence  uint64 // 存储桶的序列号，由 NextSequence 递增
+}
+
+// Bucket 是事务内的存储桶句柄，由 transaction.Bucket 返回，只在所属事务内有效。
+// 句柄引用的是事务中的 bucket 记录而不是它的副本，因此对树的修改会立即反映到句柄上。
+type Bucket struct {
+	transaction *transaction          // 所属事务
+	id          int                   // 存储桶记录在 transaction.buckets 中的索引
+	bucket      *bucket               // 事务中的存储桶记录
+	name        string                // 存储桶名称，主存储桶为空字符串
+	flags       int                   // 存储桶标志位
+	compare     func(a, b []byte) int // 键的比较函数
+	parent      *Bucket               // 父存储桶，主存储桶为 nil
+	inline      []byte                // 内联存储桶的数据页，拥有独立根页面时为 nil
+
+	// FillPercent 是页面分裂时左侧页面的目标填充比例，取值范围为 [MinFillPercent, MaxFillPercent]，
+	// 为 0 时使用 DefaultFillPercent，但以 Append 或 AppendDup 追加写入时分裂的页面保持已满。
+	// 不使用 Append 而按键的顺序写入时，将其设置为 1 可以得到最紧凑的页面。
+	FillPercent float64
+}
+
+// 页面分裂时的填充比例。
+const (
+	MinFillPercent     = 0.1
+	MaxFillPercent     = 1.0
+	DefaultFillPercent = 0.5
+)
+
+// fillPercent 返回限制在有效范围内的填充比例。
+func (b *Bucket) fillPercent() float64 {
+	if b.FillPercent == 0 {
+		return DefaultFillPercent
+	}
+	return min(max(b.FillPercent, MinFillPercent), MaxFillPercent)
+}
+
+// stat 根据存储桶记录中的计数返回统计信息。
+func (b *bucket) stat(pageSize int) *Stat {
+	return &Stat{
+		PageSize:          pageSize,
+		Depth:             int(b.depth),
+		BranchPageCount:   int(b.branches),
+		LeafPageCount:     int(b.leafs),
+		OverflowPageCount: int(b.overflows),
+		EntryCount:        int(b.entries),
+	}
+}
+
+// Name 返回存储桶的名称。
+func (b *Bucket) Name() string {
+	return b.name
+}
+
+// Flags 返回存储桶的标志位。
+func (b *Bucket) Flags() int {
+	return b.flags
+}
+
+// Get 返回 key 对应的值，key 不存在时返回 NotFoundError。
+func (b *Bucket) Get(key []byte) ([]byte, error) {
+	return b.transaction.Get(b, key)
+}
+
+// Put 写入一个键值对。
+func (b *Bucket) Put(key []byte, data []byte, flags int) error {
+	return b.transaction.Put(b, key, data, flags)
+}
+
+// Reserve 为 key 预留 size 字节的数据空间，并返回页面内对应的可写切片。
+func (b *Bucket) Reserve(key []byte, size int, flags int) ([]byte, error) {
+	return b.transaction.Reserve(b, key, size, flags)
+}
+
+// Delete 删除 key 对应的键值对，data 不为 nil 时只删除匹配的重复值。
+func (b *Bucket) Delete(key []byte, data []byte) error {
+	return b.transaction.Delete(b, key, data)
+}
+
+// Cursor 在存储桶上创建一个游标。
+func (b *Bucket) Cursor() (Cursor, error) {
+	return b.transaction.Cursor(b)
+}
+
+// Iterator 返回存储桶上的迭代器。
+func (b *Bucket) Iterator() *Iterator {
+	return b.transaction.Iterator(b)
+}
+
+// Stats 返回存储桶的统计信息。
+func (b *Bucket) Stats() *Stat {
+	return b.transaction.Stat(b)
+}
+
+// WalkStats 遍历存储桶的所有页面重新统计其页面数量和深度。
+// 与 Stats 不同，它的耗时与存储桶的大小成正比，事务的 context 被取消时返回 ctx.Err()。
+func (b *Bucket) WalkStats() (*Stat, error) {
+	return b.transaction.walkStat(b)
+}
+
+// ForEach 按键的升序对每个键值对调用 fn，fn 返回错误时停止遍历并返回该错误。
+func (b *Bucket) ForEach(fn func(k, v []byte) error) error {
+	it := b.Iterator()
+	for k, v := range it.All() {
+		if err := fn(k, v); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// Sequence 返回存储桶当前的序列号。
+func (b *Bucket) Sequence() uint64 {
+	return b.bucket.sequence
+}
+
+// NextSequence 递增并返回存储桶的序列号。
+// 序列号保存在存储桶记录中，与事务一同提交，事务中止时随之回滚。
+func (b *Bucket) NextSequence() (uint64, error) {
+	if !b.transaction.writable() {
+		return 0, ReadOnlyError
+	}
+	if b.transaction.prepared != nil {
+		return 0, PreparedError
+	}
+	b.bucket.sequence++
+	return b.bucket.sequence, nil
+}
+
+// SetSequence 将存储桶的序列号设置为 v。
+func (b *Bucket) SetSequence(v uint64) error {
+	if !b.transaction.writable() {
+		return ReadOnlyError
+	}
+	if b.transaction.prepared != nil {
+		return PreparedError
+	}
+	b.bucket.sequence = v
+	return nil
+}
+
+// Bucket 返回当前存储桶中名为 name 的子存储桶。
+// 子存储桶不存在时返回 NotFoundError，name 对应的是普通键值对时返回 InCompatibleError。
+func (b *Bucket) Bucket(name string) (*Bucket, error) {
+	if child := b.transaction.handle(b, name); child != nil {
+		return child, nil
+	}
+	c, err := b.Cursor()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	_, value, err := c.Set([]byte(name))
+	if err != nil {
+		return nil, err
+	}
+	if !c.IsBucket() {
+		return nil, InCompatibleError
+	}
+	return b.transaction.openBucket(b, name, value)
+}
+
+// CreateBucket 在当前存储桶中创建名为 name 的子存储桶。
+// 新建的子存储桶是内联的，直到其大小超过 maxInlineSize 时才会分配独立的根页面。
+func (b *Bucket) CreateBucket(name string, flags int) (*Bucket, error) {
+	if name == "" || len(name) > MaxKeySize {
+		return nil, BadValueSizeError
+	}
+	if flags&^bucketFlags != 0 {
+		return nil, InvalidArgumentError
+	}
+	c, err := b.Cursor()
+	if err != nil {
+		return nil, err
+	}
+	_, _, err = c.Set([]byte(name))
+	isBucket := c.IsBucket()
+	c.Close()
+	if err == nil {
+		if isBucket {
+			return nil, BucketExistError
+		}
+		return nil, KeyExistError
+	} else if err != NotFoundError {
+		return nil, err
+	}
+
+	value := make([]byte, bucketHeaderSize+pageHeaderSize)
+	rec := (*bucket)(unsafe.Pointer(&value[0]))
+	rec.flags = uint16(flags)
+	p := (*page)(unsafe.Pointer(&value[bucketHeaderSize]))
+	p.init(p_leaf, pageHeaderSize)
+	if _, err := b.transaction.put(b, []byte(name), value, bucketNode); err != nil {
+		return nil, err
+	}
+	return b.transaction.openBucket(b, name, value)
+}
+
+// DeleteBucket 删除当前存储桶中名为 name 的子存储桶，以及它包含的所有子存储桶。
+func (b *Bucket) DeleteBucket(name string) error {
+	child, err := b.Bucket(name)
+	if err != nil {
+		return err
+	}
+	return b.transaction.Drop(child, 1)
+}
+
+// path 返回存储桶从顶层开始的路径，主存储桶为 nil。
+func (b *Bucket) path() []string {
+	if b.parent == nil {
+		return nil
+	}
+	return append(b.parent.path(), b.name)
+}
+
+// depth 返回存储桶的嵌套深度，主存储桶为 0。
+func (b *Bucket) depth() int {
+	n := 0
+	for p := b.parent; p != nil; p = p.parent {
+		n++
+	}
+	return n
+}
+
+// descendantOf 返回存储桶是否为 ancestor 本身或其子孙。
+func (b *Bucket) descendantOf(ancestor *Bucket) bool {
+	for p := b; p != nil; p = p.parent {
+		if p == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// maxInlineSize 返回内联存储桶数据页允许的最大字节数。
+func (b *Bucket) maxInlineSize() int {
+	return b.transaction.db.pageSize / 4
+}
+
+// value 将存储桶编码为父存储桶中的值。
+// 只有一个叶子页面且足够小的存储桶连同数据页一起内联保存，并释放其根页面；否则值中只保存记录。
+func (b *Bucket) value() ([]byte, error) {
+	if err := b.inlineRoot(); err != nil {
+		return nil, err
+	}
+	value := make([]byte, bucketHeaderSize+len(b.inline))
+	*(*bucket)(unsafe.Pointer(&value[0])) = *b.bucket
+	copy(value[bucketHeaderSize:], b.inline)
+	return value, nil
+}
+
+// inlineRoot 将当前事务中修改过的、足够小的根页面转换为内联数据页。
+func (b *Bucket) inlineRoot() error {
+	t, rec := b.transaction, b.bucket
+	if b.inline != nil || rec.root == 0 {
+		return nil
+	}
+	if rec.root == p_invalid {
+		b.inline = make([]byte, pageHeaderSize)
+		(*page)(unsafe.Pointer(&b.inline[0])).init(p_leaf, pageHeaderSize)
+	} else {
+		if rec.depth != 1 || rec.overflows != 0 {
+			return nil
+		}
+		p, level, err := t.getPage(int(rec.root))
+		if err != nil {
+			return err
+		}
+		used := p.usedSize()
+		if level == 0 || pageHeaderSize+used > b.maxInlineSize() {
+			return nil
+		}
+		b.inline = make([]byte, pageHeaderSize+used)
+		copyPage(b.inline, p, t.db.pageSize)
+		ip := (*page)(unsafe.Pointer(&b.inline[0]))
+		ip.id, ip.flags = 0, p_leaf
+		t.freePages = append(t.freePages, int(rec.root))
+	}
+	rec.depth, rec.branches, rec.leafs, rec.overflows, rec.root = 0, 0, 0, 0, 0
+	return nil
 }
@@ -0,0 +1,450 @@
+package boltdb_go
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 确保 Put 写入的键值对在提交并重新打开数据库后可以通过 Get 读出，
+// 包括需要溢出页面的大值以及需要分裂页面的大量键。
+func TestBucket_PutGet(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		big := bytes.Repeat([]byte("x"), 3*db.pageSize)
+		txn, _ := db.Transaction(nil, 0)
+		b, err := txn.Bucket("", 0)
+		assert.NoError(t, err)
+		for i := 0; i < 1000; i++ {
+			assert.NoError(t, b.Put([]byte(fmt.Sprintf("key%04d", i)), []byte(fmt.Sprintf("value%d", i)), 0))
+		}
+		assert.NoError(t, b.Put([]byte("big"), big, 0))
+		v, err := b.Reserve([]byte("reserved"), 3, 0)
+		assert.NoError(t, err)
+		copy(v, "abc")
+		assert.NoError(t, txn.Commit())
+		db.Close()
+
+		db = NewDB()
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		txn, _ = db.Transaction(nil, ReadOnly)
+		defer txn.Abort()
+		b, _ = txn.Bucket("", 0)
+		for _, i := range []int{0, 1, 499, 999} {
+			v, err := b.Get([]byte(fmt.Sprintf("key%04d", i)))
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("value%d", i), string(v))
+		}
+		v, err = b.Get([]byte("big"))
+		assert.NoError(t, err)
+		assert.Equal(t, big, v)
+		v, err = b.Get([]byte("reserved"))
+		assert.NoError(t, err)
+		assert.Equal(t, "abc", string(v))
+		_, err = b.Get([]byte("key"))
+		assert.Equal(t, NotFoundError, err)
+
+		s := b.Stats()
+		assert.Equal(t, 1002, s.EntryCount)
+		assert.Greater(t, s.Depth, 1)
+		assert.Equal(t, 4, s.OverflowPageCount)
+		errs, err := txn.Check()
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+	})
+}
+
+// 确保覆盖已有的键后只保留新值，被替换的溢出页面进入空闲列表。
+func TestBucket_Overwrite(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		big := bytes.Repeat([]byte("y"), 2*db.pageSize)
+		for _, v := range [][]byte{[]byte("short"), big, []byte("other"), []byte("longer value")} {
+			txn, _ := db.Transaction(nil, 0)
+			b, _ := txn.Bucket("", 0)
+			assert.NoError(t, b.Put([]byte("k"), v, 0))
+			assert.NoError(t, txn.Commit())
+
+			txn, _ = db.Transaction(nil, ReadOnly)
+			b, _ = txn.Bucket("", 0)
+			got, err := b.Get([]byte("k"))
+			assert.NoError(t, err)
+			assert.Equal(t, v, got)
+			assert.Equal(t, 1, b.Stats().EntryCount)
+			errs, err := txn.Check()
+			assert.NoError(t, err)
+			assert.Empty(t, errs)
+			txn.Abort()
+		}
+	})
+}
+
+// 确保子存储桶中的数据在提交后保留，足够小的子存储桶保存为内联存储桶。
+func TestBucket_NestedPutGet(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		txn, _ := db.Transaction(nil, 0)
+		main, _ := txn.Bucket("", 0)
+		small, err := main.CreateBucket("small", 0)
+		assert.NoError(t, err)
+		assert.NoError(t, small.Put([]byte("a"), []byte("1"), 0))
+		large, err := main.CreateBucket("large", 0)
+		assert.NoError(t, err)
+		for i := 0; i < 200; i++ {
+			assert.NoError(t, large.Put([]byte(fmt.Sprintf("%03d", i)), bytes.Repeat([]byte("v"), 50), 0))
+		}
+		assert.NoError(t, txn.Commit())
+
+		txn, _ = db.Transaction(nil, ReadOnly)
+		defer txn.Abort()
+		small, err = txn.Bucket("small", 0)
+		assert.NoError(t, err)
+		assert.NotNil(t, small.inline)
+		v, err := small.Get([]byte("a"))
+		assert.NoError(t, err)
+		assert.Equal(t, "1", string(v))
+		large, err = txn.Bucket("large", 0)
+		assert.NoError(t, err)
+		assert.Nil(t, large.inline)
+		assert.Equal(t, 200, large.Stats().EntryCount)
+		errs, err := txn.Check()
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+	})
+}
+
+// 确保删除大部分键后页面被合并、树的高度降低，删除所有键后树为空，未删除的键仍然可以读出。
+func TestBucket_Delete(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		key := func(i int) []byte { return []byte(fmt.Sprintf("%04d%s", i, bytes.Repeat([]byte("k"), 400))) }
+		txn, _ := db.Transaction(nil, 0)
+		b, _ := txn.Bucket("", 0)
+		for i := 0; i < 1000; i++ {
+			assert.NoError(t, b.Put(key(i), []byte(fmt.Sprint(i)), 0))
+		}
+		assert.NoError(t, b.Put([]byte("big"), bytes.Repeat([]byte("x"), 2*db.pageSize), 0))
+		assert.NoError(t, txn.Commit())
+
+		txn, _ = db.Transaction(nil, 0)
+		b, _ = txn.Bucket("", 0)
+		depth := b.Stats().Depth
+		assert.GreaterOrEqual(t, depth, 3)
+		for i := 0; i < 1000; i++ {
+			if i%50 != 0 {
+				assert.NoError(t, b.Delete(key(i), nil))
+			}
+		}
+		assert.NoError(t, b.Delete([]byte("big"), nil))
+		assert.Equal(t, NotFoundError, b.Delete(key(1), nil))
+		assert.NoError(t, txn.Commit())
+
+		txn, _ = db.Transaction(nil, 0)
+		b, _ = txn.Bucket("", 0)
+		s := b.Stats()
+		assert.Equal(t, 20, s.EntryCount)
+		assert.Less(t, s.Depth, depth)
+		assert.Equal(t, 0, s.OverflowPageCount)
+		for i := 0; i < 1000; i += 50 {
+			v, err := b.Get(key(i))
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprint(i), string(v))
+		}
+		errs, err := txn.Check()
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+
+		for i := 0; i < 1000; i += 50 {
+			assert.NoError(t, b.Delete(key(i), nil))
+		}
+		assert.NoError(t, txn.Commit())
+
+		txn, _ = db.Transaction(nil, ReadOnly)
+		defer txn.Abort()
+		b, _ = txn.Bucket("", 0)
+		s = b.Stats()
+		assert.Equal(t, 0, s.EntryCount)
+		assert.Equal(t, 0, s.Depth)
+		assert.Equal(t, 0, s.LeafPageCount+s.BranchPageCount)
+		errs, err = txn.Check()
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+	})
+}
+
+// 确保通过游标删除节点后，即使页面被合并，Next 仍然返回被删除节点的下一个键。
+func TestBucket_CursorDel(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		txn, _ := db.Transaction(nil, 0)
+		b, _ := txn.Bucket("", 0)
+		for i := 0; i < 500; i++ {
+			assert.NoError(t, b.Put([]byte(fmt.Sprintf("%04d", i)), bytes.Repeat([]byte("v"), 100), 0))
+		}
+		c, _ := b.Cursor()
+		var kept []string
+		assert.NoError(t, c.First())
+		k, _, err := c.Current()
+		for i := 0; err == nil; i++ {
+			if i%10 == 0 {
+				kept = append(kept, string(k))
+				k, _, err = c.Next()
+				continue
+			}
+			assert.NoError(t, c.Del(0))
+			k, _, err = c.Next()
+		}
+		assert.Equal(t, NotFoundError, err)
+		assert.Len(t, kept, 50)
+
+		var got []string
+		assert.NoError(t, c.First())
+		for k, _, err := c.Current(); err == nil; k, _, err = c.Next() {
+			got = append(got, string(k))
+		}
+		assert.Equal(t, kept, got)
+		assert.Equal(t, 50, b.Stats().EntryCount)
+		c.Close()
+		assert.NoError(t, txn.Commit())
+
+		txn, _ = db.Transaction(nil, ReadOnly)
+		defer txn.Abort()
+		errs, err := txn.Check()
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+	})
+}
+
+// 确保按顺序追加写入时，较大的 FillPercent 使分裂后的页面更满，需要的叶子页面更少。
+func TestBucket_FillPercent(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		txn, _ := db.Transaction(nil, 0)
+		main, _ := txn.Bucket("", 0)
+		leafs := map[float64]int{}
+		for _, fill := range []float64{0.5, 1} {
+			b, err := main.CreateBucket(fmt.Sprint(fill), 0)
+			assert.NoError(t, err)
+			b.FillPercent = fill
+			for i := 0; i < 1000; i++ {
+				assert.NoError(t, b.Put([]byte(fmt.Sprintf("%04d", i)), bytes.Repeat([]byte("v"), 50), 0))
+			}
+			leafs[fill] = b.Stats().LeafPageCount
+		}
+		assert.Less(t, float64(leafs[1]), 0.6*float64(leafs[0.5]))
+		assert.NoError(t, txn.Commit())
+
+		txn, _ = db.Transaction(nil, ReadOnly)
+		defer txn.Abort()
+		errs, err := txn.Check()
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+	})
+}
+
+// 确保以 Append 追加写入时分裂的页面保持已满，除最后一个叶子页面外每个页面都放不下更多的节点。
+func TestBucket_AppendFill(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		txn, _ := db.Transaction(nil, 0)
+		main, _ := txn.Bucket("", 0)
+		b, err := main.CreateBucket("append", 0)
+		assert.NoError(t, err)
+		value := bytes.Repeat([]byte("v"), 50)
+		for i := 0; i < 1000; i++ {
+			assert.NoError(t, b.Put([]byte(fmt.Sprintf("%04d", i)), value, Append))
+		}
+		assert.Equal(t, KeyExistError, b.Put([]byte("0500"), value, Append))
+		perPage := (db.pageSize - pageHeaderSize) / db.LeafSize([]byte("0000"), value)
+		assert.Equal(t, (1000+perPage-1)/perPage, b.Stats().LeafPageCount)
+
+		dups, err := main.CreateBucket("dups", DupSort)
+		assert.NoError(t, err)
+		for i := 0; i < 300; i++ {
+			assert.NoError(t, dups.Put([]byte("k"), []byte(fmt.Sprintf("%03d", i)), AppendDup))
+		}
+		assert.Equal(t, KeyExistError, dups.Put([]byte("k"), []byte("100"), AppendDup))
+		assert.NoError(t, dups.Put([]byte("l"), []byte("000"), AppendDup))
+		assert.NoError(t, txn.Commit())
+
+		txn, _ = db.Transaction(nil, ReadOnly)
+		defer txn.Abort()
+		errs, err := txn.Check()
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+		b, _ = txn.Bucket("append", 0)
+		v, err := b.Get([]byte("0999"))
+		assert.NoError(t, err)
+		assert.Equal(t, value, v)
+		dups, _ = txn.Bucket("dups", 0)
+		c, _ := txn.Cursor(dups)
+		defer c.Close()
+		_, _, err = c.Set([]byte("k"))
+		assert.NoError(t, err)
+		n, err := c.Count()
+		assert.NoError(t, err)
+		assert.Equal(t, 300, n)
+	})
+}
+
+// 确保打开存储桶时 flags 必须与存储桶的标志位一致，并且只能包含存储桶的标志位。
+func TestBucket_Flags(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		txn, _ := db.Transaction(nil, 0)
+		defer txn.Abort()
+		main, _ := txn.Bucket("", 0)
+		_, err := main.CreateBucket("bad", NoSync)
+		assert.Equal(t, InvalidArgumentError, err)
+		_, err = main.CreateBucket("dups", DupSort)
+		assert.NoError(t, err)
+
+		b, err := txn.Bucket("dups", DupSort)
+		assert.NoError(t, err)
+		assert.Equal(t, DupSort, b.Flags())
+		b, err = txn.Bucket("dups", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, DupSort, b.Flags())
+		_, err = txn.Bucket("dups", IntegerKey)
+		assert.Equal(t, InCompatibleError, err)
+		_, err = txn.Bucket("", NoSync)
+		assert.Equal(t, InvalidArgumentError, err)
+		_, err = txn.Bucket("missing", 0)
+		assert.Equal(t, NotFoundError, err)
+	})
+}
+
+// 确保删除存储桶后它的所有页面都被释放，包括溢出页面、重复值子树以及子存储桶的页面。
+func TestBucket_DeleteBucket(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		txn, _ := db.Transaction(nil, 0)
+		main, _ := txn.Bucket("", 0)
+		b, _ := main.CreateBucket("b", 0)
+		for i := 0; i < 300; i++ {
+			assert.NoError(t, b.Put([]byte(fmt.Sprintf("%03d", i)), bytes.Repeat([]byte("v"), 50), 0))
+		}
+		assert.NoError(t, b.Put([]byte("big"), bytes.Repeat([]byte("x"), 2*db.pageSize), 0))
+		dups, _ := b.CreateBucket("dups", DupSort)
+		for i := 0; i < 300; i++ {
+			assert.NoError(t, dups.Put([]byte("k"), []byte(fmt.Sprintf("%020d", i)), 0))
+		}
+		small, _ := b.CreateBucket("small", 0)
+		assert.NoError(t, small.Put([]byte("a"), []byte("1"), 0))
+		assert.NoError(t, txn.Commit())
+
+		txn, _ = db.Transaction(nil, 0)
+		main, _ = txn.Bucket("", 0)
+		b, _ = main.Bucket("b")
+		assert.NoError(t, b.Put([]byte("new"), []byte("1"), 0))
+		assert.NoError(t, main.DeleteBucket("b"))
+		_, err := main.Bucket("b")
+		assert.Equal(t, NotFoundError, err)
+		assert.Equal(t, NotFoundError, main.DeleteBucket("b"))
+		assert.NoError(t, txn.Commit())
+
+		txn, _ = db.Transaction(nil, ReadOnly)
+		defer txn.Abort()
+		main, _ = txn.Bucket("", 0)
+		assert.Equal(t, 0, main.Stats().EntryCount)
+		errs, err := txn.Check()
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+	})
+}
+
+// 确保子存储桶的键不能通过 Put 和 Delete 修改，内部的节点类型标识不能由调用方指定。
+func TestBucket_BucketKeys(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		txn, _ := db.Transaction(nil, 0)
+		main, _ := txn.Bucket("", 0)
+		sub, err := main.CreateBucket("sub", 0)
+		assert.NoError(t, err)
+		assert.NoError(t, sub.Put([]byte("a"), []byte("1"), 0))
+		assert.NoError(t, main.Put([]byte("plain"), []byte("v"), 0))
+
+		assert.Equal(t, InCompatibleError, main.Put([]byte("sub"), []byte("v"), 0))
+		assert.Equal(t, InCompatibleError, main.Delete([]byte("sub"), nil))
+		assert.Equal(t, InvalidArgumentError, main.Put([]byte("k"), []byte("v"), bucketNode))
+		_, err = main.Reserve([]byte("k"), 1, bucketNode)
+		assert.Equal(t, InvalidArgumentError, err)
+		_, err = main.CreateBucket("sub", 0)
+		assert.Equal(t, BucketExistError, err)
+		_, err = main.CreateBucket("plain", 0)
+		assert.Equal(t, KeyExistError, err)
+		_, err = main.Bucket("plain")
+		assert.Equal(t, InCompatibleError, err)
+
+		c, _ := main.Cursor()
+		_, _, err = c.Set([]byte("sub"))
+		assert.NoError(t, err)
+		assert.True(t, c.IsBucket())
+		assert.Equal(t, InCompatibleError, c.Del(0))
+		assert.Equal(t, InvalidArgumentError, c.Put([]byte("sub"), []byte("v"), Current|bucketNode))
+		assert.Equal(t, InCompatibleError, c.Put([]byte("sub"), []byte("v"), Current))
+		c.Close()
+		assert.NoError(t, txn.Commit())
+
+		txn, _ = db.Transaction(nil, ReadOnly)
+		defer txn.Abort()
+		sub, err = txn.Bucket("sub", 0)
+		assert.NoError(t, err)
+		v, err := sub.Get([]byte("a"))
+		assert.NoError(t, err)
+		assert.Equal(t, "1", string(v))
+		errs, err := txn.Check()
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+	})
+}
+
+// 确保存储桶的序列号随事务提交保存，重新打开数据库后依然存在，事务中止时随之回滚。
+func TestBucket_Sequence(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		txn, _ := db.Transaction(nil, 0)
+		main, _ := txn.Bucket("", 0)
+		sub, _ := main.CreateBucket("sub", 0)
+		for i := 0; i < 3; i++ {
+			_, err := main.NextSequence()
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, sub.SetSequence(41))
+		n, err := sub.NextSequence()
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(42), n)
+		assert.NoError(t, txn.Commit())
+
+		txn, _ = db.Transaction(nil, 0)
+		sub, _ = txn.Bucket("sub", 0)
+		_, err = sub.NextSequence()
+		assert.NoError(t, err)
+		txn.Abort()
+		db.Close()
+
+		db = NewDB()
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		txn, _ = db.Transaction(nil, ReadOnly)
+		defer txn.Abort()
+		main, _ = txn.Bucket("", 0)
+		assert.Equal(t, uint64(3), main.Sequence())
+		sub, _ = txn.Bucket("sub", 0)
+		assert.Equal(t, uint64(42), sub.Sequence())
+		_, err = sub.NextSequence()
+		assert.Equal(t, ReadOnlyError, err)
+	})
+}
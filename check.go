@@ -0,0 +1,359 @@
+package boltdb_go
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// CheckKind 表示一致性检查发现的问题类型。
+type CheckKind int
+
+const (
+	// CheckOutOfRange 表示引用的页面ID超出了快照的页面范围。
+	CheckOutOfRange CheckKind = iota + 1
+	// CheckPageType 表示页面的类型与它在树中的位置不符。
+	CheckPageType
+	// CheckDuplicatePage 表示页面被引用了多次，或在空闲列表中出现了多次。
+	CheckDuplicatePage
+	// CheckFreeReachable 表示页面既可以从存储桶访问到，又位于空闲列表中。
+	CheckFreeReachable
+	// CheckUnsorted 表示页面中的键没有按顺序排列，或超出了父页面分隔键限定的范围。
+	CheckUnsorted
+	// CheckCounter 表示存储桶记录中的计数与实际统计的结果不符。
+	CheckCounter
+	// CheckLeakedPage 表示页面既不可访问，也不在空闲列表中。
+	CheckLeakedPage
+)
+
+// String 返回问题类型的名称。
+func (k CheckKind) String() string {
+	switch k {
+	case CheckOutOfRange:
+		return "out of range"
+	case CheckPageType:
+		return "page type"
+	case CheckDuplicatePage:
+		return "duplicate page"
+	case CheckFreeReachable:
+		return "free page reachable"
+	case CheckUnsorted:
+		return "unsorted keys"
+	case CheckCounter:
+		return "counter mismatch"
+	case CheckLeakedPage:
+		return "leaked page"
+	}
+	return "unknown"
+}
+
+// MarshalText 以名称编码问题类型，使 JSON 输出可读。
+func (k CheckKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// CheckError 是一致性检查发现的一个问题。
+type CheckError struct {
+	Kind    CheckKind
+	PageID  int    // 出现问题的页面ID
+	Bucket  string // 页面所属的存储桶："free"、"main" 或以 "main/" 开头的子存储桶路径
+	Message string
+}
+
+func (e *CheckError) Error() string {
+	return fmt.Sprintf("page %d (%s): %s: %s", e.PageID, e.Bucket, e.Kind, e.Message)
+}
+
+// Check 在一个只读事务的快照上检查数据库的一致性，返回发现的所有问题。
+// 只有无法开始事务或检查被取消时才返回 error。
+func (db *DB) Check() ([]*CheckError, error) {
+	t, err := db.Transaction(nil, ReadOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer t.Abort()
+	return t.Check()
+}
+
+// Check 检查事务快照的一致性：所有从 meta 可访问的页面都在范围内且类型正确，
+// 没有页面被引用两次或同时位于空闲列表中，键在页面内和分隔键之间有序，
+// 存储桶记录中的计数与实际相符，并且没有泄漏的页面。
+// 事务的 context 被取消时返回 ctx.Err()。
+func (t *transaction) Check() ([]*CheckError, error) {
+	c, err := t.check()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range c.freeList {
+		if owner, ok := c.owner[id]; ok {
+			c.report(CheckFreeReachable, id, "free", "page is reachable from %s", owner)
+		}
+	}
+	for id := pgno(2); id < pgno(t.nextPageNumber); id++ {
+		if _, ok := c.owner[id]; !ok && !c.free[id] {
+			c.report(CheckLeakedPage, id, "", "page is neither reachable nor free")
+		}
+	}
+	return c.errs, nil
+}
+
+// check 遍历空闲页面存储桶和主存储桶，返回记录了页面归属的 checker。
+func (t *transaction) check() (*checker, error) {
+	c := newChecker(t)
+	c.tree(t.buckets[freeBucket], "free")
+	c.tree(t.buckets[mainBucket], "main")
+	if err := t.Context().Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// checker 保存一致性检查的中间状态。
+type checker struct {
+	t        *transaction
+	errs     []*CheckError
+	owner    map[pgno]string     // 可访问的页面及其所属的存储桶
+	free     map[pgno]bool       // 空闲列表中的页面
+	freeList []pgno              // 空闲列表中的页面，按出现的顺序排列
+	leafs    map[pgno]*bucket    // 位于叶子层的页面及其所在 B+ 树的记录
+	parents  map[*bucket]*bucket // 重复值子树的记录及其所在存储桶的记录
+	cur      *bucket             // 正在遍历的 B+ 树的记录
+}
+
+func newChecker(t *transaction) *checker {
+	return &checker{
+		t:       t,
+		owner:   make(map[pgno]string),
+		free:    make(map[pgno]bool),
+		leafs:   make(map[pgno]*bucket),
+		parents: make(map[*bucket]*bucket),
+	}
+}
+
+func (c *checker) report(kind CheckKind, id pgno, bucket string, format string, v ...any) {
+	c.errs = append(c.errs, &CheckError{Kind: kind, PageID: int(id), Bucket: bucket, Message: fmt.Sprintf(format, v...)})
+}
+
+// reach 将页面标记为 bucket 可访问的页面并返回它，页面超出范围或已被引用时报告问题并返回 nil。
+func (c *checker) reach(id pgno, bucket string) *page {
+	if id < 2 || id >= pgno(c.t.nextPageNumber) {
+		c.report(CheckOutOfRange, id, bucket, "page id is outside [2, %d)", c.t.nextPageNumber)
+		return nil
+	}
+	if owner, ok := c.owner[id]; ok {
+		c.report(CheckDuplicatePage, id, bucket, "page is also reachable from %s", owner)
+		return nil
+	}
+	p, _, err := c.t.getPage(int(id))
+	if err != nil {
+		c.report(CheckOutOfRange, id, bucket, "%v", err)
+		return nil
+	}
+	c.owner[id] = bucket
+	return p
+}
+
+// tree 检查以 rec.root 为根的 B+ 树，并核对存储桶记录中的计数。
+func (c *checker) tree(rec *bucket, name string) {
+	n := c.count(rec, name)
+	c.counters(rec, &n, name)
+}
+
+// count 遍历以 rec.root 为根的 B+ 树，返回实际统计的计数。
+func (c *checker) count(rec *bucket, name string) bucket {
+	var n bucket
+	parent := c.cur
+	c.cur = rec
+	if rec.root != p_invalid && rec.root != 0 && c.t.Context().Err() == nil {
+		c.page(rec.root, name, 0, int(rec.depth), nil, nil, &n, int(rec.flags))
+	}
+	c.cur = parent
+	return n
+}
+
+// counters 比较存储桶记录 rec 和实际统计的计数 n。
+func (c *checker) counters(rec, n *bucket, name string) {
+	check := func(field string, want, got uint64) {
+		if want != got {
+			c.report(CheckCounter, rec.root, name, "%s is %d, found %d", field, want, got)
+		}
+	}
+	check("depth", uint64(rec.depth), uint64(n.depth))
+	check("branch pages", uint64(rec.branches), uint64(n.branches))
+	check("leaf pages", uint64(rec.leafs), uint64(n.leafs))
+	check("overflow pages", uint64(rec.overflows), uint64(n.overflows))
+	check("entries", rec.entries, n.entries)
+}
+
+// page 检查树中第 level 层的页面 id，页面中的键必须位于 [lo, hi) 之内，hi 为 nil 时没有上限。
+// 统计结果累加到 n 中。
+func (c *checker) page(id pgno, name string, level, depth int, lo, hi []byte, n *bucket, flags int) {
+	if c.t.Context().Err() != nil {
+		return
+	}
+	p := c.reach(id, name)
+	if p == nil {
+		return
+	}
+	want := p_branch
+	if level == depth-1 {
+		want = p_leaf
+		c.leafs[id] = c.cur
+	}
+	if p.flags&(p_branch|p_leaf|p_overflow|p_meta) != want {
+		c.report(CheckPageType, id, name, "flags 0x%x at level %d of %d, want 0x%x", p.flags, level, depth, want)
+		return
+	}
+	if level+1 > int(n.depth) {
+		n.depth = uint16(level + 1)
+	}
+	c.keys(p, id, name, lo, hi, flags)
+
+	if p.flags&p_branch != 0 {
+		n.branches++
+		for i := 0; i < p.nodeCount(); i++ {
+			// 第一个分支节点的键为空，它的下限继承自父页面。
+			clo, chi := lo, hi
+			if i > 0 {
+				clo = p.node(i).key()
+			}
+			if i+1 < p.nodeCount() {
+				chi = p.node(i + 1).key()
+			}
+			c.page(p.node(i).pgno(), name, level+1, depth, clo, chi, n, flags)
+		}
+		return
+	}
+
+	n.leafs++
+	for i := 0; i < p.nodeCount(); i++ {
+		c.leaf(p.node(i), id, name, n)
+	}
+}
+
+// keys 检查页面中的键按树使用的比较函数有序，并且位于 [lo, hi) 之内。
+func (c *checker) keys(p *page, id pgno, name string, lo, hi []byte, flags int) {
+	compare := keyCompare(flags)
+	var prev []byte
+	for i := 0; i < p.nodeCount(); i++ {
+		k := p.node(i).key()
+		if i == 0 && p.flags&p_branch != 0 {
+			continue
+		}
+		switch {
+		case prev != nil && compare(prev, k) >= 0:
+			c.report(CheckUnsorted, id, name, "key %d %q is not greater than %q", i, k, prev)
+		case lo != nil && compare(k, lo) < 0:
+			c.report(CheckUnsorted, id, name, "key %d %q is less than separator %q", i, k, lo)
+		case hi != nil && compare(k, hi) >= 0:
+			c.report(CheckUnsorted, id, name, "key %d %q is not less than separator %q", i, k, hi)
+		}
+		prev = k
+	}
+}
+
+// leaf 检查叶子节点引用的溢出页面、子存储桶或重复值，并统计条目数量。
+func (c *checker) leaf(nd *node, id pgno, name string, n *bucket) {
+	switch {
+	case nd.flags&bigNode != 0:
+		n.entries++
+		c.overflow(nd.overflowPgno(), name, n)
+		if name == "free" {
+			if v, err := c.t.readNode(nd); err == nil {
+				c.freePages(v, id)
+			}
+		}
+	case nd.flags&bucketNode != 0:
+		n.entries++
+		c.bucket(nd.value(), name+"/"+string(nd.key()), id)
+	case nd.flags&dupNode != 0 && nd.flags&subNode != 0:
+		// 重复值保存在节点内的子页面中。
+		v := nd.value()
+		if len(v) < pageHeaderSize {
+			c.report(CheckCounter, id, name, "sub-page of key %q is truncated", nd.key())
+			return
+		}
+		n.entries += uint64((*page)(unsafe.Pointer(&v[0])).nodeCount())
+	case nd.flags&dupNode != 0:
+		// 重复值保存在独立的子树中，节点的值是子树的记录。
+		v := nd.value()
+		if len(v) < bucketHeaderSize {
+			c.report(CheckCounter, id, name, "sub-tree record of key %q is truncated", nd.key())
+			return
+		}
+		rec := (*bucket)(unsafe.Pointer(&v[0]))
+		c.parents[rec] = c.cur
+		c.tree(rec, name)
+		n.entries += rec.entries
+	default:
+		n.entries++
+		if name == "free" {
+			c.freePages(nd.value(), id)
+		}
+	}
+}
+
+// overflow 检查大节点的数据所在的溢出页面，并将其占用的后续页面一并标记为可访问。
+func (c *checker) overflow(id pgno, name string, n *bucket) {
+	p := c.reach(id, name)
+	if p == nil {
+		return
+	}
+	if p.flags&p_overflow == 0 {
+		c.report(CheckPageType, id, name, "flags 0x%x, want overflow page", p.flags)
+		return
+	}
+	n.overflows += pgno(p.overflow)
+	for i := pgno(1); i < pgno(p.overflow); i++ {
+		c.reach(id+i, name)
+	}
+}
+
+// bucket 检查子存储桶，value 是它在父存储桶中的值，parent 为父存储桶中保存该值的页面。
+func (c *checker) bucket(value []byte, name string, parent pgno) {
+	if len(value) < bucketHeaderSize {
+		c.report(CheckCounter, parent, name, "bucket record is truncated")
+		return
+	}
+	rec := (*bucket)(unsafe.Pointer(&value[0]))
+	if rec.root != 0 {
+		c.tree(rec, name)
+		return
+	}
+	// 内联存储桶的数据页紧跟在记录之后。
+	if len(value) < bucketHeaderSize+pageHeaderSize {
+		c.report(CheckCounter, parent, name, "inline page is truncated")
+		return
+	}
+	p := (*page)(unsafe.Pointer(&value[bucketHeaderSize]))
+	c.keys(p, parent, name, nil, nil, int(rec.flags))
+	var n bucket
+	for i := 0; i < p.nodeCount(); i++ {
+		c.leaf(p.node(i), parent, name, &n)
+	}
+	if rec.entries != n.entries {
+		c.report(CheckCounter, parent, name, "entries is %d, found %d", rec.entries, n.entries)
+	}
+}
+
+// freePages 记录空闲列表中一条记录包含的页面。记录的值是页面ID数组，第一个元素是页面数量。
+func (c *checker) freePages(v []byte, id pgno) {
+	size := int(unsafe.Sizeof(pgno(0)))
+	ids := make([]pgno, len(v)/size)
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(ids))), len(ids)*size), v)
+	if len(ids) == 0 || int(ids[0]) != len(ids)-1 {
+		c.report(CheckCounter, id, "free", "free list record has %d ids, header says %v", max(len(ids)-1, 0), ids[:min(len(ids), 1)])
+		return
+	}
+	for _, fid := range ids[1:] {
+		switch {
+		case fid < 2 || fid >= pgno(c.t.nextPageNumber):
+			c.report(CheckOutOfRange, fid, "free", "free page id is outside [2, %d)", c.t.nextPageNumber)
+		case c.free[fid]:
+			c.report(CheckDuplicatePage, fid, "free", "page is free more than once")
+		default:
+			c.free[fid] = true
+			c.freeList = append(c.freeList, fid)
+		}
+	}
+}
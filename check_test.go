@@ -0,0 +1,75 @@
+package boltdb_go
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 确保一致的数据库没有问题，损坏的 meta 引用和泄漏的页面被报告。
+func TestDB_Check(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		errs, err := db.Check()
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+
+		// 让主存储桶指向不存在的页面，并分配一个没有被引用的页面。
+		txn, _ := db.Transaction(nil, 0)
+		txn.buckets[mainBucket].root = 100
+		txn.buckets[mainBucket].depth = 1
+		txn.nextPageNumber++
+		assert.NoError(t, txn.Commit())
+
+		errs, err = db.Check()
+		assert.NoError(t, err)
+		var kinds []CheckKind
+		var pages []int
+		for _, e := range errs {
+			kinds = append(kinds, e.Kind)
+			pages = append(pages, e.PageID)
+		}
+		assert.Equal(t, []CheckKind{CheckOutOfRange, CheckCounter, CheckLeakedPage}, kinds)
+		assert.Equal(t, []int{100, 100, 2}, pages)
+		assert.Equal(t, "page 100 (main): out of range: page id is outside [2, 3)", errs[0].Error())
+	})
+}
+
+// 确保 IntegerKey 存储桶中的键也按树使用的比较函数检查顺序。
+func TestDB_CheckIntegerKeyOrder(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		txn, _ := db.Transaction(nil, 0)
+		main, _ := txn.Bucket("", 0)
+		b, err := main.CreateBucket("ints", IntegerKey)
+		assert.NoError(t, err)
+		for _, k := range []string{"k1", "k2"} {
+			assert.NoError(t, b.Put([]byte(k), bytes.Repeat([]byte("v"), 1500), 0))
+		}
+		root := b.bucket.root
+		assert.NoError(t, txn.Commit())
+		errs, err := db.Check()
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+
+		// 把第二个键改为小于第一个键。
+		buf := make([]byte, db.pageSize)
+		f, err := os.OpenFile(path, os.O_RDWR, 0)
+		assert.NoError(t, err)
+		defer f.Close()
+		_, err = f.ReadAt(buf, int64(root)*int64(db.pageSize))
+		assert.NoError(t, err)
+		i := bytes.Index(buf, []byte("k2"))
+		assert.True(t, i > 0)
+		_, err = f.WriteAt([]byte("k0"), int64(root)*int64(db.pageSize)+int64(i))
+		assert.NoError(t, err)
+
+		errs, err = db.Check()
+		assert.NoError(t, err)
+		if assert.Len(t, errs, 1) {
+			assert.Equal(t, CheckUnsorted, errs[0].Kind)
+		}
+	})
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	bolt "boltdb-go"
+)
+
+// runBench 在一个新的数据库文件上运行写入负载，可选地同时运行并发读者，
+// 输出吞吐量、写入和提交的延迟分位数以及文件的增长。
+// 没有指定文件时使用临时文件，并在结束后删除；指定的文件必须不存在。
+func runBench(args []string, w io.Writer) error {
+	fs := newFlagSet("bench")
+	keySize := fs.Int("key-size", 8, "key size in bytes, at least 8")
+	valueSize := fs.Int("value-size", 100, "value size in bytes")
+	batch := fs.Int("batch", 1000, "puts per write transaction")
+	random := fs.Bool("random", false, "write keys in random order instead of sequentially")
+	noSync := fs.Bool("nosync", false, "do not fsync the data file on commit")
+	duration := fs.Duration("duration", 10*time.Second, "how long to run")
+	readers := fs.Int("readers", 0, "concurrent readers doing random gets")
+	if err := parseArgs(fs, args, 0, 1); err != nil {
+		return err
+	}
+	if *keySize < 8 || *valueSize < 0 || *batch < 1 || *duration <= 0 || *readers < 0 {
+		return fmt.Errorf("bench: invalid option: %w", usageError)
+	}
+
+	path := fs.Arg(0)
+	if path == "" {
+		f, err := os.CreateTemp("", "bolt-bench-")
+		if err != nil {
+			return err
+		}
+		path = f.Name()
+		f.Close()
+		defer os.Remove(path)
+	} else {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if err != nil {
+			return err
+		}
+		f.Close()
+	}
+
+	db := bolt.NewDB()
+	if err := db.Open(path, 0666); err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := db.SetFlags(bolt.NoSync, *noSync); err != nil {
+		return err
+	}
+	before, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	b := &bencher{db: db, keySize: *keySize, value: make([]byte, *valueSize), batch: *batch, random: *random}
+	res, err := b.run(*duration, *readers)
+	if err != nil {
+		return err
+	}
+	after, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	t := newTable(w)
+	secs := res.elapsed.Seconds()
+	fmt.Fprintf(t, "Puts:\t%d in %s\t%.0f ops/s\n", len(res.puts), res.elapsed.Round(time.Millisecond), float64(len(res.puts))/secs)
+	fmt.Fprintf(t, "Commits:\t%d\t%.0f ops/s\n", len(res.commits), float64(len(res.commits))/secs)
+	if *readers > 0 {
+		fmt.Fprintf(t, "Gets:\t%d\t%.0f ops/s\n", res.gets, float64(res.gets)/secs)
+	}
+	writeLatency(t, "Put latency", res.puts)
+	writeLatency(t, "Commit latency", res.commits)
+	fmt.Fprintf(t, "File size:\t%d -> %d bytes\t", before.Size(), after.Size())
+	if len(res.puts) > 0 {
+		fmt.Fprintf(t, "%.1f bytes/put", float64(after.Size()-before.Size())/float64(len(res.puts)))
+	}
+	fmt.Fprintln(t)
+	return t.Flush()
+}
+
+// writeLatency 输出延迟的分位数。
+func writeLatency(w io.Writer, name string, d []time.Duration) {
+	if len(d) == 0 {
+		return
+	}
+	slices.Sort(d)
+	p := func(q float64) time.Duration { return d[min(int(q*float64(len(d))), len(d)-1)] }
+	fmt.Fprintf(w, "%s:\tp50=%s p90=%s p99=%s\tmax=%s\n", name, p(0.50), p(0.90), p(0.99), d[len(d)-1])
+}
+
+// bencher 生成写入负载。
+type bencher struct {
+	db      *bolt.DB
+	keySize int
+	value   []byte
+	batch   int
+	random  bool
+}
+
+// benchResult 是一次负载运行的结果。
+type benchResult struct {
+	elapsed time.Duration
+	puts    []time.Duration // 每次写入的延迟
+	commits []time.Duration // 每次提交的延迟
+	gets    int64           // 读者完成的读取次数
+}
+
+// run 持续写入 d 时间，同时运行 readers 个随机读取已写入键的读者。
+func (b *bencher) run(d time.Duration, readers int) (*benchResult, error) {
+	res := &benchResult{}
+	var written atomic.Int64
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	var readErr atomic.Value
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if err := b.get(r, written.Load()); err != nil {
+					readErr.Store(err)
+					return
+				}
+				atomic.AddInt64(&res.gets, 1)
+			}
+		}(int64(i))
+	}
+
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	start := time.Now()
+	var err error
+	for n := int64(0); time.Since(start) < d; {
+		if n, err = b.write(r, n, res); err != nil {
+			break
+		}
+		written.Store(n)
+	}
+	res.elapsed = time.Since(start)
+	close(done)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := readErr.Load().(error); ok {
+		return nil, e
+	}
+	return res, nil
+}
+
+// write 在一个写事务中写入 b.batch 个键，n 为已写入的键数量，返回写入后的键数量。
+func (b *bencher) write(r *rand.Rand, n int64, res *benchResult) (int64, error) {
+	txn, err := b.db.Transaction(nil, 0)
+	if err != nil {
+		return n, err
+	}
+	main, err := txn.Bucket("", 0)
+	if err != nil {
+		txn.Abort()
+		return n, err
+	}
+	key := make([]byte, b.keySize)
+	for i := 0; i < b.batch; i++ {
+		id := uint64(n)
+		if b.random {
+			id = r.Uint64()
+		}
+		binary.BigEndian.PutUint64(key, id)
+		start := time.Now()
+		if err := main.Put(key, b.value, 0); err != nil {
+			txn.Abort()
+			return n, err
+		}
+		res.puts = append(res.puts, time.Since(start))
+		n++
+	}
+	start := time.Now()
+	if err := txn.Commit(); err != nil {
+		return n, err
+	}
+	res.commits = append(res.commits, time.Since(start))
+	return n, nil
+}
+
+// get 在一个只读事务中读取一个随机的键，随机写入时该键可能不存在。
+func (b *bencher) get(r *rand.Rand, written int64) error {
+	txn, err := b.db.Transaction(nil, bolt.ReadOnly)
+	if err != nil {
+		return err
+	}
+	defer txn.Abort()
+	main, err := txn.Bucket("", 0)
+	if err != nil {
+		return err
+	}
+	key := make([]byte, b.keySize)
+	if written > 0 {
+		binary.BigEndian.PutUint64(key, uint64(r.Int63n(written)))
+	}
+	if _, err := main.Get(key); err != nil && err != bolt.NotFoundError {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// runCheck 检查数据库的一致性，逐行输出发现的问题；存在问题时返回错误，使进程以非零状态退出。
+func runCheck(args []string, w io.Writer) error {
+	fs := newFlagSet("check")
+	asJSON := fs.Bool("json", false, "output JSON")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	db, err := openDB(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	errs, err := db.Check()
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		if err := writeJSON(w, errs); err != nil {
+			return err
+		}
+	} else {
+		for _, e := range errs {
+			fmt.Fprintln(w, e)
+		}
+		if len(errs) == 0 {
+			fmt.Fprintln(w, "OK")
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d problems found", len(errs))
+	}
+	return nil
+}
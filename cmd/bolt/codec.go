@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// codec 是命令行中键和值的编码方式。
+type codec string
+
+const (
+	codecRaw    codec = "raw"
+	codecHex    codec = "hex"
+	codecBase64 codec = "base64"
+)
+
+// parseCodec 解析编码名称，"utf-8" 和 "utf8" 是 raw 的别名。
+func parseCodec(name string) (codec, error) {
+	switch name {
+	case "raw", "utf-8", "utf8":
+		return codecRaw, nil
+	case "hex":
+		return codecHex, nil
+	case "base64":
+		return codecBase64, nil
+	}
+	return "", fmt.Errorf("unknown encoding %q, want raw, hex or base64", name)
+}
+
+func (c codec) encode(b []byte) string {
+	switch c {
+	case codecHex:
+		return hex.EncodeToString(b)
+	case codecBase64:
+		return base64.StdEncoding.EncodeToString(b)
+	}
+	return string(b)
+}
+
+func (c codec) decode(s string) ([]byte, error) {
+	switch c {
+	case codecHex:
+		return hex.DecodeString(s)
+	case codecBase64:
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return []byte(s), nil
+}
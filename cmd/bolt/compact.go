@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+
+	bolt "boltdb-go"
+)
+
+// transaction 是命令中使用的事务方法。
+type transaction interface {
+	Bucket(name string, flags int) (*bolt.Bucket, error)
+	Commit() error
+	Abort()
+}
+
+// runCompact 将数据库中的所有存储桶按键的顺序重新写入一个新文件，输出压缩前后的大小以及每个存储桶的统计。
+// 输出文件已存在时拒绝执行。
+func runCompact(args []string, w io.Writer) error {
+	fs := newFlagSet("compact")
+	output := fs.String("o", "", "output file, must not exist")
+	fill := fs.Float64("fill", bolt.MaxFillPercent, "target page fill factor")
+	txMax := fs.Int("tx-max-size", 64<<20, "write dirty pages to the output file once the transaction holds this many bytes, 0 keeps them all in memory")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	if *output == "" {
+		return fmt.Errorf("compact: missing -o: %w", usageError)
+	}
+	if *fill < bolt.MinFillPercent || *fill > bolt.MaxFillPercent {
+		return fmt.Errorf("compact: -fill must be in [%g, %g]: %w", bolt.MinFillPercent, bolt.MaxFillPercent, usageError)
+	}
+	if *txMax < 0 {
+		return fmt.Errorf("compact: -tx-max-size must not be negative: %w", usageError)
+	}
+
+	src, err := openDB(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	// 以 O_EXCL 创建输出文件，避免覆盖已有的文件。
+	f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	// 收到中断信号时中止复制并删除输出文件。
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	c := &compactor{ctx: ctx, fill: *fill, max: *txMax}
+	if err := c.run(src, *output); err != nil {
+		os.Remove(*output)
+		return err
+	}
+
+	before, err := os.Stat(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	after, err := os.Stat(*output)
+	if err != nil {
+		return err
+	}
+	t := newTable(w)
+	fmt.Fprintf(t, "Before:\t%d bytes\n", before.Size())
+	fmt.Fprintf(t, "After:\t%d bytes (%.1f%%)\n", after.Size(), 100*float64(after.Size())/float64(before.Size()))
+	fmt.Fprintln(t)
+	fmt.Fprintln(t, "BUCKET\tENTRIES\tBYTES")
+	for _, s := range c.stats {
+		fmt.Fprintf(t, "%s\t%d\t%d\n", s.name, s.entries, s.bytes)
+	}
+	return t.Flush()
+}
+
+// compactStat 是一个存储桶的压缩统计。
+type compactStat struct {
+	name    string
+	entries int
+	bytes   int64
+}
+
+// compactor 在一个写事务中将源数据库的存储桶复制到目标数据库。
+// 写事务的脏页超过 max 字节时被写入输出文件，使内存占用与文件大小无关；
+// 多次提交会留下写时复制替换掉的页面，而单个写事务的输出与一次写入内存的结果相同。
+// ctx 被取消时复制中止并返回 ctx.Err()。
+type compactor struct {
+	ctx   context.Context
+	fill  float64
+	max   int
+	dst   *bolt.DB
+	txn   transaction
+	b     *bolt.Bucket // 正在写入的存储桶
+	path  []string     // b 的路径
+	stats []*compactStat
+}
+
+func (c *compactor) run(src *bolt.DB, output string) error {
+	c.dst = bolt.NewDB()
+	if err := c.dst.Open(output, 0666); err != nil {
+		return err
+	}
+	defer c.dst.Close()
+	if err := c.dst.SetMaxDirtySize(c.max); err != nil {
+		return err
+	}
+
+	stxn, err := src.TransactionContext(c.ctx, nil, bolt.ReadOnly)
+	if err != nil {
+		return err
+	}
+	defer stxn.Abort()
+	main, err := stxn.Bucket("", 0)
+	if err != nil {
+		return err
+	}
+	t, err := c.dst.TransactionContext(c.ctx, nil, 0)
+	if err != nil {
+		return err
+	}
+	c.txn = t
+	if err := c.copy(main, nil); err != nil {
+		c.txn.Abort()
+		return err
+	}
+	return c.txn.Commit()
+}
+
+// bucket 返回目标数据库中路径为 path 的存储桶，最后一级不存在时以 flags 创建。
+func (c *compactor) bucket(path []string, flags int) (*bolt.Bucket, error) {
+	if c.b != nil && slices.Equal(c.path, path) {
+		return c.b, nil
+	}
+	main, err := c.txn.Bucket("", 0)
+	if err != nil {
+		return nil, err
+	}
+	b, err := createBuckets(main, path, flags)
+	if err != nil {
+		return nil, err
+	}
+	b.FillPercent = c.fill
+	c.b, c.path = b, path
+	return b, nil
+}
+
+// copy 复制存储桶 src 中的键值对、序列号以及所有子存储桶。
+func (c *compactor) copy(src *bolt.Bucket, path []string) error {
+	stat := &compactStat{name: strings.Join(append([]string{"main"}, path...), "/")}
+	c.stats = append(c.stats, stat)
+	b, err := c.bucket(path, src.Flags())
+	if err != nil {
+		return err
+	}
+	if err := b.SetSequence(src.Sequence()); err != nil {
+		return err
+	}
+
+	flags := bolt.Append
+	if src.Flags()&bolt.DupSort != 0 {
+		flags |= bolt.AppendDup
+	}
+	var children []string
+	err = walkBucket(src, func(k, v []byte, isBucket bool) error {
+		if isBucket {
+			children = append(children, string(k))
+			return nil
+		}
+		stat.entries++
+		stat.bytes += int64(len(k) + len(v))
+		return c.put(path, src.Flags(), k, v, flags)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range children {
+		child, err := src.Bucket(name)
+		if err != nil {
+			return err
+		}
+		if err := c.copy(child, append(path[:len(path):len(path)], name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// put 在目标存储桶中写入一个键值对。
+func (c *compactor) put(path []string, bflags int, k, v []byte, flags int) error {
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	b, err := c.bucket(path, bflags)
+	if err != nil {
+		return err
+	}
+	return b.Put(k, v, flags)
+}
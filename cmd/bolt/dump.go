@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	bolt "boltdb-go"
+)
+
+// dumpVersion 是 mdb_dump 文本格式的版本号。
+const dumpVersion = 3
+
+// dumpFlags 是 mdb_dump 头部中的存储桶标志位名称。
+var dumpFlags = []struct {
+	name string
+	flag int
+}{
+	{"duplicates", bolt.DupSort},
+	{"integerkey", bolt.IntegerKey},
+	{"integerdup", bolt.IntegerDupKey},
+}
+
+// runDump 以 mdb_dump 文本格式输出主存储桶以及所有子存储桶。
+// 主存储桶中的子存储桶记录不作为键值对输出，子存储桶以 "/" 连接的路径作为 database 名称。
+func runDump(args []string, w io.Writer) error {
+	fs := newFlagSet("dump")
+	printable := fs.Bool("p", false, "output printable characters as-is")
+	name := fs.String("s", "", "dump only the named bucket, nested buckets separated by /")
+	output := fs.String("o", "", "write to file instead of stdout")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	db, err := openDB(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *output != "" {
+		f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	d := &dumper{w: bw, printable: *printable, info: db.Info()}
+
+	txn, err := db.Transaction(nil, bolt.ReadOnly)
+	if err != nil {
+		return err
+	}
+	defer txn.Abort()
+	main, err := txn.Bucket("", 0)
+	if err != nil {
+		return err
+	}
+	b, err := openBucketPath(main, *name)
+	if err != nil {
+		return err
+	}
+	if err := d.dump(b, *name, *name == ""); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// dumper 以 mdb_dump 文本格式输出存储桶。
+type dumper struct {
+	w         *bufio.Writer
+	printable bool
+	info      *bolt.Info
+}
+
+// dump 输出存储桶 b 中的键值对，recursive 为 true 时随后输出它的所有子存储桶。
+func (d *dumper) dump(b *bolt.Bucket, name string, recursive bool) error {
+	d.header(name, b.Flags())
+	var children []string
+	err := walkBucket(b, func(k, v []byte, isBucket bool) error {
+		if isBucket {
+			children = append(children, string(k))
+		} else {
+			d.value(k)
+			d.value(v)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	d.w.WriteString("DATA=END\n")
+
+	if !recursive {
+		return nil
+	}
+	for _, child := range children {
+		cb, err := b.Bucket(child)
+		if err != nil {
+			return err
+		}
+		path := child
+		if name != "" {
+			path = name + "/" + child
+		}
+		if err := d.dump(cb, path, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// header 输出一个数据库的头部，name 为空表示主存储桶。
+func (d *dumper) header(name string, flags int) {
+	fmt.Fprintf(d.w, "VERSION=%d\n", dumpVersion)
+	if d.printable {
+		d.w.WriteString("format=print\n")
+	} else {
+		d.w.WriteString("format=bytevalue\n")
+	}
+	if name != "" {
+		d.w.WriteString("database=")
+		d.w.WriteString(name)
+		d.w.WriteByte('\n')
+	}
+	d.w.WriteString("type=btree\n")
+	fmt.Fprintf(d.w, "mapsize=%d\n", d.info.MapSize)
+	fmt.Fprintf(d.w, "maxreaders=%d\n", d.info.MaxReaders)
+	fmt.Fprintf(d.w, "db_pagesize=%d\n", d.info.PageSize)
+	for _, f := range dumpFlags {
+		if flags&f.flag != 0 {
+			fmt.Fprintf(d.w, "%s=1\n", f.name)
+		}
+	}
+	d.w.WriteString("HEADER=END\n")
+}
+
+// value 输出一个键或值，每行以空格开头。
+// print 格式中可打印字符原样输出，反斜杠转义为 "\\"，其他字节输出为 "\" 加两位十六进制数。
+func (d *dumper) value(b []byte) {
+	d.w.WriteByte(' ')
+	if !d.printable {
+		d.w.WriteString(hex.EncodeToString(b))
+	} else {
+		for _, c := range b {
+			switch {
+			case c == '\\':
+				d.w.WriteString(`\\`)
+			case c >= 0x20 && c < 0x7f:
+				d.w.WriteByte(c)
+			default:
+				fmt.Fprintf(d.w, `\%02x`, c)
+			}
+		}
+	}
+	d.w.WriteByte('\n')
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	bolt "boltdb-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// 确保键和值在两种格式下都可以无损地编码和解码。
+func TestDump_Value(t *testing.T) {
+	v := []byte("a\\b\x00\xff c\n")
+	for _, printable := range []bool{false, true} {
+		var buf bytes.Buffer
+		d := &dumper{w: bufio.NewWriter(&buf), printable: printable}
+		d.value(v)
+		d.w.Flush()
+		if printable {
+			assert.Equal(t, " a\\\\b\\00\\ff c\\0a\n", buf.String())
+		} else {
+			assert.Equal(t, " 615c6200ff20630a\n", buf.String())
+		}
+		b, err := decodeValue(strings.TrimSuffix(buf.String()[1:], "\n"), printable)
+		assert.NoError(t, err)
+		assert.Equal(t, v, b)
+	}
+}
+
+// 确保可以读取 mdb_dump 输出的多个 database，并识别头部中的标志位。
+func TestLoad_Read(t *testing.T) {
+	input := "VERSION=3\nformat=print\ntype=btree\nmapsize=1048576\nmaxreaders=126\ndb_pagesize=4096\nHEADER=END\n" +
+		" foo\n bar\nDATA=END\n" +
+		"VERSION=3\nformat=bytevalue\ndatabase=users\ntype=btree\nduplicates=1\nHEADER=END\n" +
+		" 6b\n 7631\n 6b\n 7632\nDATA=END\n"
+	d := &dumpReader{r: bufio.NewReader(strings.NewReader(input))}
+
+	h, err := d.header()
+	assert.NoError(t, err)
+	assert.Equal(t, &dumpHeader{printable: true}, h)
+	k, v, err := d.record(h)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("foo"), k)
+	assert.Equal(t, []byte("bar"), v)
+	_, _, err = d.record(h)
+	assert.Equal(t, io.EOF, err)
+
+	h, err = d.header()
+	assert.NoError(t, err)
+	assert.Equal(t, &dumpHeader{database: "users", flags: bolt.DupSort}, h)
+	for _, want := range []string{"v1", "v2"} {
+		k, v, err = d.record(h)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("k"), k)
+		assert.Equal(t, []byte(want), v)
+	}
+	_, _, err = d.record(h)
+	assert.Equal(t, io.EOF, err)
+	_, err = d.header()
+	assert.Equal(t, io.EOF, err)
+}
+
+// 确保格式错误的输入返回带行号的错误。
+func TestLoad_Invalid(t *testing.T) {
+	for _, input := range []string{
+		"format=print\nHEADER=END\n",
+		"VERSION=2\nHEADER=END\n",
+		"VERSION=3\ntype=hash\nHEADER=END\n",
+		"VERSION=3\nbogus=1\nHEADER=END\n",
+		"VERSION=3\n",
+	} {
+		d := &dumpReader{r: bufio.NewReader(strings.NewReader(input))}
+		_, err := d.header()
+		assert.ErrorContains(t, err, "line ", input)
+	}
+}
+
+// 确保 dump 的输出可以被 load 读取。
+func TestDumpLoad(t *testing.T) {
+	path := newTestDB(t)
+	var buf bytes.Buffer
+	assert.NoError(t, run([]string{"dump", "-p", path}, &buf))
+	assert.True(t, strings.HasPrefix(buf.String(), "VERSION=3\nformat=print\ntype=btree\n"))
+	assert.True(t, strings.HasSuffix(buf.String(), "HEADER=END\nDATA=END\n"))
+
+	out := path + ".dump"
+	assert.NoError(t, run([]string{"dump", "-o", out, path}, io.Discard))
+	assert.Error(t, run([]string{"dump", "-o", out, path}, io.Discard))
+	assert.NoError(t, run([]string{"load", "-a", "-f", out, path + ".2"}, io.Discard))
+
+	// 已经存在的存储桶的标志位与头部不一致时失败。
+	in := path + ".in"
+	assert.NoError(t, os.WriteFile(in, []byte("VERSION=3\ndatabase=d\nHEADER=END\nDATA=END\n"), 0666))
+	assert.NoError(t, run([]string{"load", "-f", in, path}, io.Discard))
+	assert.NoError(t, run([]string{"load", "-f", in, path}, io.Discard))
+	assert.NoError(t, os.WriteFile(in, []byte("VERSION=3\ndatabase=d\nduplicates=1\nHEADER=END\nDATA=END\n"), 0666))
+	assert.ErrorContains(t, run([]string{"load", "-f", in, path}, io.Discard), "do not match the header")
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"unicode/utf8"
+
+	bolt "boltdb-go"
+)
+
+// recordBucket 是描述存储桶本身的记录的类型。
+const recordBucket = "bucket"
+
+// exportRecord 是 NDJSON 导出格式中的一行。
+// Bucket 是存储桶的路径，每个元素是按键的编码参数编码的一级存储桶名称，空数组表示主存储桶。
+// Type 为 "bucket" 的记录描述路径为 Bucket 的存储桶本身，带有它的标志位和序列号，
+// 在存储桶的键值对之前输出，使空存储桶和序列号也能被导入；没有 Type 的记录是键值对。
+// Dup 只在 DupSort 存储桶中输出，表示该值在同一个键的重复值中的序号。
+type exportRecord struct {
+	Type     string   `json:"type,omitempty"`
+	Bucket   []string `json:"bucket"`
+	Key      string   `json:"key,omitempty"`
+	Value    string   `json:"value,omitempty"`
+	Dup      *int     `json:"dup,omitempty"`
+	Flags    int      `json:"flags,omitempty"`
+	Sequence uint64   `json:"sequence,omitempty"`
+}
+
+// same 返回 r 和 o 是否指向同一条记录。
+func (r *exportRecord) same(o *exportRecord) bool {
+	if r.Type != o.Type || !slices.Equal(r.Bucket, o.Bucket) || r.Key != o.Key || (r.Dup == nil) != (o.Dup == nil) {
+		return false
+	}
+	return r.Dup == nil || *r.Dup == *o.Dup
+}
+
+// recordCodecs 是导出和导入共用的键和值的编码参数。
+type recordCodecs struct {
+	key   *string
+	value *string
+}
+
+func addRecordCodecs(fs *flag.FlagSet) *recordCodecs {
+	return &recordCodecs{
+		key:   fs.String("key-encoding", "base64", "encoding of keys: base64, hex or utf-8"),
+		value: fs.String("value-encoding", "base64", "encoding of values: base64, hex or utf-8"),
+	}
+}
+
+func (r *recordCodecs) parse() (key, value codec, err error) {
+	if key, err = parseCodec(*r.key); err != nil {
+		return "", "", fmt.Errorf("-key-encoding: %v: %w", err, usageError)
+	}
+	if value, err = parseCodec(*r.value); err != nil {
+		return "", "", fmt.Errorf("-value-encoding: %v: %w", err, usageError)
+	}
+	return key, value, nil
+}
+
+// encodeField 编码一个键或值，utf-8 编码时拒绝不是合法 UTF-8 的数据，避免 JSON 编码时被替换而丢失。
+func encodeField(c codec, b []byte) (string, error) {
+	if c == codecRaw && !utf8.Valid(b) {
+		return "", errors.New("not valid utf-8, use hex or base64")
+	}
+	return c.encode(b), nil
+}
+
+// runExport 以 NDJSON 格式输出存储桶以及它的所有子存储桶，每行一条记录。
+// 每个存储桶先输出一条存储桶记录，再输出它的键值对。
+func runExport(args []string, w io.Writer) error {
+	fs := newFlagSet("export")
+	name := fs.String("b", "", "export only the named bucket and its nested buckets")
+	output := fs.String("o", "", "write to file instead of stdout")
+	rc := addRecordCodecs(fs)
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	kc, vc, err := rc.parse()
+	if err != nil {
+		return err
+	}
+	db, err := openDB(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *output != "" {
+		f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	txn, err := db.Transaction(nil, bolt.ReadOnly)
+	if err != nil {
+		return err
+	}
+	defer txn.Abort()
+	main, err := txn.Bucket("", 0)
+	if err != nil {
+		return err
+	}
+	names := splitPath(*name)
+	b, err := openBuckets(main, names)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	e := &exporter{enc: json.NewEncoder(bw), key: kc, value: vc}
+	path := []string{}
+	for _, n := range names {
+		if path, err = e.child(path, n); err != nil {
+			return err
+		}
+	}
+	if err := e.export(b, path); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// exporter 以 NDJSON 格式输出存储桶。
+type exporter struct {
+	enc   *json.Encoder
+	key   codec
+	value codec
+}
+
+// export 输出存储桶 b 的存储桶记录和它的键值对，然后按顺序输出它的子存储桶，path 是 b 编码后的路径。
+func (e *exporter) export(b *bolt.Bucket, path []string) error {
+	if err := e.enc.Encode(&exportRecord{Type: recordBucket, Bucket: path, Flags: b.Flags(), Sequence: b.Sequence()}); err != nil {
+		return err
+	}
+	dupSort := b.Flags()&bolt.DupSort != 0
+	var children []string
+	var prev []byte
+	dup := 0
+	err := walkBucket(b, func(k, v []byte, isBucket bool) error {
+		if isBucket {
+			children = append(children, string(k))
+			return nil
+		}
+		r := &exportRecord{Bucket: path}
+		if dupSort {
+			if prev != nil && bytes.Equal(prev, k) {
+				dup++
+			} else {
+				prev, dup = append(prev[:0], k...), 0
+			}
+			r.Dup = &dup
+		}
+		var err error
+		if r.Key, err = encodeField(e.key, k); err != nil {
+			return fmt.Errorf("bucket %q: key %q: %w", path, k, err)
+		}
+		if r.Value, err = encodeField(e.value, v); err != nil {
+			return fmt.Errorf("bucket %q: value of key %q: %w", path, k, err)
+		}
+		return e.enc.Encode(r)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range children {
+		child, err := b.Bucket(name)
+		if err != nil {
+			return err
+		}
+		p, err := e.child(path, name)
+		if err != nil {
+			return err
+		}
+		if err := e.export(child, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// child 返回路径 path 下名为 name 的子存储桶编码后的路径，不修改 path。
+func (e *exporter) child(path []string, name string) ([]string, error) {
+	n, err := encodeField(e.key, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("bucket %q: name %q: %w", path, name, err)
+	}
+	return append(path[:len(path):len(path)], n), nil
+}
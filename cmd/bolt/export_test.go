@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	bolt "boltdb-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// 确保记录按编码参数编码，utf-8 编码拒绝不合法的数据，DupSort 存储桶以外不输出 dup。
+func TestExport_Record(t *testing.T) {
+	var buf bytes.Buffer
+	e := &exporter{enc: json.NewEncoder(&buf), key: codecRaw, value: codecHex}
+	k, err := encodeField(e.key, []byte("k"))
+	assert.NoError(t, err)
+	assert.NoError(t, e.enc.Encode(&exportRecord{Bucket: []string{"a/b"}, Key: k, Value: e.value.encode([]byte{0xff})}))
+	assert.Equal(t, `{"bucket":["a/b"],"key":"k","value":"ff"}`+"\n", buf.String())
+
+	_, err = encodeField(codecRaw, []byte{0xff})
+	assert.Error(t, err)
+
+	one, two := 1, 1
+	assert.True(t, (&exportRecord{Key: "k", Dup: &one}).same(&exportRecord{Key: "k", Dup: &two}))
+	assert.False(t, (&exportRecord{Key: "k", Dup: &one}).same(&exportRecord{Key: "k"}))
+}
+
+// 确保导入按批次提交，中断后从检查点继续，并在完成后删除检查点文件。
+func TestImport(t *testing.T) {
+	path := newTestDB(t)
+	checkpoint := path + ".checkpoint"
+	input := `{"bucket":[],"key":"YQ==","value":"MQ=="}
+{"bucket":["dXNlcnM="],"key":"Yg==","value":"Mg==","dup":0}
+
+{"bucket":["dXNlcnM="],"key":"Yg==","value":"Mw==","dup":1}
+`
+	assert.NoError(t, os.WriteFile(checkpoint, []byte(`{"bucket":["dXNlcnM="],"key":"Yg==","value":"Mg==","dup":0}`), 0666))
+	defer func(r io.Reader) { stdin = r }(stdin)
+	stdin = strings.NewReader(input)
+	var buf bytes.Buffer
+	assert.NoError(t, run([]string{"import", "-batch", "1", "-checkpoint", checkpoint, path}, &buf))
+	assert.Equal(t, "imported 1 records in 1 transactions, skipped 2\n", buf.String())
+	assert.NoFileExists(t, checkpoint)
+	buf.Reset()
+	assert.NoError(t, run([]string{"keys", "-values", path}, &buf))
+	assert.NoError(t, run([]string{"keys", "-b", "users", "-values", path}, &buf))
+	assert.Equal(t, "b\t3\n", buf.String())
+
+	// 导入中途失败时检查点指向最后一条已提交的记录。
+	path2 := path + ".2"
+	stdin = strings.NewReader(input + "{\n")
+	err := run([]string{"import", "-batch", "2", "-checkpoint", checkpoint, path2}, io.Discard)
+	assert.ErrorContains(t, err, "line 5:")
+	b, err := os.ReadFile(checkpoint)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"bucket":["dXNlcnM="],"key":"Yg==","value":"Mg==","dup":0}`+"\n", string(b))
+	// 第一个批次已提交，失败的批次被回滚。
+	buf.Reset()
+	assert.NoError(t, run([]string{"keys", "-values", path2}, &buf))
+	assert.Equal(t, "a\t1\n", buf.String())
+	buf.Reset()
+	assert.NoError(t, run([]string{"keys", "-b", "users", "-values", path2}, &buf))
+	assert.Equal(t, "b\t2\n", buf.String())
+
+	stdin = strings.NewReader(`{"bucket":[],"key":"a","value":"1"}` + "\n")
+	assert.ErrorContains(t, run([]string{"import", "-checkpoint", checkpoint, path}, io.Discard), "checkpoint record not found")
+	assert.ErrorIs(t, run([]string{"import", "-key-encoding", "rot13", path}, io.Discard), usageError)
+}
+
+// 确保提交之后、写入检查点之前中断的导入可以继续：已提交批次中相同的记录被跳过，不同的记录仍然失败。
+func TestImportResumeCommitted(t *testing.T) {
+	defer func(r io.Reader) { stdin = r }(stdin)
+	path := newTestDB(t)
+	checkpoint := path + ".checkpoint"
+	a := `{"bucket":[],"key":"YQ==","value":"MQ=="}`
+	b := `{"bucket":[],"key":"Yg==","value":"Mg=="}`
+	c := `{"bucket":[],"key":"Yw==","value":"Mw=="}`
+	stdin = strings.NewReader(a + "\n" + b + "\n")
+	assert.NoError(t, run([]string{"import", path}, io.Discard))
+	assert.NoError(t, os.WriteFile(checkpoint, []byte(a), 0666))
+
+	var buf bytes.Buffer
+	stdin = strings.NewReader(a + "\n" + b + "\n" + c + "\n")
+	assert.NoError(t, run([]string{"import", "-batch", "1", "-checkpoint", checkpoint, path}, &buf))
+	assert.Equal(t, "imported 1 records in 1 transactions, skipped 2\n", buf.String())
+	assert.NoFileExists(t, checkpoint)
+	buf.Reset()
+	assert.NoError(t, run([]string{"keys", "-values", path}, &buf))
+	assert.Equal(t, "a\t1\nb\t2\nc\t3\n", buf.String())
+
+	assert.NoError(t, os.WriteFile(checkpoint, []byte(a), 0666))
+	stdin = strings.NewReader(a + "\n" + `{"bucket":[],"key":"Yg==","value":"NA=="}` + "\n")
+	assert.ErrorIs(t, run([]string{"import", "-batch", "1", "-checkpoint", checkpoint, path}, io.Discard), bolt.KeyExistError)
+}
+
+// 确保不能原样写入的记录使导入失败：已存在的键、已存在的重复值以及与存储桶标志不一致的记录。
+func TestImportRoundTrip(t *testing.T) {
+	defer func(r io.Reader) { stdin = r }(stdin)
+	path := newTestDB(t)
+	stdin = strings.NewReader(`{"bucket":[],"key":"YQ==","value":"MQ=="}` + "\n" + `{"bucket":["ZA=="],"key":"YQ==","value":"MQ==","dup":0}` + "\n")
+	assert.NoError(t, run([]string{"import", path}, io.Discard))
+
+	for _, input := range []string{
+		`{"bucket":[],"key":"YQ==","value":"Mg=="}`,
+		`{"bucket":["ZA=="],"key":"YQ==","value":"MQ==","dup":0}`,
+	} {
+		stdin = strings.NewReader(input + "\n")
+		assert.ErrorIs(t, run([]string{"import", path}, io.Discard), bolt.KeyExistError)
+	}
+	stdin = strings.NewReader(`{"bucket":["ZA=="],"key":"Yg==","value":"MQ=="}` + "\n")
+	assert.ErrorContains(t, run([]string{"import", path}, io.Discard), "DupSort flag does not match")
+	stdin = strings.NewReader(`{"type":"bucket","bucket":["ZA=="]}` + "\n")
+	assert.ErrorContains(t, run([]string{"import", path}, io.Discard), "do not match the record")
+
+	var buf bytes.Buffer
+	assert.NoError(t, run([]string{"keys", "-values", path}, &buf))
+	assert.NoError(t, run([]string{"keys", "-b", "d", "-values", path}, &buf))
+	assert.Equal(t, "a\t1\na\t1\n", buf.String())
+}
+
+// 确保 export 的输出可以被 import 读取，名称中带有 "/" 的存储桶、空存储桶、标志位和序列号都被保留。
+func TestExportImport(t *testing.T) {
+	path := newTestDB(t)
+	db := bolt.NewDB()
+	assert.NoError(t, db.Open(path, 0666))
+	txn, _ := db.Transaction(nil, 0)
+	main, _ := txn.Bucket("", 0)
+	ab, err := main.CreateBucket("a/b", 0)
+	assert.NoError(t, err)
+	assert.NoError(t, ab.Put([]byte("k"), []byte("v"), 0))
+	assert.NoError(t, ab.SetSequence(7))
+	_, err = ab.CreateBucket("empty", bolt.DupSort)
+	assert.NoError(t, err)
+	assert.NoError(t, txn.Commit())
+	db.Close()
+
+	out := path + ".ndjson"
+	assert.NoError(t, run([]string{"export", "-key-encoding", "utf-8", "-o", out, path}, io.Discard))
+	assert.Error(t, run([]string{"export", "-o", out, path}, io.Discard))
+
+	f, err := os.Open(out)
+	assert.NoError(t, err)
+	defer f.Close()
+	for s := bufio.NewScanner(f); s.Scan(); {
+		assert.NoError(t, json.Unmarshal(s.Bytes(), &exportRecord{}))
+	}
+	path2 := path + ".2"
+	assert.NoError(t, run([]string{"import", "-key-encoding", "utf-8", "-f", out, path2}, io.Discard))
+
+	db = bolt.NewDB()
+	assert.NoError(t, db.Open(path2, 0666))
+	defer db.Close()
+	txn, _ = db.Transaction(nil, bolt.ReadOnly)
+	defer txn.Abort()
+	main, _ = txn.Bucket("", 0)
+	ab, err = main.Bucket("a/b")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(7), ab.Sequence())
+	v, err := ab.Get([]byte("k"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v"), v)
+	empty, err := ab.Bucket("empty")
+	assert.NoError(t, err)
+	assert.Equal(t, bolt.DupSort, empty.Flags())
+	_, err = main.Bucket("a")
+	assert.Equal(t, bolt.NotFoundError, err)
+}
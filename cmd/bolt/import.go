@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	bolt "boltdb-go"
+)
+
+// runImport 读取 NDJSON 格式的记录并写入数据库，数据库文件不存在时创建它。
+// 存储桶记录创建它描述的存储桶并设置序列号，存储桶已经存在时其标志位必须与记录一致。
+// 键值对记录必须能原样写入：普通存储桶中的键已存在、DupSort 存储桶中的键值对已存在，
+// 或者记录是否带有 dup 序号与存储桶的 DupSort 标志不一致时返回错误。
+// 每 -batch 条记录提交一次写事务；指定 -checkpoint 时每次提交后把最后一条已提交的记录写入检查点文件，
+// 中断后使用相同的参数重新运行会跳过检查点之前的记录，全部导入完成后删除检查点文件。
+// 提交之后、写入检查点之前中断时，检查点之后的一个批次已经提交，
+// 因此指定 -checkpoint 时本次运行写入的第一个批次中与数据库中完全相同的记录被当作已导入而跳过。
+func runImport(args []string, w io.Writer) error {
+	fs := newFlagSet("import")
+	input := fs.String("f", "", "read from file instead of stdin")
+	batch := fs.Int("batch", 10000, "records per write transaction")
+	checkpoint := fs.String("checkpoint", "", "checkpoint file for resuming an interrupted import")
+	rc := addRecordCodecs(fs)
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	if *batch < 1 {
+		return fmt.Errorf("import: -batch must be positive: %w", usageError)
+	}
+	kc, vc, err := rc.parse()
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = stdin
+	if *input != "" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	im := &importer{batch: *batch, checkpoint: *checkpoint, key: kc, value: vc}
+	if *checkpoint != "" {
+		im.redo = *batch
+	}
+	if im.resume, err = readCheckpoint(*checkpoint); err != nil {
+		return err
+	}
+
+	im.db = bolt.NewDB()
+	if err := im.db.Open(fs.Arg(0), 0666); err != nil {
+		return err
+	}
+	defer im.db.Close()
+	if err := im.run(r); err != nil {
+		return err
+	}
+	if *checkpoint != "" {
+		if err := os.Remove(*checkpoint); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "imported %d records in %d transactions, skipped %d\n", im.imported, im.commits, im.skipped)
+	return err
+}
+
+// readCheckpoint 读取检查点文件中的记录，文件不存在时返回 nil。
+func readCheckpoint(path string) (*exportRecord, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	r := &exportRecord{}
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// importer 把记录写入数据库，写入的记录达到 batch 条时提交当前写事务并开始新的写事务。
+type importer struct {
+	db         *bolt.DB
+	batch      int
+	checkpoint string
+	key        codec
+	value      codec
+	resume     *exportRecord // 检查点中的记录，找到它之前的记录都被跳过
+	redo       int           // 之后还有多少条记录可能已经在中断前提交
+
+	txn     transaction
+	b       *bolt.Bucket  // 当前写事务中正在写入的存储桶，提交后失效
+	path    []string      // b 编码后的路径
+	pending int           // 当前写事务中的记录数
+	last    *exportRecord // 当前写事务中的最后一条记录
+	line    int
+
+	imported, skipped, commits int
+}
+
+func (im *importer) run(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64<<10), 256<<20)
+	for s.Scan() {
+		im.line++
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+		rec := &exportRecord{}
+		if err := json.Unmarshal(s.Bytes(), rec); err != nil {
+			return im.abort(err)
+		}
+		if im.resume != nil {
+			if rec.same(im.resume) {
+				im.resume = nil
+			}
+			im.skipped++
+			continue
+		}
+		if err := im.put(rec); err != nil {
+			return im.abort(err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return im.abort(err)
+	}
+	if im.resume != nil {
+		return im.abort(errors.New("checkpoint record not found in input"))
+	}
+	return im.commit()
+}
+
+// abort 回滚当前写事务，返回带有行号的错误。
+func (im *importer) abort(err error) error {
+	if im.txn != nil {
+		im.txn.Abort()
+		im.txn = nil
+	}
+	return fmt.Errorf("line %d: %w", im.line, err)
+}
+
+// put 在当前写事务中写入一条记录，需要时开始新的写事务，写满 batch 条后提交。
+func (im *importer) put(rec *exportRecord) error {
+	if rec.Type != "" && rec.Type != recordBucket {
+		return fmt.Errorf("unknown record type %q", rec.Type)
+	}
+	if im.txn == nil {
+		t, err := im.db.Transaction(nil, 0)
+		if err != nil {
+			return err
+		}
+		im.txn, im.b = t, nil
+	}
+	redo := im.redo > 0
+	if redo {
+		im.redo--
+	}
+	if rec.Type == recordBucket {
+		if err := im.putBucket(rec); err != nil {
+			return err
+		}
+	} else if ok, err := im.putValue(rec, redo); err != nil {
+		return err
+	} else if !ok {
+		im.skipped++
+		return nil
+	}
+	im.pending++
+	im.last = rec
+	if im.pending >= im.batch {
+		return im.commit()
+	}
+	return nil
+}
+
+// bucket 返回当前写事务中编码后的路径为 path 的存储桶，不存在时以 flags 创建。
+func (im *importer) bucket(path []string, flags int) (*bolt.Bucket, error) {
+	if im.b != nil && slices.Equal(im.path, path) {
+		return im.b, nil
+	}
+	names := make([]string, len(path))
+	for i, n := range path {
+		name, err := im.key.decode(n)
+		if err != nil {
+			return nil, fmt.Errorf("bucket %q: %w", path, err)
+		}
+		names[i] = string(name)
+	}
+	main, err := im.txn.Bucket("", 0)
+	if err != nil {
+		return nil, err
+	}
+	b, err := createBuckets(main, names, flags)
+	if err != nil {
+		return nil, err
+	}
+	im.b, im.path = b, path
+	return b, nil
+}
+
+// putBucket 创建存储桶记录描述的存储桶，序列号只会增大，不会覆盖存储桶中更大的序列号。
+func (im *importer) putBucket(rec *exportRecord) error {
+	b, err := im.bucket(rec.Bucket, rec.Flags)
+	if err != nil {
+		return err
+	}
+	if b.Flags() != rec.Flags {
+		return fmt.Errorf("bucket %q: flags %#x do not match the record %#x", rec.Bucket, b.Flags(), rec.Flags)
+	}
+	if rec.Sequence > b.Sequence() {
+		return b.SetSequence(rec.Sequence)
+	}
+	return nil
+}
+
+// putValue 写入一个键值对，redo 表示记录可能已经在中断前提交。
+// 记录与数据库中已提交的键值对完全相同而被跳过时返回 false。
+func (im *importer) putValue(rec *exportRecord, redo bool) (bool, error) {
+	k, err := im.key.decode(rec.Key)
+	if err != nil {
+		return false, fmt.Errorf("key: %w", err)
+	}
+	v, err := im.value.decode(rec.Value)
+	if err != nil {
+		return false, fmt.Errorf("value: %w", err)
+	}
+	// 没有存储桶记录时按是否带有 dup 序号创建存储桶：带有 dup 序号的记录来自 DupSort 存储桶。
+	flags := 0
+	if rec.Dup != nil {
+		flags = bolt.DupSort
+	}
+	b, err := im.bucket(rec.Bucket, flags)
+	if err != nil {
+		return false, err
+	}
+	if (b.Flags()&bolt.DupSort != 0) != (rec.Dup != nil) {
+		return false, fmt.Errorf("bucket %q: DupSort flag does not match the record", rec.Bucket)
+	}
+	// 覆盖已有的值或忽略重复的键值对都会使导入的数据与输入不一致。
+	flags = bolt.NoOverwrite
+	if rec.Dup != nil {
+		flags = bolt.NoDupData
+	}
+	if err := b.Put(k, v, flags); err != nil {
+		if err == bolt.KeyExistError && redo && im.committed(k, v, rec) {
+			return false, nil
+		}
+		return false, fmt.Errorf("key %q: %w", rec.Key, err)
+	}
+	return true, nil
+}
+
+// committed 返回写入失败的记录是否与数据库中已有的键值对完全相同。
+// DupSort 存储桶中 NoDupData 失败本身就说明相同的键值对已经存在。
+func (im *importer) committed(k, v []byte, rec *exportRecord) bool {
+	if rec.Dup != nil {
+		return true
+	}
+	old, err := im.b.Get(k)
+	return err == nil && bytes.Equal(old, v)
+}
+
+// commit 提交当前写事务并更新检查点文件。
+func (im *importer) commit() error {
+	if im.txn == nil {
+		return nil
+	}
+	err := im.txn.Commit()
+	im.txn, im.b = nil, nil
+	if err != nil {
+		return err
+	}
+	im.imported += im.pending
+	im.commits++
+	im.pending = 0
+	if im.checkpoint == "" {
+		return nil
+	}
+	b, err := json.Marshal(im.last)
+	if err != nil {
+		return err
+	}
+	return writeCheckpoint(im.checkpoint, append(b, '\n'))
+}
+
+// writeCheckpoint 先写入并同步临时文件再重命名，最后同步所在目录，
+// 中断时检查点文件要么是旧的要么是新的，重命名之后的内容也不会因为断电而丢失。
+func writeCheckpoint(path string, b []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
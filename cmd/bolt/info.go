@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	bolt "boltdb-go"
+)
+
+// infoOutput 是 info 子命令的输出。
+type infoOutput struct {
+	*bolt.Info
+	FileSize int64
+}
+
+// runInfo 输出数据库的环境信息、文件大小以及两个 meta 页面的状态。
+func runInfo(args []string, w io.Writer) error {
+	fs := newFlagSet("info")
+	asJSON := fs.Bool("json", false, "output JSON")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	path := fs.Arg(0)
+	db, err := openDB(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	out := infoOutput{Info: db.Info(), FileSize: fi.Size()}
+	if *asJSON {
+		return writeJSON(w, out)
+	}
+	t := newTable(w)
+	fmt.Fprintf(t, "Page Size:\t%d\n", out.PageSize)
+	fmt.Fprintf(t, "File Size:\t%d\n", out.FileSize)
+	fmt.Fprintf(t, "Map Size:\t%d\n", out.MapSize)
+	fmt.Fprintf(t, "Last Page ID:\t%d\n", out.LastPageID)
+	fmt.Fprintf(t, "Last Transaction ID:\t%d\n", out.LastTransactionID)
+	fmt.Fprintf(t, "Max Readers:\t%d\n", out.MaxReaders)
+	fmt.Fprintf(t, "Reader Count:\t%d\n", out.ReaderCount)
+	for _, m := range out.Metas {
+		valid := "valid"
+		if !m.Valid {
+			valid = "invalid"
+		}
+		fmt.Fprintf(t, "Meta %d:\ttxnid=%d last_pgno=%d %s\n", m.PageID, m.LastTransactionID, m.LastPageID, valid)
+	}
+	return t.Flush()
+}
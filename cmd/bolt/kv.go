@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+
+	bolt "boltdb-go"
+)
+
+// kvFlags 是读写单个存储桶的子命令共用的参数。
+type kvFlags struct {
+	bucket   *string
+	encoding *string
+}
+
+func addKVFlags(fs *flag.FlagSet) *kvFlags {
+	return &kvFlags{
+		bucket:   fs.String("b", "", "bucket path, nested buckets separated by /"),
+		encoding: fs.String("e", "raw", "encoding of keys and values: raw, hex or base64"),
+	}
+}
+
+// withBucket 打开数据库并在一个事务中对 -b 指定的存储桶调用 fn。
+// write 为 true 时使用写事务并在 fn 成功后提交，create 为 true 时创建不存在的存储桶。
+func withBucket(path string, f *kvFlags, write, create bool, fn func(b *bolt.Bucket, c codec) error) error {
+	c, err := parseCodec(*f.encoding)
+	if err != nil {
+		return fmt.Errorf("%v: %w", err, usageError)
+	}
+	open := openDB
+	flags := bolt.ReadOnly
+	if write {
+		open, flags = openDBWritable, 0
+	}
+	db, err := open(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	txn, err := db.Transaction(nil, flags)
+	if err != nil {
+		return err
+	}
+	main, err := txn.Bucket("", 0)
+	if err != nil {
+		txn.Abort()
+		return err
+	}
+	var b *bolt.Bucket
+	if create {
+		b, err = createBucketPath(main, *f.bucket, 0)
+	} else {
+		b, err = openBucketPath(main, *f.bucket)
+	}
+	if err == nil {
+		err = fn(b, c)
+	}
+	if err != nil || !write {
+		txn.Abort()
+		return err
+	}
+	return txn.Commit()
+}
+
+// writeValue 输出一个编码后的值，raw 编码时原样输出而不追加换行符，便于在脚本中处理二进制数据。
+func writeValue(w io.Writer, c codec, v []byte) error {
+	if c == codecRaw {
+		_, err := w.Write(v)
+		return err
+	}
+	_, err := fmt.Fprintln(w, c.encode(v))
+	return err
+}
+
+// runGet 输出一个键的值，键不存在时返回错误。
+func runGet(args []string, w io.Writer) error {
+	fs := newFlagSet("get")
+	f := addKVFlags(fs)
+	if err := parseArgs(fs, args, 2, 2); err != nil {
+		return err
+	}
+	return withBucket(fs.Arg(0), f, false, false, func(b *bolt.Bucket, c codec) error {
+		k, err := c.decode(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		v, err := b.Get(k)
+		if err != nil {
+			return fmt.Errorf("key %q: %w", fs.Arg(1), err)
+		}
+		return writeValue(w, c, v)
+	})
+}
+
+// runPut 写入一个键值对。
+func runPut(args []string, w io.Writer) error {
+	fs := newFlagSet("put")
+	f := addKVFlags(fs)
+	create := fs.Bool("create", false, "create the bucket path if it does not exist")
+	if err := parseArgs(fs, args, 3, 3); err != nil {
+		return err
+	}
+	return withBucket(fs.Arg(0), f, true, *create, func(b *bolt.Bucket, c codec) error {
+		k, err := c.decode(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		v, err := c.decode(fs.Arg(2))
+		if err != nil {
+			return err
+		}
+		return b.Put(k, v, 0)
+	})
+}
+
+// runDelete 删除一个键，键不存在时返回错误。
+func runDelete(args []string, w io.Writer) error {
+	fs := newFlagSet("delete")
+	f := addKVFlags(fs)
+	if err := parseArgs(fs, args, 2, 2); err != nil {
+		return err
+	}
+	return withBucket(fs.Arg(0), f, true, false, func(b *bolt.Bucket, c codec) error {
+		k, err := c.decode(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		if err := b.Delete(k, nil); err != nil {
+			return fmt.Errorf("key %q: %w", fs.Arg(1), err)
+		}
+		return nil
+	})
+}
+
+// runKeys 按顺序每行输出一个键，可以限定前缀或 [start, end) 范围，子存储桶不输出。
+func runKeys(args []string, w io.Writer) error {
+	fs := newFlagSet("keys")
+	f := addKVFlags(fs)
+	prefix := fs.String("prefix", "", "only keys with this prefix")
+	start := fs.String("start", "", "first key of the range, inclusive")
+	end := fs.String("end", "", "last key of the range, exclusive")
+	values := fs.Bool("values", false, "print values after a tab")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	return withBucket(fs.Arg(0), f, false, false, func(b *bolt.Bucket, c codec) error {
+		p, err := c.decode(*prefix)
+		if err != nil {
+			return err
+		}
+		s, err := c.decode(*start)
+		if err != nil {
+			return err
+		}
+		e, err := c.decode(*end)
+		if err != nil {
+			return err
+		}
+		if len(p) > 0 && bytes.Compare(p, s) > 0 {
+			s = p
+		}
+		stop := func(k []byte) bool {
+			return !bytes.HasPrefix(k, p) || len(e) > 0 && bytes.Compare(k, e) >= 0
+		}
+		if len(s) == 0 {
+			s = nil
+		}
+		return scanBucket(b, s, stop, func(k, v []byte, isBucket bool) error {
+			if isBucket {
+				return nil
+			}
+			if *values {
+				_, err := fmt.Fprintf(w, "%s\t%s\n", c.encode(k), c.encode(v))
+				return err
+			}
+			_, err := fmt.Fprintln(w, c.encode(k))
+			return err
+		})
+	})
+}
+
+// runBuckets 列出存储桶中的子存储桶，-r 时递归列出所有子孙存储桶的完整路径。
+func runBuckets(args []string, w io.Writer) error {
+	fs := newFlagSet("buckets")
+	f := addKVFlags(fs)
+	recursive := fs.Bool("r", false, "list nested buckets recursively")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	return withBucket(fs.Arg(0), f, false, false, func(b *bolt.Bucket, c codec) error {
+		return listBuckets(w, b, "", *recursive)
+	})
+}
+
+// listBuckets 输出 b 的子存储桶，prefix 是 b 相对于起始存储桶的路径。
+func listBuckets(w io.Writer, b *bolt.Bucket, prefix string, recursive bool) error {
+	return walkBucket(b, func(k, v []byte, isBucket bool) error {
+		if !isBucket {
+			return nil
+		}
+		name := prefix + string(k)
+		fmt.Fprintln(w, name)
+		if !recursive {
+			return nil
+		}
+		child, err := b.Bucket(string(k))
+		if err != nil {
+			return err
+		}
+		return listBuckets(w, child, name+"/", true)
+	})
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	bolt "boltdb-go"
+)
+
+// runLoad 读取 mdb_dump 文本格式的输入并写入数据库，数据库文件不存在时创建它。
+// 每个 database 在一个写事务中写入，database 名称按 "/" 分隔为嵌套的存储桶路径，不存在的存储桶按头部中的标志位创建，
+// 已经存在的存储桶的标志位必须与头部一致。
+func runLoad(args []string, w io.Writer) error {
+	fs := newFlagSet("load")
+	input := fs.String("f", "", "read from file instead of stdin")
+	name := fs.String("s", "", "load into the named bucket, overriding the database in the header")
+	appendMode := fs.Bool("a", false, "append records, input keys must be sorted")
+	noOverwrite := fs.Bool("N", false, "do not overwrite existing keys")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	db := bolt.NewDB()
+	if err := db.Open(fs.Arg(0), 0666); err != nil {
+		return err
+	}
+	defer db.Close()
+
+	d := &dumpReader{r: bufio.NewReader(r)}
+	for {
+		h, err := d.header()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if *name != "" {
+			h.database = *name
+		}
+		flags := 0
+		if *appendMode {
+			flags |= bolt.Append
+			if h.flags&bolt.DupSort != 0 {
+				flags |= bolt.AppendDup
+			}
+		}
+		if *noOverwrite {
+			flags |= bolt.NoOverwrite
+			if h.flags&bolt.DupSort != 0 {
+				flags |= bolt.NoDupData
+			}
+		}
+		if err := load(db, d, h, flags); err != nil {
+			return err
+		}
+	}
+}
+
+// load 在一个写事务中写入一个 database 的所有记录。
+func load(db *bolt.DB, d *dumpReader, h *dumpHeader, flags int) error {
+	txn, err := db.Transaction(nil, 0)
+	if err != nil {
+		return err
+	}
+	main, err := txn.Bucket("", 0)
+	if err != nil {
+		txn.Abort()
+		return err
+	}
+	b, err := createBucketPath(main, h.database, h.flags)
+	if err != nil {
+		txn.Abort()
+		return err
+	}
+	// 已经存在的存储桶的标志位与头部不一致时，记录无法按头部描述的方式写入。
+	if b.Flags() != h.flags {
+		txn.Abort()
+		return fmt.Errorf("database %q: flags %#x do not match the header %#x", h.database, b.Flags(), h.flags)
+	}
+
+	for {
+		k, v, err := d.record(h)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			txn.Abort()
+			return err
+		}
+		if err := b.Put(k, v, flags); err != nil && !(err == bolt.KeyExistError && flags&bolt.NoOverwrite != 0) {
+			txn.Abort()
+			return fmt.Errorf("line %d: %w", d.line, err)
+		}
+	}
+	return txn.Commit()
+}
+
+// dumpHeader 是 mdb_dump 文本格式中一个 database 的头部。
+type dumpHeader struct {
+	printable bool   // format=print
+	database  string // 为空表示主存储桶
+	flags     int    // 存储桶标志位
+}
+
+// dumpReader 读取 mdb_dump 文本格式，line 为已读取的行数，用于错误信息。
+type dumpReader struct {
+	r    *bufio.Reader
+	line int
+}
+
+// readLine 读取一行并去掉行尾的换行符，输入结束时返回 io.EOF。
+func (d *dumpReader) readLine() (string, error) {
+	s, err := d.r.ReadString('\n')
+	if err == io.EOF && s != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	d.line++
+	return strings.TrimSuffix(s, "\n"), nil
+}
+
+func (d *dumpReader) errorf(format string, v ...any) error {
+	return fmt.Errorf("line %d: %s", d.line, fmt.Sprintf(format, v...))
+}
+
+// header 读取下一个 database 的头部，没有更多输入时返回 io.EOF。
+func (d *dumpReader) header() (*dumpHeader, error) {
+	h := &dumpHeader{}
+	for n := 0; ; n++ {
+		s, err := d.readLine()
+		if err == io.EOF && n == 0 {
+			return nil, io.EOF
+		} else if err == io.EOF {
+			return nil, d.errorf("unexpected end of input in header")
+		} else if err != nil {
+			return nil, err
+		}
+		if s == "HEADER=END" {
+			return h, nil
+		}
+		key, value, ok := strings.Cut(s, "=")
+		if !ok {
+			return nil, d.errorf("malformed header %q", s)
+		}
+		if n == 0 && key != "VERSION" {
+			return nil, d.errorf("missing VERSION")
+		}
+		switch key {
+		case "VERSION":
+			if v, err := strconv.Atoi(value); err != nil || v != dumpVersion {
+				return nil, d.errorf("unsupported VERSION %q", value)
+			}
+		case "format":
+			switch value {
+			case "bytevalue":
+			case "print":
+				h.printable = true
+			default:
+				return nil, d.errorf("unsupported format %q", value)
+			}
+		case "database":
+			h.database = value
+		case "type":
+			if value != "btree" {
+				return nil, d.errorf("unsupported type %q", value)
+			}
+		case "mapaddr", "mapsize", "maxreaders", "db_pagesize":
+			// 环境参数由数据库文件决定，忽略。
+		default:
+			f := -1
+			for _, df := range dumpFlags {
+				if df.name == key {
+					f = df.flag
+				}
+			}
+			if f < 0 {
+				return nil, d.errorf("unrecognized keyword %q", key)
+			}
+			if value == "1" {
+				h.flags |= f
+			}
+		}
+	}
+}
+
+// record 读取下一个键值对，读到 DATA=END 时返回 io.EOF。
+func (d *dumpReader) record(h *dumpHeader) ([]byte, []byte, error) {
+	var kv [2][]byte
+	for i := range kv {
+		s, err := d.readLine()
+		if err == io.EOF {
+			return nil, nil, d.errorf("unexpected end of input, missing DATA=END")
+		} else if err != nil {
+			return nil, nil, err
+		}
+		if i == 0 && s == "DATA=END" {
+			return nil, nil, io.EOF
+		}
+		if !strings.HasPrefix(s, " ") {
+			return nil, nil, d.errorf("malformed record %q", s)
+		}
+		if kv[i], err = decodeValue(s[1:], h.printable); err != nil {
+			return nil, nil, d.errorf("%v", err)
+		}
+	}
+	return kv[0], kv[1], nil
+}
+
+// decodeValue 解码一行中的键或值，printable 为 true 时使用 print 格式。
+func decodeValue(s string, printable bool) ([]byte, error) {
+	if !printable {
+		return hex.DecodeString(s)
+	}
+	b := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b = append(b, s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == '\\' {
+			b = append(b, '\\')
+			i++
+			continue
+		}
+		if i+2 >= len(s) {
+			return nil, fmt.Errorf("truncated escape in %q", s)
+		}
+		c, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid escape in %q", s)
+		}
+		b = append(b, c[0])
+		i += 2
+	}
+	return b, nil
+}
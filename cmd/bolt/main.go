@@ -0,0 +1,223 @@
+// bolt 是用于检查和维护 boltdb 数据库文件的命令行工具。
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	bolt "boltdb-go"
+)
+
+// usageError 表示命令行参数错误，main 会在错误信息之后打印用法。
+var usageError = errors.New("usage")
+
+// command 是 bolt 的一个子命令。
+type command struct {
+	name  string
+	usage string
+	run   func(args []string, w io.Writer) error
+}
+
+// commands 是所有子命令，按用法中的显示顺序排列。
+var commands = []*command{
+	{"info", "info [-json] <file>", runInfo},
+	{"stat", "stat [-json] <file> [bucket]", runStat},
+	{"buckets", "buckets [-b bucket] [-r] <file>", runBuckets},
+	{"keys", "keys [-b bucket] [-e encoding] [-prefix p] [-start k] [-end k] [-values] <file>", runKeys},
+	{"get", "get [-b bucket] [-e encoding] <file> <key>", runGet},
+	{"put", "put [-b bucket] [-e encoding] [-create] <file> <key> <value>", runPut},
+	{"delete", "delete [-b bucket] [-e encoding] <file> <key>", runDelete},
+	{"shell", "shell <file>", runShell},
+	{"readers", "readers [-json] [-clear-stale] [-lag n] <file>", runReaders},
+	{"check", "check [-json] <file>", runCheck},
+	{"pages", "pages [-json] <file>", runPages},
+	{"page", "page [-json] <file> <id>", runPage},
+	{"surgery", "surgery (-o output | -in-place) [-from backup] <revert-meta|rebuild-freelist|copy-page|clear-leaf> <file> [page]", runSurgery},
+	{"compact", "compact -o <output> [-fill f] [-tx-max-size n] <file>", runCompact},
+	{"bench", "bench [-key-size n] [-value-size n] [-batch n] [-random] [-nosync] [-duration d] [-readers n] [file]", runBench},
+	{"dump", "dump [-p] [-s bucket] [-o output] <file>", runDump},
+	{"load", "load [-a] [-N] [-s bucket] [-f input] <file>", runLoad},
+	{"export", "export [-b bucket] [-key-encoding e] [-value-encoding e] [-o output] <file>", runExport},
+	{"import", "import [-key-encoding e] [-value-encoding e] [-batch n] [-checkpoint file] [-f input] <file>", runImport},
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	if err == nil {
+		return
+	}
+	if err != usageError {
+		fmt.Fprintln(os.Stderr, "bolt:", err)
+	}
+	if errors.Is(err, usageError) {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	os.Exit(1)
+}
+
+// run 执行 args 指定的子命令，输出写入 w。
+func run(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return usageError
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:], w)
+		}
+	}
+	return fmt.Errorf("unknown command %q: %w", args[0], usageError)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: bolt <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, c := range commands {
+		fmt.Fprintln(w, "  bolt", c.usage)
+	}
+}
+
+// newFlagSet 返回子命令的参数解析器，解析错误以 usageError 返回而不是退出进程。
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseArgs 解析子命令的参数，并检查位置参数的数量在 [min, max] 之内。
+func parseArgs(fs *flag.FlagSet, args []string, min, max int) error {
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%v: %w", err, usageError)
+	}
+	if fs.NArg() < min || fs.NArg() > max {
+		return fmt.Errorf("%s: wrong number of arguments: %w", fs.Name(), usageError)
+	}
+	return nil
+}
+
+// openDB 以只读方式打开一个已存在的数据库文件，不会修改文件。
+func openDB(path string) (*bolt.DB, error) {
+	db := bolt.NewDB()
+	if err := db.OpenReadOnly(path); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// openDBWritable 以读写方式打开一个已存在的数据库文件，文件不存在时返回错误而不是创建新文件。
+func openDBWritable(path string) (*bolt.DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db := bolt.NewDB()
+	if err := db.Open(path, 0666); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// writeJSON 以缩进的 JSON 格式输出 v。
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// newTable 返回按列对齐输出的 tabwriter，调用方需要在输出完成后调用 Flush。
+func newTable(w io.Writer) *tabwriter.Writer {
+	return tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+}
+
+// walkBucket 按键的顺序对存储桶 b 中的每个键值对调用 fn，isBucket 表示该键是否为子存储桶。
+func walkBucket(b *bolt.Bucket, fn func(k, v []byte, isBucket bool) error) error {
+	return scanBucket(b, nil, nil, fn)
+}
+
+// scanBucket 从第一个大于或等于 start 的键开始按顺序对键值对调用 fn，直到 stop 返回 true。
+// start 为 nil 时从第一个键开始，stop 为 nil 时遍历到最后一个键。
+func scanBucket(b *bolt.Bucket, start []byte, stop func(k []byte) bool, fn func(k, v []byte, isBucket bool) error) error {
+	c, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if start == nil {
+		err = c.First()
+	} else {
+		_, _, err = c.SetRange(start)
+	}
+	for err == nil {
+		var k, v []byte
+		if k, v, err = c.Current(); err != nil {
+			break
+		}
+		if stop != nil && stop(k) {
+			return nil
+		}
+		if err := fn(k, v, c.IsBucket()); err != nil {
+			return err
+		}
+		_, _, err = c.Next()
+	}
+	if err != bolt.NotFoundError {
+		return err
+	}
+	return nil
+}
+
+// openBucketPath 打开 main 中以 "/" 分隔的路径指定的存储桶，路径为空时返回 main。
+func openBucketPath(main *bolt.Bucket, path string) (*bolt.Bucket, error) {
+	return openBuckets(main, splitPath(path))
+}
+
+// createBucketPath 与 openBucketPath 相同，但创建不存在的存储桶：最后一级以 flags 创建，中间的存储桶不带标志位。
+func createBucketPath(main *bolt.Bucket, path string, flags int) (*bolt.Bucket, error) {
+	return createBuckets(main, splitPath(path), flags)
+}
+
+// splitPath 把以 "/" 分隔的存储桶路径拆分为各级名称，空路径表示主存储桶。
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// openBuckets 依次打开 names 中的各级存储桶，names 为空时返回 main。
+func openBuckets(main *bolt.Bucket, names []string) (*bolt.Bucket, error) {
+	b := main
+	for _, name := range names {
+		var err error
+		if b, err = b.Bucket(name); err != nil {
+			return nil, fmt.Errorf("bucket %q: %w", strings.Join(names, "/"), err)
+		}
+	}
+	return b, nil
+}
+
+// createBuckets 与 openBuckets 相同，但创建不存在的存储桶：最后一级以 flags 创建，中间的存储桶不带标志位。
+func createBuckets(main *bolt.Bucket, names []string, flags int) (*bolt.Bucket, error) {
+	b := main
+	for i, name := range names {
+		child, err := b.Bucket(name)
+		if err == bolt.NotFoundError {
+			f := 0
+			if i == len(names)-1 {
+				f = flags
+			}
+			child, err = b.CreateBucket(name, f)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bucket %q: %w", strings.Join(names, "/"), err)
+		}
+		b = child
+	}
+	return b, nil
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bolt "boltdb-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// 确保 info 输出 meta 页面的状态以及文件大小。
+func TestInfo(t *testing.T) {
+	path := newTestDB(t)
+	var buf bytes.Buffer
+	assert.NoError(t, run([]string{"info", "-json", path}, &buf))
+	var out infoOutput
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	assert.Equal(t, 1, out.LastTransactionID)
+	assert.Equal(t, int64(2*out.PageSize), out.FileSize)
+	assert.True(t, out.Metas[0].Valid)
+	assert.True(t, out.Metas[1].Valid)
+
+	buf.Reset()
+	assert.NoError(t, run([]string{"info", path}, &buf))
+	assert.Contains(t, buf.String(), "Last Transaction ID:  1\n")
+}
+
+// 确保 info 报告无效的 meta 页面而不修改文件。
+func TestInfoInvalidMeta(t *testing.T) {
+	path := newTestDB(t)
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	// 破坏 meta0 的 magic，它位于 32 字节的页面头之后。
+	copy(data[32:36], []byte{0, 0, 0, 0})
+	assert.NoError(t, os.WriteFile(path, data, 0666))
+
+	var buf bytes.Buffer
+	assert.NoError(t, run([]string{"info", "-json", path}, &buf))
+	var out infoOutput
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	assert.False(t, out.Metas[0].Valid)
+	assert.True(t, out.Metas[1].Valid)
+	assert.Equal(t, 1, out.LastTransactionID)
+
+	buf.Reset()
+	assert.NoError(t, run([]string{"info", path}, &buf))
+	assert.Contains(t, buf.String(), "Meta 0:               txnid=0 last_pgno=1 invalid\n")
+	after, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, data, after)
+}
+
+// 确保 stat 输出主存储桶和空闲页面存储桶的统计信息。
+func TestStat(t *testing.T) {
+	path := newTestDB(t)
+	var buf bytes.Buffer
+	assert.NoError(t, run([]string{"stat", "-json", path}, &buf))
+	var out statOutput
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	assert.NotNil(t, out.Main)
+	assert.NotNil(t, out.Free)
+	assert.Nil(t, out.Bucket)
+
+	buf.Reset()
+	assert.NoError(t, run([]string{"stat", path}, &buf))
+	assert.Contains(t, buf.String(), "main:\n")
+	assert.Contains(t, buf.String(), "free:\n")
+}
+
+// 确保参数错误和不存在的文件返回错误，且不会创建文件。
+func TestUsage(t *testing.T) {
+	assert.ErrorIs(t, run(nil, nil), usageError)
+	assert.ErrorIs(t, run([]string{"nope"}, nil), usageError)
+	assert.ErrorIs(t, run([]string{"info"}, nil), usageError)
+
+	path := filepath.Join(t.TempDir(), "missing.db")
+	assert.Error(t, run([]string{"info", path}, nil))
+	assert.NoFileExists(t, path)
+}
+
+// newTestDB 创建一个提交过一次写事务的数据库文件。
+func newTestDB(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db := bolt.NewDB()
+	assert.NoError(t, db.Open(path, 0666))
+	txn, err := db.Transaction(nil, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, txn.Commit())
+	db.Close()
+	_, err = os.Stat(path)
+	assert.NoError(t, err)
+	return path
+}
+
+// 确保一致的数据库通过检查。
+func TestCheck(t *testing.T) {
+	path := newTestDB(t)
+	var buf bytes.Buffer
+	assert.NoError(t, run([]string{"check", path}, &buf))
+	assert.Equal(t, "OK\n", buf.String())
+}
+
+// 确保 pages 列出 meta 页面，page 输出 meta 字段和十六进制内容。
+func TestPages(t *testing.T) {
+	path := newTestDB(t)
+	var buf bytes.Buffer
+	assert.NoError(t, run([]string{"pages", path}, &buf))
+	assert.Equal(t, "ID  TYPE  OVERFLOW  LOWER  UPPER  BUCKET\n0   meta  0         0      0      \n1   meta  0         0      0      \n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, run([]string{"page", path, "1"}, &buf))
+	assert.Contains(t, buf.String(), "Last Transaction ID:  1\n")
+	assert.Contains(t, buf.String(), "00000000  01 00 00 00")
+	assert.Error(t, run([]string{"page", path, "7"}, &buf))
+	assert.ErrorIs(t, run([]string{"page", path, "x"}, &buf), usageError)
+}
+
+// 确保 compact 生成一致的新文件，并拒绝覆盖已存在的输出文件。
+func TestCompact(t *testing.T) {
+	path := newTestDB(t)
+	db := bolt.NewDB()
+	assert.NoError(t, db.Open(path, 0666))
+	txn, _ := db.Transaction(nil, 0)
+	main, _ := txn.Bucket("", 0)
+	users, err := main.CreateBucket("users", 0)
+	assert.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, users.Put([]byte(fmt.Sprintf("user%04d", i)), bytes.Repeat([]byte("v"), 50), 0))
+	}
+	assert.NoError(t, users.SetSequence(7))
+	tags, err := main.CreateBucket("tags", bolt.DupSort)
+	assert.NoError(t, err)
+	for _, v := range []string{"a", "b", "c"} {
+		assert.NoError(t, tags.Put([]byte("t"), []byte(v), 0))
+	}
+	_, err = main.CreateBucket("a/b", 0)
+	assert.NoError(t, err)
+	assert.NoError(t, txn.Commit())
+	db.Close()
+
+	leafs := map[string]int{}
+	for _, fill := range []string{"1", "0.5"} {
+		out := path + ".compact" + fill
+		var buf bytes.Buffer
+		assert.NoError(t, run([]string{"compact", "-o", out, "-fill", fill, "-tx-max-size", "4096", path}, &buf))
+		assert.Contains(t, buf.String(), "Before:")
+		assert.Contains(t, buf.String(), "main/users  1000     58000\n")
+		assert.NoError(t, run([]string{"check", out}, io.Discard))
+
+		db := bolt.NewDB()
+		assert.NoError(t, db.OpenReadOnly(out))
+		txn, _ := db.Transaction(nil, bolt.ReadOnly)
+		main, _ := txn.Bucket("", 0)
+		b, err := main.Bucket("users")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(7), b.Sequence())
+		v, err := b.Get([]byte("user0999"))
+		assert.NoError(t, err)
+		assert.Equal(t, strings.Repeat("v", 50), string(v))
+		tags, err := main.Bucket("tags")
+		assert.NoError(t, err)
+		c, _ := tags.Cursor()
+		_, _, err = c.Set([]byte("t"))
+		assert.NoError(t, err)
+		n, _ := c.Count()
+		assert.Equal(t, 3, n)
+		c.Close()
+		leafs[fill] = b.Stats().LeafPageCount
+		// 名称中带有 "/" 的存储桶不会被拆分为嵌套的存储桶。
+		_, err = main.Bucket("a/b")
+		assert.NoError(t, err)
+		txn.Abort()
+		db.Close()
+	}
+	// 填充率为 0.5 时每个叶子页面大约只填充一半。
+	assert.Less(t, float64(leafs["1"]), 0.6*float64(leafs["0.5"]))
+
+	out := path + ".compact1"
+	assert.Error(t, run([]string{"compact", "-o", out, path}, io.Discard))
+	assert.ErrorIs(t, run([]string{"compact", path}, io.Discard), usageError)
+	assert.ErrorIs(t, run([]string{"compact", "-o", out + "2", "-fill", "2", path}, io.Discard), usageError)
+	assert.NoFileExists(t, out+"2")
+}
+
+// 确保限制事务大小的 compact 与在一个写事务中完成的 compact 输出的文件大小相差不超过一个页面。
+func TestCompactBoundedTransaction(t *testing.T) {
+	path := newTestDB(t)
+	db := bolt.NewDB()
+	assert.NoError(t, db.Open(path, 0666))
+	txn, _ := db.Transaction(nil, 0)
+	main, _ := txn.Bucket("", 0)
+	for j := 0; j < 3; j++ {
+		b, err := main.CreateBucket(fmt.Sprint("b", j), 0)
+		assert.NoError(t, err)
+		for i := 0; i < 5000; i++ {
+			assert.NoError(t, b.Put([]byte(fmt.Sprintf("key%08d", i)), bytes.Repeat([]byte("v"), 100), 0))
+		}
+	}
+	dups, err := main.CreateBucket("dups", bolt.DupSort)
+	assert.NoError(t, err)
+	for i := 0; i < 5000; i++ {
+		assert.NoError(t, dups.Put([]byte(fmt.Sprint(i%10)), []byte(fmt.Sprintf("%08d", i)), 0))
+	}
+	assert.NoError(t, txn.Commit())
+	pageSize := db.Info().PageSize
+	db.Close()
+
+	sizes := map[string]int64{}
+	for _, max := range []string{"0", "4096"} {
+		out := path + ".compact" + max
+		assert.NoError(t, run([]string{"compact", "-o", out, "-tx-max-size", max, path}, io.Discard))
+		assert.NoError(t, run([]string{"check", out}, io.Discard))
+		db := bolt.NewDB()
+		assert.NoError(t, db.OpenReadOnly(out))
+		txn, _ := db.Transaction(nil, bolt.ReadOnly)
+		main, _ := txn.Bucket("", 0)
+		b, err := main.Bucket("b2")
+		assert.NoError(t, err)
+		v, err := b.Get([]byte("key00004999"))
+		assert.NoError(t, err)
+		assert.Equal(t, strings.Repeat("v", 100), string(v))
+		txn.Abort()
+		db.Close()
+		info, err := os.Stat(out)
+		assert.NoError(t, err)
+		sizes[max] = info.Size()
+	}
+	assert.InDelta(t, sizes["0"], sizes["4096"], float64(pageSize))
+	assert.ErrorIs(t, run([]string{"compact", "-o", path + ".bad", "-tx-max-size", "-1", path}, io.Discard), usageError)
+}
+
+// 确保 context 被取消后 compact 中止复制并返回 ctx.Err()。
+func TestCompactCanceled(t *testing.T) {
+	path := newTestDB(t)
+	assert.NoError(t, run([]string{"put", path, "k", "v"}, io.Discard))
+	src, err := openDB(path)
+	assert.NoError(t, err)
+	defer src.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := &compactor{ctx: ctx, fill: bolt.MaxFillPercent}
+	assert.ErrorIs(t, c.run(src, path+".compact"), context.Canceled)
+}
+
+// 确保 bench 输出吞吐量和延迟，并拒绝使用已存在的文件。
+func TestBench(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, run([]string{"bench", "-duration", "20ms", "-batch", "10", "-nosync", "-readers", "2"}, &buf))
+	assert.Contains(t, buf.String(), "Puts:")
+	assert.Contains(t, buf.String(), "Gets:")
+	assert.Contains(t, buf.String(), "Put latency:")
+	assert.Contains(t, buf.String(), "File size:")
+
+	assert.Error(t, run([]string{"bench", "-duration", "1ms", newTestDB(t)}, io.Discard))
+	assert.ErrorIs(t, run([]string{"bench", "-key-size", "4"}, io.Discard), usageError)
+}
+
+// 确保键值子命令可以读写主存储桶，并正确处理编码和不存在的键。
+func TestKV(t *testing.T) {
+	path := newTestDB(t)
+	assert.NoError(t, run([]string{"put", "-e", "hex", path, "6b", "76"}, io.Discard))
+	assert.NoError(t, run([]string{"put", "-b", "users", "-create", path, "k", "v"}, io.Discard))
+	assert.ErrorIs(t, run([]string{"get", "-e", "rot13", path, "k"}, io.Discard), usageError)
+	assert.Error(t, run([]string{"get", "-b", "missing", path, "k"}, io.Discard))
+
+	var buf bytes.Buffer
+	assert.NoError(t, run([]string{"keys", "-prefix", "k", "-values", path}, &buf))
+	assert.NoError(t, run([]string{"buckets", "-r", path}, &buf))
+	assert.Equal(t, "k\tv\nusers\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, run([]string{"get", path, "k"}, &buf))
+	assert.Equal(t, "v", buf.String())
+	buf.Reset()
+	assert.NoError(t, run([]string{"get", "-e", "hex", path, "6b"}, &buf))
+	assert.Equal(t, "76\n", buf.String())
+	buf.Reset()
+	assert.NoError(t, run([]string{"get", "-b", "users", path, "k"}, &buf))
+	assert.Equal(t, "v", buf.String())
+
+	// 删除主存储桶中的键不影响 users 中的同名键。
+	assert.NoError(t, run([]string{"delete", path, "k"}, io.Discard))
+	assert.ErrorIs(t, run([]string{"get", path, "k"}, io.Discard), bolt.NotFoundError)
+	assert.ErrorIs(t, run([]string{"delete", path, "k"}, io.Discard), bolt.NotFoundError)
+	buf.Reset()
+	assert.NoError(t, run([]string{"keys", "-b", "users", "-values", path}, &buf))
+	assert.Equal(t, "k\tv\n", buf.String())
+}
+
+// 确保 readers 在没有读者时输出提示，并可以清除失效的槽位。
+func TestReaders(t *testing.T) {
+	path := newTestDB(t)
+	var buf bytes.Buffer
+	assert.NoError(t, run([]string{"readers", "-clear-stale", path}, &buf))
+	assert.Equal(t, "cleared 0 stale readers\n(no active readers)\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, run([]string{"readers", "-json", path}, &buf))
+	assert.JSONEq(t, "[]", buf.String())
+	assert.ErrorIs(t, run([]string{"readers", "-lag", "0", path}, io.Discard), usageError)
+
+	// 读者表保存在锁文件中，其他打开数据库的实例中的读者也会被列出。
+	db := bolt.NewDB()
+	assert.NoError(t, db.Open(path, 0666))
+	defer db.Close()
+	txn, err := db.Transaction(nil, bolt.ReadOnly)
+	assert.NoError(t, err)
+	defer txn.Abort()
+	buf.Reset()
+	assert.NoError(t, run([]string{"readers", "-json", path}, &buf))
+	var readers []readerOutput
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &readers))
+	if assert.Len(t, readers, 1) {
+		assert.Equal(t, os.Getpid(), readers[0].PID)
+		assert.Equal(t, 1, readers[0].TransactionID)
+		assert.False(t, readers[0].Behind)
+	}
+}
+
+// 确保 revert-meta 不打开数据库就可以修复损坏的 meta 页面。
+func TestSurgeryRevertCorruptMeta(t *testing.T) {
+	path := newTestDB(t)
+	assert.NoError(t, run([]string{"put", path, "k", "v"}, io.Discard))
+	// 第二次提交写入 meta0，破坏它的 magic，它位于 32 字节的页面头之后。
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	copy(data[32:36], []byte{0, 0, 0, 0})
+	assert.NoError(t, os.WriteFile(path, data, 0666))
+
+	out := path + ".repaired"
+	var buf bytes.Buffer
+	assert.NoError(t, run([]string{"surgery", "-o", out, "revert-meta", path}, &buf))
+	assert.Equal(t, "reverted to transaction 1\n"+out+": check OK\n", buf.String())
+	after, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, data, after)
+
+	buf.Reset()
+	assert.NoError(t, run([]string{"info", "-json", out}, &buf))
+	var info infoOutput
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &info))
+	assert.True(t, info.Metas[0].Valid)
+	assert.True(t, info.Metas[1].Valid)
+	assert.Equal(t, 1, info.LastTransactionID)
+	assert.ErrorIs(t, run([]string{"get", out, "k"}, io.Discard), bolt.NotFoundError)
+	assert.ErrorIs(t, run([]string{"surgery", "-in-place", "revert-meta", out}, io.Discard), bolt.NoOlderMetaError)
+
+	// 数据库有活动的事务时不能直接修改文件。
+	db := bolt.NewDB()
+	assert.NoError(t, db.OpenReadOnly(out))
+	r, err := db.Transaction(nil, bolt.ReadOnly)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, run([]string{"surgery", "-in-place", "revert-meta", out}, io.Discard), bolt.DatabaseBusyError)
+	r.Abort()
+	db.Close()
+	assert.NoError(t, db.Open(out, 0666))
+	w, err := db.Transaction(nil, 0)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, run([]string{"surgery", "-in-place", "revert-meta", out}, io.Discard), bolt.WriterBusyError)
+	w.Abort()
+	db.Close()
+}
+
+// 确保 surgery 默认修改副本，并检查操作所需的参数。
+func TestSurgery(t *testing.T) {
+	path := newTestDB(t)
+	out := path + ".repaired"
+	var buf bytes.Buffer
+	assert.NoError(t, run([]string{"surgery", "-o", out, "rebuild-freelist", path}, &buf))
+	assert.Equal(t, "rebuilt free list with 0 pages\n"+out+": check OK\n", buf.String())
+	assert.FileExists(t, out)
+
+	// rebuild-freelist 提交了一次，回滚它之后两个 meta 相同，不能再回滚。
+	buf.Reset()
+	assert.NoError(t, run([]string{"surgery", "-in-place", "revert-meta", out}, &buf))
+	assert.Equal(t, "reverted to transaction 1\n"+out+": check OK\n", buf.String())
+	assert.ErrorIs(t, run([]string{"surgery", "-in-place", "revert-meta", out}, io.Discard), bolt.NoOlderMetaError)
+
+	// 修改副本失败时删除副本。
+	assert.ErrorIs(t, run([]string{"surgery", "-o", out + "2", "clear-leaf", path, "0"}, io.Discard), bolt.NotLeafPageError)
+	assert.NoFileExists(t, out+"2")
+
+	for _, args := range [][]string{
+		{"rebuild-freelist", path},
+		{"-o", out + "3", "-in-place", "rebuild-freelist", path},
+		{"-in-place", "clear-leaf", path},
+		{"-in-place", "copy-page", path, "2"},
+		{"-in-place", "-from", path, "clear-leaf", path, "2"},
+		{"-in-place", "fix", path},
+	} {
+		assert.ErrorIs(t, run(append([]string{"surgery"}, args...), io.Discard), usageError, args)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	bolt "boltdb-go"
+)
+
+// readerOutput 是 readers 子命令输出的一个读者，Behind 表示它持有的快照落后于最新事务至少 -lag 个事务。
+type readerOutput struct {
+	bolt.ReaderInfo
+	Behind bool
+}
+
+// runReaders 输出读者表中正在使用的槽位，与 mdb_reader_list 的列相同，另外输出持有快照的时间和落后的事务数。
+// 落后过多的读者会阻止写事务复用空闲页面，使文件不断增长，这些读者会被标记出来。
+// 指定 -clear-stale 时先清除属于已经退出的进程的槽位。
+func runReaders(args []string, w io.Writer) error {
+	fs := newFlagSet("readers")
+	asJSON := fs.Bool("json", false, "output JSON")
+	clearStale := fs.Bool("clear-stale", false, "clear slots of readers whose process has exited")
+	lag := fs.Int("lag", 100, "flag readers at least this many transactions behind the latest meta")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	if *lag < 1 {
+		return fmt.Errorf("readers: -lag must be positive: %w", usageError)
+	}
+	db, err := openDB(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if *clearStale {
+		n, err := db.CheckReaders()
+		if err != nil {
+			return err
+		}
+		if !*asJSON {
+			fmt.Fprintf(w, "cleared %d stale readers\n", n)
+		}
+	}
+	readers := []readerOutput{}
+	for _, r := range db.Readers() {
+		readers = append(readers, readerOutput{r, r.TransactionID != -1 && r.Lag >= *lag})
+	}
+	if *asJSON {
+		return writeJSON(w, readers)
+	}
+	if len(readers) == 0 {
+		_, err := fmt.Fprintln(w, "(no active readers)")
+		return err
+	}
+
+	t := newTable(w)
+	fmt.Fprintln(t, "PID\tTHREAD\tTXNID\tAGE\tLAG\t")
+	for _, r := range readers {
+		if r.TransactionID == -1 {
+			fmt.Fprintf(t, "%d\t%x\t-\t-\t-\t\n", r.PID, r.Thread)
+			continue
+		}
+		fmt.Fprintf(t, "%d\t%x\t%d\t%s\t%d\t", r.PID, r.Thread, r.TransactionID, r.Age.Round(time.Millisecond), r.Lag)
+		if r.Behind {
+			fmt.Fprint(t, "behind")
+		}
+		fmt.Fprintln(t)
+	}
+	return t.Flush()
+}
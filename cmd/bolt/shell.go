@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	bolt "boltdb-go"
+)
+
+// stdin 是 shell 读取命令的输入，测试中可以替换。
+var stdin io.Reader = os.Stdin
+
+const shellHelp = `commands:
+  cd <bucket>|..|/       change the current bucket
+  pwd                    print the current bucket path
+  ls                     list sub-buckets (with a trailing /) and keys
+  get <key>              print the value of a key
+  scan [prefix]          print keys and values, optionally with a prefix
+  encoding raw|hex|base64
+                         set the encoding of keys and values
+  refresh                open a new read snapshot
+  begin                  start a write transaction
+  put <key> <value>      write a key, only inside begin/commit
+  delete <key>           delete a key, only inside begin/commit
+  commit                 commit the write transaction
+  abort                  roll back the write transaction
+  exit                   leave the shell, aborting any write transaction
+arguments containing spaces can be written as Go quoted strings.
+`
+
+// runShell 运行一个交互式命令行，默认在一个只读快照上浏览数据库，通过 begin/commit 进行修改。
+func runShell(args []string, w io.Writer) error {
+	fs := newFlagSet("shell")
+	if err := parseArgs(fs, args, 1, 1); err != nil {
+		return err
+	}
+	db, err := openDBWritable(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	s := &shell{db: db, w: w, codec: codecRaw}
+	if err := s.begin(false); err != nil {
+		return err
+	}
+	defer func() { s.txn.Abort() }()
+
+	scanner := bufio.NewScanner(stdin)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	s.prompt()
+	for scanner.Scan() {
+		args, err := splitArgs(scanner.Text())
+		if err == nil && len(args) > 0 && (args[0] == "exit" || args[0] == "quit") {
+			break
+		}
+		if err == nil && len(args) > 0 {
+			err = s.exec(args)
+		}
+		if err != nil {
+			fmt.Fprintln(w, "error:", err)
+		}
+		s.prompt()
+	}
+	if s.writing {
+		fmt.Fprintln(w, "uncommitted write transaction aborted")
+	}
+	return scanner.Err()
+}
+
+// shell 是交互式命令行的状态。
+type shell struct {
+	db      *bolt.DB
+	w       io.Writer
+	txn     transaction
+	writing bool     // txn 是否为写事务
+	path    []string // 当前存储桶的路径
+	codec   codec
+}
+
+func (s *shell) prompt() {
+	fmt.Fprintf(s.w, "bolt:/%s> ", strings.Join(s.path, "/"))
+}
+
+// begin 结束当前事务并开始一个新事务，write 为 true 时开始写事务。
+func (s *shell) begin(write bool) error {
+	flags := bolt.ReadOnly
+	if write {
+		flags = 0
+	}
+	t, err := s.db.Transaction(nil, flags)
+	if err != nil {
+		return err
+	}
+	if s.txn != nil {
+		s.txn.Abort()
+	}
+	s.txn, s.writing = t, write
+	return nil
+}
+
+// bucket 返回当前事务中路径为 path 的存储桶。
+func (s *shell) bucket(path []string) (*bolt.Bucket, error) {
+	main, err := s.txn.Bucket("", 0)
+	if err != nil {
+		return nil, err
+	}
+	return openBucketPath(main, strings.Join(path, "/"))
+}
+
+// exec 执行一条命令。
+func (s *shell) exec(args []string) error {
+	cmd, args := args[0], args[1:]
+	want := map[string]int{"cd": 1, "get": 1, "encoding": 1, "put": 2, "delete": 1}
+	if n, ok := want[cmd]; ok && len(args) != n {
+		return fmt.Errorf("%s takes %d arguments", cmd, n)
+	}
+
+	switch cmd {
+	case "help":
+		_, err := io.WriteString(s.w, shellHelp)
+		return err
+	case "pwd":
+		_, err := fmt.Fprintf(s.w, "/%s\n", strings.Join(s.path, "/"))
+		return err
+	case "cd":
+		return s.cd(args[0])
+	case "encoding":
+		c, err := parseCodec(args[0])
+		if err != nil {
+			return err
+		}
+		s.codec = c
+		return nil
+	case "ls":
+		return s.scan(nil, true)
+	case "scan":
+		var prefix []byte
+		if len(args) > 0 {
+			p, err := s.codec.decode(args[0])
+			if err != nil {
+				return err
+			}
+			prefix = p
+		}
+		return s.scan(prefix, false)
+	case "get":
+		b, k, err := s.key(args[0])
+		if err != nil {
+			return err
+		}
+		v, err := b.Get(k)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(s.w, s.codec.encode(v))
+		return err
+	case "refresh":
+		if s.writing {
+			return errors.New("inside a write transaction, commit or abort first")
+		}
+		return s.begin(false)
+	case "begin":
+		if s.writing {
+			return errors.New("already inside a write transaction")
+		}
+		return s.begin(true)
+	case "put", "delete":
+		if !s.writing {
+			return errors.New("no write transaction, run begin first")
+		}
+		b, k, err := s.key(args[0])
+		if err != nil {
+			return err
+		}
+		if cmd == "delete" {
+			return b.Delete(k, nil)
+		}
+		v, err := s.codec.decode(args[1])
+		if err != nil {
+			return err
+		}
+		return b.Put(k, v, 0)
+	case "commit", "abort", "rollback":
+		if !s.writing {
+			return errors.New("no write transaction")
+		}
+		var err error
+		if cmd == "commit" {
+			err = s.txn.Commit()
+		}
+		// 提交或回滚之后回到只读快照，提交失败时事务已被回滚。
+		s.txn.Abort()
+		s.txn = nil
+		if e := s.begin(false); err == nil {
+			err = e
+		}
+		return err
+	}
+	return fmt.Errorf("unknown command %q, try help", cmd)
+}
+
+// cd 切换当前存储桶，目标存储桶必须存在。
+func (s *shell) cd(name string) error {
+	var path []string
+	switch name {
+	case "/":
+	case "..":
+		if len(s.path) > 0 {
+			path = s.path[:len(s.path)-1]
+		}
+	default:
+		path = append(s.path[:len(s.path):len(s.path)], strings.Split(strings.Trim(name, "/"), "/")...)
+		if strings.HasPrefix(name, "/") {
+			path = strings.Split(strings.Trim(name, "/"), "/")
+		}
+	}
+	if _, err := s.bucket(path); err != nil {
+		return err
+	}
+	s.path = path
+	return nil
+}
+
+// key 解码键并返回当前存储桶。
+func (s *shell) key(arg string) (*bolt.Bucket, []byte, error) {
+	k, err := s.codec.decode(arg)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := s.bucket(s.path)
+	return b, k, err
+}
+
+// scan 输出当前存储桶中以 prefix 开头的键，names 为 true 时只输出键和子存储桶的名称，否则同时输出值。
+func (s *shell) scan(prefix []byte, names bool) error {
+	b, err := s.bucket(s.path)
+	if err != nil {
+		return err
+	}
+	stop := func(k []byte) bool { return !bytes.HasPrefix(k, prefix) }
+	if len(prefix) == 0 {
+		prefix, stop = nil, nil
+	}
+	return scanBucket(b, prefix, stop, func(k, v []byte, isBucket bool) error {
+		switch {
+		case isBucket:
+			fmt.Fprintf(s.w, "%s/\n", s.codec.encode(k))
+		case names:
+			fmt.Fprintln(s.w, s.codec.encode(k))
+		default:
+			fmt.Fprintf(s.w, "%s\t%s\n", s.codec.encode(k), s.codec.encode(v))
+		}
+		return nil
+	})
+}
+
+// splitArgs 按空白分割命令行，以双引号或反引号开头的参数按 Go 的字符串字面量解析。
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return args, nil
+		}
+		if line[0] == '"' || line[0] == '`' {
+			q, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted argument: %s", line)
+			}
+			arg, _ := strconv.Unquote(q)
+			args = append(args, arg)
+			line = line[len(q):]
+			continue
+		}
+		i := strings.IndexAny(line, " \t")
+		if i < 0 {
+			i = len(line)
+		}
+		args = append(args, line[:i])
+		line = line[i:]
+	}
+}
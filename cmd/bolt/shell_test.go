@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 确保 shell 执行命令并报告错误，写命令只能在 begin/commit 之间使用。
+func TestShell(t *testing.T) {
+	path := newTestDB(t)
+	defer func(r io.Reader) { stdin = r }(stdin)
+	stdin = strings.NewReader(strings.Join([]string{
+		"pwd",
+		"put k v",
+		"begin",
+		`put "a key" v`,
+		"begin",
+		"commit",
+		"cd missing",
+		"encoding hex",
+		"encoding rot13",
+		"nope",
+		"exit",
+		"pwd",
+	}, "\n"))
+
+	var buf bytes.Buffer
+	assert.NoError(t, run([]string{"shell", path}, &buf))
+	assert.Equal(t, strings.Join([]string{
+		"bolt:/> /",
+		"bolt:/> error: no write transaction, run begin first",
+		"bolt:/> bolt:/> bolt:/> error: already inside a write transaction",
+		`bolt:/> bolt:/> error: bucket "missing": no matching key/value pair found`,
+		`bolt:/> bolt:/> error: unknown encoding "rot13", want raw, hex or base64`,
+		`bolt:/> error: unknown command "nope", try help`,
+		"bolt:/> ",
+	}, "\n"), buf.String())
+}
+
+// 确保参数按空白分割，引号中的参数按字符串字面量解析。
+func TestSplitArgs(t *testing.T) {
+	args, err := splitArgs(`put  "a b\n" ` + "`c d`" + ` e`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"put", "a b\n", "c d", "e"}, args)
+	_, err = splitArgs(`get "unterminated`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	bolt "boltdb-go"
+)
+
+// statOutput 是 stat 子命令的输出，Bucket 只在指定了存储桶时输出。
+type statOutput struct {
+	Main   *bolt.Stat
+	Free   *bolt.Stat
+	Bucket *bolt.Stat `json:",omitempty"`
+}
+
+// runStat 输出主存储桶、空闲页面存储桶以及指定存储桶的统计信息。
+func runStat(args []string, w io.Writer) error {
+	fs := newFlagSet("stat")
+	asJSON := fs.Bool("json", false, "output JSON")
+	if err := parseArgs(fs, args, 1, 2); err != nil {
+		return err
+	}
+	db, err := openDB(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	out := statOutput{Main: db.Stat(), Free: db.FreeStat()}
+	name := fs.Arg(1)
+	if name != "" {
+		txn, err := db.Transaction(nil, bolt.ReadOnly)
+		if err != nil {
+			return err
+		}
+		defer txn.Abort()
+		b, err := txn.Bucket(name, 0)
+		if err != nil {
+			return fmt.Errorf("bucket %q: %w", name, err)
+		}
+		out.Bucket = b.Stats()
+	}
+	if *asJSON {
+		return writeJSON(w, out)
+	}
+
+	t := newTable(w)
+	writeStat(t, "main", out.Main)
+	writeStat(t, "free", out.Free)
+	if out.Bucket != nil {
+		writeStat(t, name, out.Bucket)
+	}
+	return t.Flush()
+}
+
+// writeStat 以文本格式输出一个存储桶的统计信息。
+func writeStat(w io.Writer, name string, s *bolt.Stat) {
+	fmt.Fprintf(w, "%s:\n", name)
+	fmt.Fprintf(w, "  Page Size:\t%d\n", s.PageSize)
+	fmt.Fprintf(w, "  Depth:\t%d\n", s.Depth)
+	fmt.Fprintf(w, "  Branch Pages:\t%d\n", s.BranchPageCount)
+	fmt.Fprintf(w, "  Leaf Pages:\t%d\n", s.LeafPageCount)
+	fmt.Fprintf(w, "  Overflow Pages:\t%d\n", s.OverflowPageCount)
+	fmt.Fprintf(w, "  Entries:\t%d\n", s.EntryCount)
+}
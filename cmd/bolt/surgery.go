@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	bolt "boltdb-go"
+)
+
+// runSurgery 直接修改数据库文件以便从损坏中恢复，支持以下操作：
+//
+//	revert-meta       用较旧的 meta 覆盖当前有效的 meta，回滚最后一次提交
+//	copy-page         从 -from 指定的备份文件中复制一个页面
+//	rebuild-freelist  把不可访问的页面重新作为空闲列表
+//	clear-leaf        清空一个损坏的叶子页面并修正存储桶的计数
+//
+// 默认先把数据库复制到 -o 指定的新文件再修改副本，只有指定 -in-place 时才修改原文件。
+// 修改之后检查数据库的一致性并输出结果。
+func runSurgery(args []string, w io.Writer) error {
+	fs := newFlagSet("surgery")
+	output := fs.String("o", "", "write the repaired database to this file, must not exist")
+	inPlace := fs.Bool("in-place", false, "modify the database file itself")
+	from := fs.String("from", "", "backup file to copy the page from, for copy-page")
+	if err := parseArgs(fs, args, 2, 3); err != nil {
+		return err
+	}
+	op, path := fs.Arg(0), fs.Arg(1)
+	needPage := op == "copy-page" || op == "clear-leaf"
+	switch {
+	case op != "revert-meta" && op != "rebuild-freelist" && !needPage:
+		return fmt.Errorf("surgery: unknown operation %q: %w", op, usageError)
+	case needPage != (fs.NArg() == 3):
+		return fmt.Errorf("surgery: wrong number of arguments for %s: %w", op, usageError)
+	case (*output == "") == !*inPlace:
+		return fmt.Errorf("surgery: exactly one of -o and -in-place is required: %w", usageError)
+	case (*from != "") != (op == "copy-page"):
+		return fmt.Errorf("surgery: -from is required by and only allowed for copy-page: %w", usageError)
+	}
+	id := 0
+	if needPage {
+		var err error
+		if id, err = strconv.Atoi(fs.Arg(2)); err != nil {
+			return fmt.Errorf("surgery: invalid page id %q: %w", fs.Arg(2), usageError)
+		}
+	}
+
+	if !*inPlace {
+		if err := copyFile(path, *output); err != nil {
+			return err
+		}
+		path = *output
+	}
+	err := surgery(w, path, op, id, *from)
+	if err != nil && !*inPlace {
+		os.Remove(path)
+	}
+	return err
+}
+
+// surgery 在数据库文件 path 上执行一个修复操作。
+// revert-meta 和 copy-page 直接修改文件而不打开数据库，可以用于无法正常打开的文件；
+// 其余操作需要遍历 B+ 树，通过读写打开的数据库执行。
+func surgery(w io.Writer, path, op string, id int, from string) error {
+	switch op {
+	case "revert-meta":
+		if err := bolt.RevertMetaFile(path); err != nil {
+			return err
+		}
+	case "copy-page":
+		src, err := os.Open(from)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		if err := bolt.CopyPageFile(path, src, id); err != nil {
+			return fmt.Errorf("page %d: %w", id, err)
+		}
+		fmt.Fprintf(w, "copied page %d from %s\n", id, from)
+	default:
+		if err := repairTree(w, path, op, id); err != nil {
+			return err
+		}
+	}
+
+	db, err := openDB(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if op == "revert-meta" {
+		fmt.Fprintf(w, "reverted to transaction %d\n", db.Info().LastTransactionID)
+	}
+	errs, err := db.Check()
+	if err != nil {
+		return err
+	}
+	if len(errs) == 0 {
+		fmt.Fprintf(w, "%s: check OK\n", path)
+	} else {
+		fmt.Fprintf(w, "%s: check found %d problems, run bolt check for details\n", path, len(errs))
+	}
+	return nil
+}
+
+// repairTree 在读写打开的数据库 path 上执行 rebuild-freelist 或 clear-leaf。
+func repairTree(w io.Writer, path, op string, id int) error {
+	db, err := openDBWritable(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	switch op {
+	case "rebuild-freelist":
+		ids, err := db.RebuildFreeList()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "rebuilt free list with %d pages\n", len(ids))
+	case "clear-leaf":
+		if err := db.ClearLeaf(id); err != nil {
+			return fmt.Errorf("page %d: %w", id, err)
+		}
+		fmt.Fprintf(w, "cleared page %d\n", id)
+	}
+	return nil
+}
+
+// copyFile 把 src 按字节复制到新文件 dst，dst 已存在时返回错误。
+// 损坏的数据库不一定能通过事务读取，因此不使用 DB.Copy。
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
@@ -1,6 +1,8 @@
 package boltdb_go
 
-const Version = 1
+// Version 是数据文件格式的版本号，其他版本的文件在打开时返回 VersionMismatchError。
+// 版本 2 在存储桶记录中增加了 sequence 字段。
+const Version = 2
 
 const (
 	MaxKeySize  = 511
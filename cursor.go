@@ -1,5 +1,12 @@
 package boltdb_go
 
+import (
+	"bytes"
+	"slices"
+	"sort"
+	"unsafe"
+)
+
 // Cursor 接口定义了操作数据库游标的接口。
 type Cursor interface {
 	// First 将游标定位到当前Bucket中的第一个键值对。
@@ -13,9 +20,9 @@ type Cursor interface {
 	// Current 返回当前游标指向的键和值。
 	Current() ([]byte, []byte, error)
 	// Last 将游标定位到当前Bucket中的最后一个键值对。
-	Last()
+	Last() error
 	// LastDup 将游标定位到当前Bucket中的最后一个重复的键值对。
-	LastDup()
+	LastDup() error
 	// Next 将游标移动到下一个键值对。
 	Next() ([]byte, []byte, error)
 	// NextDup 将游标移动到下一个重复的键值对。
@@ -28,16 +35,38 @@ type Cursor interface {
 	PreDup() ([]byte, []byte, error)
 	// PreNoDup 将游标移动到前一个不重复的键值对。
 	PreNoDup() ([]byte, []byte, error)
-	// Set 将游标定位到指定的键值对。
-	Set() ([]byte, []byte, error)
-	// SetRange 将游标定位到指定范围的键值对。
-	SetRange() ([]byte, []byte, error)
+	// Set 将游标定位到与 key 完全匹配的键值对。
+	Set(key []byte) ([]byte, []byte, error)
+	// SetRange 将游标定位到第一个大于或等于 key 的键值对。
+	SetRange(key []byte) ([]byte, []byte, error)
+	// Count 返回 DupSort 存储桶中当前键的重复值数量。
+	Count() (int, error)
+	// IsBucket 返回当前游标指向的键是否为子存储桶。
+	IsBucket() bool
+	// Put 在游标所在的存储桶中写入一个键值对，并将游标定位到该键值对。
+	Put(key []byte, data []byte, flags int) error
+	// Del 删除游标当前指向的键值对，flags 为 NoDupData 时删除当前键的所有重复值。
+	Del(flags int) error
+	// Close 关闭游标并释放其占用的资源。
+	Close()
 }
 
+// 游标的标志位。
+const (
+	// c_initialized 表示游标已经定位。
+	c_initialized = 0x01
+	// c_eof 表示游标已经移动到末尾。
+	c_eof = 0x02
+	// c_del 表示游标指向的节点已被其他游标删除，游标当前位置即为原节点的下一个节点。
+	c_del = 0x04
+)
+
 // cursor 结构体实现了Cursor接口，具体实现了数据库游标的操作逻辑。
+// 写事务中打开的游标通过 next 链接在 transaction.cursor 中，
+// 以便在页面分裂、合并或删除节点后修正同一存储桶中其他游标的位置。
 type cursor struct {
 	flags       int          // 标志位，用于控制游标行为
-	next        *cursor      // 下一个游标，用于实现嵌套游标操作
+	next        *cursor      // 同一事务、同一存储桶中的下一个游标
 	backup      *cursor      // 备份游标，用于实现回滚等操作
 	xcursor     *xcursor     // 用于底层存储访问的游标
 	transaction *transaction // 关联的事务对象
@@ -53,29 +82,156 @@ type cursor struct {
 
 type xcursor struct {
 	cursor cursor
-	bucket *Bucket
+	bucket *bucket
 	//bucketx    *BucketX
 	bucketFlag int
 }
 
-func (c *cursor) xkeep(pflags int, all int) error {
+// cursorStackSize 是页栈的最大深度，即 B+ 树的最大高度。
+const cursorStackSize = 32
+
+// 页面查找的标志位。
+const (
+	// ps_modify 表示查找的同时把路径上的页面变为当前事务的脏页，以便随后修改。
+	ps_modify = 0x01
+	// ps_first 表示查找最左侧的叶子页面，忽略 key。
+	ps_first = 0x02
+	// ps_last 表示查找最右侧的叶子页面，忽略 key。
+	ps_last = 0x04
+)
+
+// keep 把游标及其子游标的页栈中的页面加入 pages，这些页面在写出脏页时需要保留在内存中。
+func (c *cursor) keep(pages map[*page]bool) {
+	for _, p := range c.page[:c.snum] {
+		pages[p] = true
+	}
+	if c.xcursor != nil {
+		c.xcursor.cursor.keep(pages)
+	}
+}
+
+// touch 使页栈中第 level 层的页面成为当前事务的脏页。
+// 不是脏页的页面被复制到新分配的页面中，原页面被释放，父页面中指向它的分支节点随之更新；
+// 当前事务已经写入文件的页面直接读回内存，页面ID不变；
+// 内联存储桶的数据页被复制到独立的根页面中。同一存储桶中指向原页面的其他游标改为指向新页面。
+// 调用方需要先使第 level-1 层的页面成为脏页。
+func (c *cursor) touch(level int) error {
+	p := c.page[level]
+	if p.flags&p_dirty != 0 {
+		return nil
+	}
+	t, size := c.transaction, c.transaction.db.pageSize
+	if (level != 0 || c.bucket.inline == nil) && t.spillPages[p.id] {
+		c.replacePage(level, p, t.unspill(p))
+		return nil
+	}
+	np, err := t.allocPage(1)
+	if err != nil {
+		return err
+	}
+	id := np.id
+	if level == 0 && c.bucket.inline != nil {
+		copyPage(np.bytes(size), p, len(c.bucket.inline))
+		c.bucket.inline = nil
+		c.bucket.bucket.depth, c.bucket.bucket.leafs = 1, 1
+	} else {
+		copy(np.bytes(size), p.bytes(size))
+		t.freePages = append(t.freePages, int(p.id))
+	}
+	np.id, np.overflow = id, 0
+	np.flags |= p_dirty
+	if level == 0 {
+		c.bucket.bucket.root = id
+	} else {
+		c.page[level-1].node(c.ki[level-1]).setPgno(id)
+	}
+	c.replacePage(level, p, np)
 	return nil
 }
 
-func (c *cursor) spill(key []byte, data []byte) error {
+// replacePage 把游标以及同一存储桶中其他游标的页栈第 level 层的页面 p 替换为 np。
+func (c *cursor) replacePage(level int, p, np *page) {
+	for m := c.transaction.tracked(c.bucketID); m != nil; m = m.next {
+		if m != c && m.snum > level && m.page[level] == p {
+			m.page[level] = np
+		}
+	}
+	c.page[level] = np
+}
+
+// touchAll 使页栈中从根页面到叶子页面的所有页面成为当前事务的脏页。
+func (c *cursor) touchAll() error {
+	for level := 0; level < c.snum; level++ {
+		if err := c.touch(level); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (c *cursor) page_touch() int {
-	return 0
+// copyPage 把大小为 srcSize 的页面 src 复制到 dst 中，dst 的大小可以与 src 不同。
+// 节点的数据被移动到 dst 的末尾，节点的偏移量随之调整，因此 dst 中的空闲空间位于偏移量数组和节点数据之间。
+func copyPage(dst []byte, src *page, srcSize int) {
+	delta := len(dst) - srcSize
+	copy(dst[:src.lower], src.bytes(int(src.lower)))
+	copy(dst[int(src.upper)+delta:], src.bytes(srcSize)[src.upper:])
+	p := (*page)(unsafe.Pointer(&dst[0]))
+	offsets := p.offsets()
+	for i := range offsets {
+		offsets[i] = indx(int(offsets[i]) + delta)
+	}
+	p.upper = indx(int(src.upper) + delta)
 }
 
-func (c *cursor) search(key []byte) (*node, error) {
-	return nil, nil
+// rootPage 返回存储桶的根页面，内联存储桶返回其数据页，空存储桶返回 NotFoundError。
+func (c *cursor) rootPage() (*page, error) {
+	if c.bucket.inline != nil {
+		return (*page)(unsafe.Pointer(&c.bucket.inline[0])), nil
+	}
+	root := c.bucket.bucket.root
+	if root == p_invalid || root == 0 {
+		return nil, NotFoundError
+	}
+	p, _, err := c.transaction.getPage(int(root))
+	return p, err
 }
 
-func (c *cursor) pop() {
+// pageSearch 从存储桶的根页面开始查找 key 所在的叶子页面，并把路径上的页面压入页栈。
+// flags 为页面查找的标志位，存储桶为空时返回 NotFoundError。
+func (c *cursor) pageSearch(key []byte, flags int) error {
+	c.snum, c.top = 0, 0
+	c.flags &^= c_initialized | c_eof | c_del
+	root, err := c.rootPage()
+	if err != nil {
+		return err
+	}
+	if err := c.push(root); err != nil {
+		return err
+	}
+	if flags&ps_modify != 0 {
+		if err := c.touch(0); err != nil {
+			return err
+		}
+	}
+	return c.searchRoot(key, flags)
+}
 
+// search 在叶子页面 p 中查找第一个大于或等于 key 的节点，返回它的索引以及它的键是否等于 key。
+// 所有节点都小于 key 时返回的索引等于节点数量。
+func (c *cursor) search(p *page, key []byte) (int, bool) {
+	n := p.nodeCount()
+	i := sort.Search(n, func(i int) bool {
+		return c.bucket.compare(p.node(i).key(), key) >= 0
+	})
+	return i, i < n && c.bucket.compare(p.node(i).key(), key) == 0
+}
+
+// pop 弹出页栈顶部的页面。
+func (c *cursor) pop() {
+	if c.snum > 0 {
+		c.snum--
+		c.top = max(c.snum-1, 0)
+	}
 }
 
 // push将指定的页面p添加到cursor的内部结构中。
@@ -84,47 +240,1026 @@ func (c *cursor) pop() {
 // p *page - 需要被添加到cursor中的页面指针。
 //
 // 返回值:
-// error - 如果添加过程中遇到错误，则返回非nil的error对象；否则返回nil。
+// error - 页栈已满时返回 CursorFullError，否则返回nil。
 func (c *cursor) push(p *page) error {
+	if c.snum >= cursorStackSize {
+		return CursorFullError
+	}
+	if c.snum < len(c.page) {
+		c.page[c.snum], c.ki[c.snum] = p, 0
+	} else {
+		c.page, c.ki = append(c.page, p), append(c.ki, 0)
+	}
+	c.top = c.snum
+	c.snum++
 	return nil
 }
 
+// searchRoot 从页栈顶部的页面开始逐层向下查找 key 所在的叶子页面，并把经过的页面压入页栈。
+// 分支页面中选择最后一个键小于或等于 key 的节点，第一个节点的键视为无穷小。
 func (c *cursor) searchRoot(key []byte, flags int) error {
+	for {
+		p := c.page[c.top]
+		if p.flags&p_branch == 0 {
+			break
+		}
+		n := p.nodeCount()
+		if n == 0 {
+			return CorruptedError
+		}
+		var i int
+		switch {
+		case flags&ps_first != 0:
+			i = 0
+		case flags&ps_last != 0:
+			i = n - 1
+		default:
+			i = sort.Search(n-1, func(i int) bool {
+				return c.bucket.compare(p.node(i+1).key(), key) > 0
+			})
+		}
+		c.ki[c.top] = i
+		child, _, err := c.transaction.getPage(int(p.node(i).pgno()))
+		if err != nil {
+			return err
+		}
+		if err := c.push(child); err != nil {
+			return err
+		}
+		if flags&ps_modify != 0 {
+			if err := c.touch(c.top); err != nil {
+				return err
+			}
+		}
+	}
+	if c.page[c.top].flags&p_leaf == 0 {
+		return CorruptedError
+	}
 	return nil
 }
-func (c *cursor) searchLowest() error {
-	return nil
+
+// set 把游标定位到叶子页面中第一个大于或等于 key 的位置，返回该位置的键是否等于 key。
+// 所有键都小于 key 时游标位于最后一个叶子页面的末尾，此时 Current 返回 NotFoundError。
+func (c *cursor) set(key []byte, flags int) (bool, error) {
+	if err := c.pageSearch(key, flags); err != nil {
+		return false, err
+	}
+	i, exact := c.search(c.page[c.top], key)
+	c.ki[c.top] = i
+	c.flags |= c_initialized
+	return exact, nil
+}
+
+// Put 在游标所在的存储桶中写入一个键值对。
+func (c *cursor) Put(key []byte, data []byte, flags int) error {
+	if !c.transaction.writable() {
+		return ReadOnlyError
+	}
+	if c.transaction.prepared != nil {
+		return PreparedError
+	}
+	if len(key) == 0 || len(key) > MaxKeySize || uint64(len(data)) > MaxDataSize {
+		return BadValueSizeError
+	}
+	if flags&nodeFlags != 0 {
+		// 节点的类型标识只能由存储桶内部设置。
+		return InvalidArgumentError
+	}
+	_, err := c.put(key, data, flags)
+	return err
 }
 
+// Del 删除游标当前指向的键值对。
 func (c *cursor) Del(flags int) error {
+	if !c.transaction.writable() {
+		return ReadOnlyError
+	}
+	if c.transaction.prepared != nil {
+		return PreparedError
+	}
+	if c.flags&c_initialized == 0 || c.snum == 0 {
+		return InvalidArgumentError
+	}
+	if c.flags&c_del != 0 || (c.xcursor != nil && c.xcursor.cursor.flags&c_del != 0) {
+		// 当前节点或重复值已被删除。
+		return NotFoundError
+	}
+	if err := c.transaction.spill(c); err != nil {
+		return err
+	}
+	key, value, err := c.Current()
+	if err != nil {
+		return err
+	}
+	// 键和值引用页面中的数据，删除节点时会被覆盖。
+	key, value = bytes.Clone(key), bytes.Clone(value)
+	if c.IsBucket() {
+		// 子存储桶只能通过 DeleteBucket 删除。
+		return InCompatibleError
+	}
+	if c.xcursor != nil && flags&NoDupData == 0 {
+		// 只删除当前的重复值，子游标为空时才删除整个键。
+		if n, err := c.count(); err != nil {
+			return err
+		} else if n > 1 {
+			if err := c.delDup(key, value); err != nil {
+				return err
+			}
+			c.transaction.record(c.bucket, OpDelete, key, value)
+			return nil
+		}
+	}
+	if err := c.del0(c.page[c.top].node(c.ki[c.top])); err != nil {
+		return err
+	}
+	c.transaction.record(c.bucket, OpDelete, key, nil)
 	return nil
 }
 
-func (c *cursor) newPage(flags int, num int) ([]*page, error) {
-	return nil, nil
+// put 在游标所在的存储桶中写入一个键值对，返回值在页面中的数据区。
+// 写入前根据 flags 检查键值对是否已存在以及追加的顺序是否正确。
+func (c *cursor) put(key []byte, data []byte, flags int) ([]byte, error) {
+	dupSort := c.bucketFlag&DupSort != 0
+	if flags&Reserve != 0 && dupSort {
+		return nil, InCompatibleError
+	}
+	if dupSort && len(data) > MaxKeySize {
+		// 重复值保存为子页面或子树中的键，大小受键的大小限制。
+		return nil, BadValueSizeError
+	}
+	if err := c.transaction.spill(c); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case flags&Current != 0:
+		k, v, err := c.Current()
+		if err != nil {
+			return nil, err
+		}
+		if c.bucket.compare(k, key) != 0 {
+			return nil, InvalidArgumentError
+		}
+		if dupSort && c.bucket.compare(v, data) != 0 {
+			// 重复值按值排序，替换后的值必须与当前值相同。
+			return nil, InvalidArgumentError
+		}
+
+	case flags&(Append|AppendDup) != 0:
+		// 追加时只需与最后一个键值对比较；查找最后一个键值对时已经修改了最右侧路径上的页面，
+		// insert 直接复用这条路径，不再从根节点查找 key。
+		k, v, err := c.seekAppend()
+		if err == NotFoundError {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		cmp := c.bucket.compare(key, k)
+		if cmp < 0 || (cmp == 0 && (!dupSort || flags&AppendDup == 0)) {
+			return nil, KeyExistError
+		}
+		if cmp == 0 && c.bucket.compare(data, v) <= 0 {
+			return nil, KeyExistError
+		}
+
+	default:
+		_, v, err := c.Set(key)
+		if err == NotFoundError {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if flags&NoOverwrite != 0 {
+			return v, KeyExistError
+		}
+	}
+	v, err := c.insert(key, data, flags)
+	if err == KeyExistError && dupSort && flags&NoDupData == 0 {
+		// 相同的键值对已存在，没有修改，也不产生事件。
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if flags&bucketNode == 0 {
+		if flags&Reserve != 0 {
+			c.transaction.record(c.bucket, OpPut, key, nil)
+		} else {
+			c.transaction.record(c.bucket, OpPut, key, data)
+		}
+	}
+	return v, nil
 }
 
-func (c *cursor) addNode(index int, key []byte, data []byte, pgno int, flags int) error {
-	return nil
+// insert 将游标定位到 key 并插入节点，key 已存在时覆盖它，返回节点在页面中的数据区。
+// 设置 Reserve 时数据区不会被填充，由调用方直接写入。
+// 设置 Append 或 AppendDup 时游标已经由 seekAppend 定位，新节点插入在最后一个叶子页面的末尾。
+func (c *cursor) insert(key []byte, data []byte, flags int) ([]byte, error) {
+	var exact bool
+	var err error
+	if flags&(Append|AppendDup) != 0 {
+		exact, err = c.appendIndex(key)
+	} else {
+		exact, err = c.set(key, ps_modify)
+	}
+	if err == NotFoundError && c.snum == 0 {
+		// 存储桶为空，为其创建根页面。
+		if err = c.newRoot(); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	if exact {
+		if n := c.page[c.top].node(c.ki[c.top]); int(n.flags)&bucketNode != flags&bucketNode {
+			// 普通的键值对和子存储桶不能相互覆盖。
+			return nil, InCompatibleError
+		}
+		if c.bucketFlag&DupSort != 0 && flags&bucketNode == 0 {
+			return nil, c.putDup(key, data)
+		}
+		return c.overwrite(key, data, flags)
+	}
+	p, index := c.page[c.top], c.ki[c.top]
+	c.fixInsert(c.top, p, index)
+	v, err := c.addNode(p, index, key, data, 0, flags)
+	if err == PageFullError {
+		v, err = c.splitPage(key, data, 0, flags)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.bucket.bucket.entries++
+	c.flags |= c_initialized
+	return v, nil
 }
-func (c *cursor) deleteNode(ksize int) {
 
+// seekAppend 沿最右侧的路径把页面变为当前事务的脏页，并将游标定位到最后一个键值对，返回它的键和值。
+// 存储桶为空时返回 NotFoundError，此时页栈中仍保留空的根页面（如果存在）。
+func (c *cursor) seekAppend() ([]byte, []byte, error) {
+	c.xreset()
+	if err := c.pageSearch(nil, ps_last|ps_modify); err != nil {
+		return nil, nil, err
+	}
+	n := c.page[c.top].nodeCount()
+	if n == 0 {
+		return nil, nil, NotFoundError
+	}
+	c.ki[c.top] = n - 1
+	c.flags |= c_initialized
+	if c.dup() {
+		if err := c.xcursor.cursor.Last(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return c.Current()
+}
+
+// appendIndex 在 seekAppend 建立的页栈顶部确定 key 的位置：key 等于最后一个键时指向它，否则指向页面末尾。
+// 存储桶没有根页面时返回 NotFoundError。
+func (c *cursor) appendIndex(key []byte) (bool, error) {
+	if c.snum == 0 {
+		return false, NotFoundError
+	}
+	p := c.page[c.top]
+	n := p.nodeCount()
+	exact := n > 0 && c.bucket.compare(p.node(n-1).key(), key) == 0
+	c.ki[c.top] = n
+	if exact {
+		c.ki[c.top] = n - 1
+	}
+	c.flags |= c_initialized
+	return exact, nil
+}
+
+// overwrite 覆盖游标当前指向的节点的数据，返回节点在页面中的数据区。
+// 大小不变的数据直接在原位置覆盖，大节点的数据尽量写回当前事务已分配的溢出页面，
+// 否则释放原溢出页面，删除节点后在同一位置重新插入。
+func (c *cursor) overwrite(key []byte, data []byte, flags int) ([]byte, error) {
+	t, p, index := c.transaction, c.page[c.top], c.ki[c.top]
+	n := p.node(index)
+	big := nodeHeaderSize+len(key)+len(data) > t.db.maxNodeSize
+	if n.flags&bigNode != 0 {
+		id := n.overflowPgno()
+		op, level, err := t.getPage(int(id))
+		if err != nil {
+			return nil, err
+		}
+		if big && level == 1 && pageHeaderSize+len(data) <= op.overflow*t.db.pageSize {
+			n.setFlags(flags&nodeFlags | bigNode)
+			n.setDataSize(len(data))
+			v := op.bytes(pageHeaderSize + len(data))[pageHeaderSize:]
+			if flags&Reserve == 0 {
+				copy(v, data)
+			}
+			return v, nil
+		}
+		c.freeOverflow(id, op.overflow)
+	} else if !big && n.dataSize() == len(data) {
+		n.setFlags(flags & nodeFlags)
+		v := n.value()
+		if flags&Reserve == 0 {
+			copy(v, data)
+		}
+		return v, nil
+	}
+	p.removeNode(index)
+	v, err := c.addNode(p, index, key, data, 0, flags)
+	if err == PageFullError {
+		v, err = c.splitPage(key, data, 0, flags)
+	}
+	return v, err
+}
+
+// newRoot 为空的存储桶创建一个叶子页面作为根页面，并将其压入页栈。
+func (c *cursor) newRoot() error {
+	p, err := c.newPage(p_leaf, 1)
+	if err != nil {
+		return err
+	}
+	c.bucket.bucket.root = p.id
+	c.bucket.bucket.depth = 1
+	return c.push(p)
+}
+
+// newPage 为游标所在的存储桶分配 num 个连续的页面，并将其初始化为 flags 类型的空页面。
+// 存储桶记录中对应类型的页面计数随之增加。
+func (c *cursor) newPage(flags int, num int) (*page, error) {
+	p, err := c.transaction.allocPage(num)
+	if err != nil {
+		return nil, err
+	}
+	p.init(flags, c.transaction.db.pageSize)
+	rec := c.bucket.bucket
+	switch {
+	case flags&p_overflow != 0:
+		p.overflow = num
+		rec.overflows += pgno(num)
+	case flags&p_branch != 0:
+		rec.branches++
+	default:
+		rec.leafs++
+	}
+	return p, nil
+}
+
+// addNode 在页面 p 的第 index 个位置插入一个节点。
+// 分支节点指向子页面 child；叶子节点保存 data，数据过大时保存在新分配的溢出页面中。
+// 返回叶子节点的数据区，页面剩余空间不足时返回 PageFullError。
+func (c *cursor) addNode(p *page, index int, key []byte, data []byte, child pgno, flags int) ([]byte, error) {
+	db := c.transaction.db
+	if p.flags&p_branch != 0 {
+		if db.BranchSize(key) > p.remainingSize() {
+			return nil, PageFullError
+		}
+		p.insertNode(index, key, 0).setPgno(child)
+		return nil, nil
+	}
+
+	if db.LeafSize(key, data) > p.remainingSize() {
+		return nil, PageFullError
+	}
+	if nodeHeaderSize+len(key)+len(data) <= db.maxNodeSize {
+		n := p.insertNode(index, key, len(data))
+		n.setFlags(flags & nodeFlags)
+		n.setDataSize(len(data))
+		v := n.value()
+		if flags&Reserve == 0 {
+			copy(v, data)
+		}
+		return v, nil
+	}
+
+	count := (pageHeaderSize + len(data) + db.pageSize - 1) / db.pageSize
+	op, err := c.newPage(p_overflow, count)
+	if err != nil {
+		return nil, err
+	}
+	n := p.insertNode(index, key, int(unsafe.Sizeof(pgno(0))))
+	n.setFlags(flags&nodeFlags | bigNode)
+	n.setDataSize(len(data))
+	n.setOverflowPgno(op.id)
+	v := op.bytes(pageHeaderSize + len(data))[pageHeaderSize:]
+	if flags&Reserve == 0 {
+		copy(v, data)
+	}
+	return v, nil
+}
+
+// deleteNode 删除页栈顶部的页面中游标指向的节点，并释放大节点占用的溢出页面以及重复值子树的页面。
+func (c *cursor) deleteNode() error {
+	p, index := c.page[c.top], c.ki[c.top]
+	n := p.node(index)
+	switch {
+	case p.flags&p_leaf == 0:
+	case n.flags&bigNode != 0:
+		op, _, err := c.transaction.getPage(int(n.overflowPgno()))
+		if err != nil {
+			return err
+		}
+		c.freeOverflow(n.overflowPgno(), op.overflow)
+	case n.flags&dupNode != 0 && n.flags&subNode == 0:
+		if err := c.freeTree((*bucket)(unsafe.Pointer(&n.value()[0])).root); err != nil {
+			return err
+		}
+	}
+	p.removeNode(index)
+	return nil
 }
-func (c *cursor) xcursor_init0() {
 
+// xcursor_init0 初始化 DupSort 存储桶的子游标，子游标把重复值当作键来遍历。
+func (c *cursor) xcursor_init0() {
+	mx := c.xcursor
+	mx.bucket = &bucket{}
+	mx.cursor.init(c.transaction, &Bucket{
+		transaction: c.transaction,
+		id:          c.bucketID,
+		bucket:      mx.bucket,
+		compare:     c.bucket.compare,
+	}, nil)
 }
+
+// xcursor_init1 根据叶子节点 n 初始化子游标所在的重复值集合。
+// 重复值较少时以子页面的形式内联在节点中，子游标把子页面当作内联存储桶的数据页；
+// 重复值较多时存放在独立的子树中，节点数据即子树的存储桶记录。
+// 两种情况下重复值的数量都保存在 xcursor.bucket.entries 中，无需遍历即可获得。
 func (c *cursor) xcursor_init1(n *node) {
+	mx := c.xcursor
+	if n.flags&subNode != 0 {
+		sp := (*page)(unsafe.Pointer(&n.value()[0]))
+		*mx.bucket = bucket{entries: uint64(sp.nodeCount())}
+		mx.cursor.bucket.inline = n.value()
+	} else {
+		*mx.bucket = *(*bucket)(unsafe.Pointer(&n.value()[0]))
+		mx.cursor.bucket.inline = nil
+	}
+	mx.bucketFlag = int(mx.bucket.flags)
+}
+
+// dup 返回当前游标指向的节点是否包含多个重复值，并在需要时初始化子游标。
+// 子游标首次初始化时定位到第一个重复值；之后每次调用都根据节点重新读取子页面或子树的记录，
+// 使其在节点被其他游标移动或修改后依然有效。
+func (c *cursor) dup() bool {
+	if c.xcursor == nil || c.flags&c_initialized == 0 || c.snum == 0 {
+		return false
+	}
+	n := c.page[c.top].node(c.ki[c.top])
+	if n == nil || n.flags&dupNode == 0 {
+		return false
+	}
+	mx := c.xcursor
+	if mx.cursor.transaction == nil {
+		c.xcursor_init0()
+	}
+	c.xcursor_init1(n)
+	if mx.cursor.flags&c_initialized == 0 {
+		mx.cursor.snum, mx.cursor.top = 0, 0
+		mx.cursor.First()
+	} else if n.flags&subNode != 0 && mx.cursor.snum > 0 {
+		mx.cursor.page[0] = (*page)(unsafe.Pointer(&mx.cursor.bucket.inline[0]))
+	}
+	return true
+}
+
+// subPage 返回由 values 组成的子页面，values 必须已经按存储桶的比较函数排序。
+// 子页面是一个紧凑的叶子页面，每个重复值是一个没有数据的节点的键。
+func subPage(values [][]byte) []byte {
+	size := pageHeaderSize
+	for _, v := range values {
+		size += even(nodeHeaderSize+len(v)) + int(unsafe.Sizeof(indx(0)))
+	}
+	buf := make([]byte, size)
+	p := (*page)(unsafe.Pointer(&buf[0]))
+	p.init(p_leaf|p_sub, size)
+	for i, v := range values {
+		p.insertNode(i, v, 0)
+	}
+	return buf
+}
 
+// subPageValues 返回节点 n 的子页面中所有重复值的副本。
+func subPageValues(n *node) [][]byte {
+	sp := (*page)(unsafe.Pointer(&n.value()[0]))
+	values := make([][]byte, sp.nodeCount())
+	for i := range values {
+		values[i] = bytes.Clone(sp.node(i).key())
+	}
+	return values
 }
 
-func (c *cursor) init(t *transaction, bucket *Bucket, mx *xcursor) {
+// subCursor 返回一个用于修改重复值子树 rec 的游标，子树的页面计数记录在 rec 中。
+func (c *cursor) subCursor(rec *bucket) *cursor {
+	xc := &cursor{}
+	xc.init(c.transaction, &Bucket{
+		transaction: c.transaction,
+		id:          c.bucketID,
+		bucket:      rec,
+		compare:     c.bucket.compare,
+		FillPercent: c.bucket.FillPercent,
+	}, nil)
+	return xc
+}
 
+// putDup 将 data 写入为游标当前指向的键的一个重复值，调用方需要先使页栈中的页面成为脏页。
+// 键只有一个值时保存为普通节点；有多个值时以子页面的形式内联在节点中，
+// 子页面超过节点的最大大小后转换为独立的子树。data 已存在时返回 KeyExistError。
+func (c *cursor) putDup(key []byte, data []byte) error {
+	n := c.page[c.top].node(c.ki[c.top])
+	switch {
+	case n.flags&dupNode == 0:
+		v := bytes.Clone(n.value())
+		cmp := c.bucket.compare(data, v)
+		if cmp == 0 {
+			return KeyExistError
+		}
+		values := [][]byte{v, data}
+		if cmp < 0 {
+			values[0], values[1] = data, v
+		}
+		if err := c.putSubPage(key, values); err != nil {
+			return err
+		}
+	case n.flags&subNode != 0:
+		values := subPageValues(n)
+		i, found := slices.BinarySearchFunc(values, data, c.bucket.compare)
+		if found {
+			return KeyExistError
+		}
+		if err := c.putSubPage(key, slices.Insert(values, i, data)); err != nil {
+			return err
+		}
+	default:
+		rec := *(*bucket)(unsafe.Pointer(&n.value()[0]))
+		xc := c.subCursor(&rec)
+		if exact, err := xc.set(data, 0); err != nil && err != NotFoundError {
+			return err
+		} else if exact {
+			return KeyExistError
+		}
+		if _, err := xc.insert(data, nil, 0); err != nil {
+			return err
+		}
+		c.setSubTree(&rec)
+	}
+	c.bucket.bucket.entries++
+	c.xreset()
+	return nil
 }
+
+// putSubPage 将游标当前指向的节点替换为由 values 组成的子页面，
+// 子页面超过节点的最大大小时将 values 写入新的子树，节点中只保存子树的记录。
+func (c *cursor) putSubPage(key []byte, values [][]byte) error {
+	sub := subPage(values)
+	if nodeHeaderSize+len(key)+len(sub) <= c.transaction.db.maxNodeSize {
+		_, err := c.overwrite(key, sub, dupNode|subNode)
+		return err
+	}
+	rec := bucket{root: p_invalid}
+	xc := c.subCursor(&rec)
+	for _, v := range values {
+		if _, err := xc.insert(v, nil, 0); err != nil {
+			return err
+		}
+	}
+	_, err := c.overwrite(key, unsafe.Slice((*byte)(unsafe.Pointer(&rec)), bucketHeaderSize), dupNode)
+	return err
+}
+
+// setSubTree 将子树的记录 rec 写回游标当前指向的节点。
+func (c *cursor) setSubTree(rec *bucket) {
+	n := c.page[c.top].node(c.ki[c.top])
+	*(*bucket)(unsafe.Pointer(&n.value()[0])) = *rec
+}
+
+// delDup 删除游标当前指向的键的重复值 value，该键必须至少还有两个重复值。
+// 只剩一个值时节点恢复为普通节点。删除后游标指向下一个重复值，子游标被标记为 c_del。
+func (c *cursor) delDup(key []byte, value []byte) error {
+	if err := c.touchAll(); err != nil {
+		return err
+	}
+	n := c.page[c.top].node(c.ki[c.top])
+	if n.flags&subNode != 0 {
+		values := subPageValues(n)
+		i, found := slices.BinarySearchFunc(values, value, c.bucket.compare)
+		if !found {
+			return NotFoundError
+		}
+		values = slices.Delete(values, i, i+1)
+		var err error
+		if len(values) == 1 {
+			_, err = c.overwrite(key, values[0], 0)
+		} else {
+			_, err = c.overwrite(key, subPage(values), dupNode|subNode)
+		}
+		if err != nil {
+			return err
+		}
+	} else {
+		rec := *(*bucket)(unsafe.Pointer(&n.value()[0]))
+		xc := c.subCursor(&rec)
+		exact, err := xc.set(value, ps_modify)
+		if err != nil {
+			return err
+		}
+		if !exact {
+			return NotFoundError
+		}
+		if err := xc.del0(xc.page[xc.top].node(xc.ki[xc.top])); err != nil {
+			return err
+		}
+		c.setSubTree(&rec)
+	}
+	c.bucket.bucket.entries--
+
+	c.xreset()
+	if !c.dup() {
+		// 只剩一个值，它位于被删除的值之后时就是下一个值。
+		if c.bucket.compare(c.page[c.top].node(c.ki[c.top]).value(), value) > 0 {
+			c.flags |= c_del
+		}
+		return nil
+	}
+	mx := &c.xcursor.cursor
+	if _, err := mx.set(value, 0); err != nil {
+		return err
+	}
+	mx.flags |= c_del
+	return nil
+}
+
+// init 将游标绑定到事务 t 中的存储桶 b，mx 为 DupSort 存储桶使用的子游标。
+func (c *cursor) init(t *transaction, b *Bucket, mx *xcursor) {
+	c.transaction = t
+	c.bucket = b
+	c.bucketID = b.id
+	c.bucketFlag = b.flags
+	c.xcursor = mx
+}
+
+// xreset 在游标移动到其他键之前重置子游标，使其在需要时根据新的节点重新初始化。
+func (c *cursor) xreset() {
+	if c.xcursor != nil {
+		c.xcursor.cursor.flags &^= c_initialized
+	}
+}
+
+// count 返回当前键的重复值数量，非 DupSort 存储桶返回 InCompatibleError。
 func (c *cursor) count() (int, error) {
-	return 0, nil
+	if c.xcursor == nil {
+		return 0, InCompatibleError
+	}
+	if c.flags&c_initialized == 0 || c.snum == 0 {
+		return 0, InvalidArgumentError
+	}
+	if !c.dup() {
+		return 1, nil
+	}
+	return int(c.xcursor.bucket.entries), nil
+}
+
+// Count 返回 DupSort 存储桶中当前键的重复值数量。
+func (c *cursor) Count() (int, error) {
+	return c.count()
+}
+
+// First 将游标定位到 Bucket 中最小的键，Bucket 为空时返回 NotFoundError。
+func (c *cursor) First() error {
+	c.xreset()
+	if err := c.pageSearch(nil, ps_first); err != nil {
+		return err
+	}
+	if c.page[c.top].nodeCount() == 0 {
+		return NotFoundError
+	}
+	c.ki[c.top] = 0
+	c.flags |= c_initialized
+	return nil
+}
+
+// FirstDup 将游标定位到当前键的第一个重复值。
+func (c *cursor) FirstDup() error {
+	if c.xcursor == nil {
+		return InCompatibleError
+	}
+	if c.flags&c_initialized == 0 {
+		return InvalidArgumentError
+	}
+	if !c.dup() {
+		return nil
+	}
+	return c.xcursor.cursor.First()
+}
+
+// Last 将游标定位到 Bucket 中最大的键，Bucket 为空时返回 NotFoundError。
+func (c *cursor) Last() error {
+	c.xreset()
+	if err := c.pageSearch(nil, ps_last); err != nil {
+		return err
+	}
+	n := c.page[c.top].nodeCount()
+	if n == 0 {
+		return NotFoundError
+	}
+	c.ki[c.top] = n - 1
+	c.flags |= c_initialized
+	if c.dup() {
+		return c.xcursor.cursor.Last()
+	}
+	return nil
+}
+
+// LastDup 将游标定位到当前键的最后一个重复值。
+func (c *cursor) LastDup() error {
+	if c.xcursor == nil {
+		return InCompatibleError
+	}
+	if c.flags&c_initialized == 0 {
+		return InvalidArgumentError
+	}
+	if !c.dup() {
+		return nil
+	}
+	return c.xcursor.cursor.Last()
+}
+
+// Get 返回当前游标指向的键和值。
+func (c *cursor) Get() ([]byte, []byte, error) {
+	return c.Current()
+}
+
+// GetRange 返回当前范围内的键和值。
+func (c *cursor) GetRange() ([]byte, []byte, error) {
+	return c.Current()
+}
+
+// Current 返回当前游标指向的键和值，游标尚未定位时返回 NotFoundError。
+// DupSort 存储桶中的重复值保存为子游标的键，因此值取自子游标的当前位置。
+func (c *cursor) Current() ([]byte, []byte, error) {
+	if c.snum == 0 {
+		return nil, nil, NotFoundError
+	}
+	n := c.page[c.top].node(c.ki[c.top])
+	if n == nil {
+		return nil, nil, NotFoundError
+	}
+	if c.dup() {
+		v, _, err := c.xcursor.cursor.Current()
+		if err != nil {
+			return nil, nil, err
+		}
+		return c.transaction.guard(n.key(), v)
+	}
+	v, err := c.transaction.readNode(n)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.transaction.guard(n.key(), v)
+}
+
+// Next 将游标移动到下一个键值对，DupSort 存储桶中先遍历完当前键的所有重复值。
+func (c *cursor) Next() ([]byte, []byte, error) {
+	if c.flags&c_del != 0 {
+		// 原节点已被删除，由 NextNoDup 返回当前位置的节点。
+		return c.NextNoDup()
+	}
+	if c.dup() {
+		if _, _, err := c.xcursor.cursor.Next(); err == nil {
+			return c.Current()
+		} else if err != NotFoundError {
+			return nil, nil, err
+		}
+	}
+	return c.NextNoDup()
+}
+
+// NextDup 将游标移动到当前键的下一个重复值，已是最后一个时返回 NotFoundError。
+func (c *cursor) NextDup() ([]byte, []byte, error) {
+	if !c.dup() {
+		return nil, nil, NotFoundError
+	}
+	if _, _, err := c.xcursor.cursor.Next(); err != nil {
+		return nil, nil, err
+	}
+	return c.Current()
+}
+
+// NextNoDup 将游标移动到下一个键，跳过当前键剩余的重复值。
+// 游标尚未定位时移动到第一个键，已经位于最后一个键时返回 NotFoundError。
+func (c *cursor) NextNoDup() ([]byte, []byte, error) {
+	c.xreset()
+	if c.flags&c_initialized == 0 || c.snum == 0 {
+		if err := c.First(); err != nil {
+			return nil, nil, err
+		}
+		return c.Current()
+	}
+	if c.flags&c_eof != 0 {
+		return nil, nil, NotFoundError
+	}
+	if c.flags&c_del != 0 {
+		// 原节点已被删除，当前位置就是下一个节点。
+		c.flags &^= c_del
+	} else {
+		c.ki[c.top]++
+	}
+	if c.ki[c.top] >= c.page[c.top].nodeCount() {
+		if err := c.sibling(true); err != nil {
+			if err == NotFoundError {
+				c.ki[c.top] = c.page[c.top].nodeCount()
+				c.flags |= c_eof
+			}
+			return nil, nil, err
+		}
+	}
+	return c.Current()
+}
+
+// Pre 将游标移动到前一个键值对，DupSort 存储桶中先反向遍历完当前键的所有重复值。
+func (c *cursor) Pre() ([]byte, []byte, error) {
+	if c.dup() {
+		if _, _, err := c.xcursor.cursor.Pre(); err == nil {
+			return c.Current()
+		} else if err != NotFoundError {
+			return nil, nil, err
+		}
+	}
+	return c.PreNoDup()
+}
+
+// PreDup 将游标移动到当前键的前一个重复值，已是第一个时返回 NotFoundError。
+func (c *cursor) PreDup() ([]byte, []byte, error) {
+	if !c.dup() {
+		return nil, nil, NotFoundError
+	}
+	if _, _, err := c.xcursor.cursor.Pre(); err != nil {
+		return nil, nil, err
+	}
+	return c.Current()
+}
+
+// PreNoDup 将游标移动到前一个键，跳过当前键剩余的重复值。
+// 游标尚未定位时移动到最后一个键，已经位于第一个键时返回 NotFoundError。
+func (c *cursor) PreNoDup() ([]byte, []byte, error) {
+	c.xreset()
+	if c.flags&c_initialized == 0 || c.snum == 0 {
+		if err := c.Last(); err != nil {
+			return nil, nil, err
+		}
+		return c.Current()
+	}
+	c.flags &^= c_eof | c_del
+	if c.ki[c.top] > 0 {
+		c.ki[c.top]--
+	} else if err := c.sibling(false); err != nil {
+		return nil, nil, err
+	}
+	if c.dup() {
+		// 反向移动到前一个键时定位到它的最后一个重复值。
+		if err := c.xcursor.cursor.Last(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return c.Current()
+}
+
+// sibling 将游标移动到右侧（right 为 true）或左侧相邻的叶子页面，
+// 并定位到该页面的第一个或最后一个节点。没有相邻的页面时游标保持不变并返回 NotFoundError。
+// 每次跨越页面时检查事务的 context，context 被取消时游标保持不变并返回 ctx.Err()，使长时间的遍历可以被中止。
+func (c *cursor) sibling(right bool) error {
+	if err := c.transaction.Context().Err(); err != nil {
+		return err
+	}
+	level := c.top - 1
+	for ; level >= 0; level-- {
+		if right && c.ki[level]+1 < c.page[level].nodeCount() {
+			c.ki[level]++
+			break
+		}
+		if !right && c.ki[level] > 0 {
+			c.ki[level]--
+			break
+		}
+	}
+	if level < 0 {
+		return NotFoundError
+	}
+	for ; level < c.top; level++ {
+		child, _, err := c.transaction.getPage(int(c.page[level].node(c.ki[level]).pgno()))
+		if err != nil {
+			return err
+		}
+		c.page[level+1], c.ki[level+1] = child, 0
+		if !right {
+			c.ki[level+1] = child.nodeCount() - 1
+		}
+	}
+	return nil
+}
+
+// Set 将游标定位到与 key 完全匹配的键，key 不存在时返回 NotFoundError。
+func (c *cursor) Set(key []byte) ([]byte, []byte, error) {
+	c.xreset()
+	exact, err := c.set(key, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exact {
+		c.flags &^= c_initialized
+		return nil, nil, NotFoundError
+	}
+	return c.Current()
+}
+
+// SetRange 将游标定位到第一个大于或等于 key 的键。
+func (c *cursor) SetRange(key []byte) ([]byte, []byte, error) {
+	c.xreset()
+	if _, err := c.set(key, 0); err != nil {
+		return nil, nil, err
+	}
+	if c.ki[c.top] >= c.page[c.top].nodeCount() {
+		// key 大于叶子页面中的所有键，第一个大于它的键位于右侧相邻的页面中。
+		if err := c.sibling(true); err != nil {
+			if err == NotFoundError {
+				c.flags |= c_eof
+			}
+			return nil, nil, err
+		}
+	}
+	return c.Current()
+}
+
+// IsBucket 返回当前游标指向的键是否为子存储桶。
+func (c *cursor) IsBucket() bool {
+	if c.snum == 0 {
+		return false
+	}
+	n := c.page[c.top].node(c.ki[c.top])
+	return n != nil && n.flags&bucketNode != 0
+}
+
+// Close 关闭游标，并将其从事务的游标链表中移除。
+func (c *cursor) Close() {
+	if c.transaction != nil {
+		c.transaction.untrack(c)
+	}
+	c.snum, c.top = 0, 0
+	c.flags &^= c_initialized | c_eof | c_del
+}
+
+// fixInsert 在页面 p 的第 index 个位置插入节点后，修正同一存储桶中其他游标在该层的索引。
+func (c *cursor) fixInsert(level int, p *page, index int) {
+	for m := c.transaction.tracked(c.bucketID); m != nil; m = m.next {
+		if m != c && m.snum > level && m.page[level] == p && m.ki[level] >= index {
+			m.ki[level]++
+		}
+	}
+}
+
+// fixDelete 在删除页面 p 的第 index 个节点后，修正同一存储桶中其他游标在该层的索引。
+// 指向被删除节点的游标被标记为 c_del，下一次 Next 时直接返回其当前位置的节点。
+func (c *cursor) fixDelete(level int, p *page, index int) {
+	for m := c.transaction.tracked(c.bucketID); m != nil; m = m.next {
+		if m == c || m.snum <= level || m.page[level] != p {
+			continue
+		}
+		if m.ki[level] == index {
+			m.flags |= c_del
+		} else if m.ki[level] > index {
+			m.ki[level]--
+		}
+	}
+}
+
+// fixSplit 在页面 p 从第 split 个节点起分裂到新页面 rp 后，将指向后半部分的游标移动到 rp。
+// rp 在父页面中紧跟在 p 之后，因此被移动的游标在父页面中的索引也需要加一。
+func (c *cursor) fixSplit(level int, p *page, rp *page, split int) {
+	for m := c.transaction.tracked(c.bucketID); m != nil; m = m.next {
+		if m == c || m.snum <= level || m.page[level] != p || m.ki[level] < split {
+			continue
+		}
+		m.page[level] = rp
+		m.ki[level] -= split
+		if level > 0 {
+			m.ki[level-1]++
+		}
+	}
 }
 
-func (c *cursor) Close() {}
+// fixMerge 在页面 src 的节点合并到页面 dst 的末尾后，将指向 src 的游标移动到 dst。
+// 参数:
+//   - offset: 合并前 dst 中的节点数量。
+//   - parentIndex: dst 在父页面中的索引。
+func (c *cursor) fixMerge(level int, src *page, dst *page, offset int, parentIndex int) {
+	for m := c.transaction.tracked(c.bucketID); m != nil; m = m.next {
+		if m == c || m.snum <= level || m.page[level] != src {
+			continue
+		}
+		m.page[level] = dst
+		m.ki[level] += offset
+		if level > 0 {
+			m.ki[level-1] = parentIndex
+		}
+	}
+}
 
 func (c *cursor) Transaction() Transaction {
 	return nil
@@ -134,32 +1269,475 @@ func (c *cursor) Bucket() *Bucket {
 	return c.bucket
 }
 
+// updateKey 将页栈顶部的分支页面中游标指向的节点的键替换为 key，页面剩余空间不足时分裂页面。
 func (c *cursor) updateKey(key []byte) error {
-	return nil
+	p, index := c.page[c.top], c.ki[c.top]
+	n := p.node(index)
+	if bytes.Equal(n.key(), key) {
+		return nil
+	}
+	key, child := bytes.Clone(key), n.pgno()
+	p.removeNode(index)
+	_, err := c.addNode(p, index, key, nil, child, 0)
+	if err == PageFullError {
+		_, err = c.splitPage(key, nil, child, 0)
+	}
+	return err
 }
 
+// parentCursor 返回一个与游标共享页面、页栈顶部为游标所在页面的父页面的游标。
+func (c *cursor) parentCursor() *cursor {
+	pc := &cursor{}
+	c.copyTo(pc)
+	pc.snum--
+	pc.top--
+	return pc
+}
+
+// moveNodeTo 将游标指向的节点移动到 dst 指向的位置，两个页面必须是同一父页面下相邻的页面，
+// 并且节点只能从页面的开头或末尾移出，移动到另一个页面的末尾或开头。
+// 页面的第一个节点发生变化时，父页面中对应的分隔键随之更新。
 func (c *cursor) moveNodeTo(dst *cursor) error {
-	return nil
+	level := c.top
+	src, si := c.page[level], c.ki[level]
+	dp, di := dst.page[level], dst.ki[level]
+	n := src.node(si)
+	key := bytes.Clone(n.key())
+	if src.flags&p_branch != 0 {
+		if si == 0 {
+			// 分支页面的第一个节点的键不一定有效，使用父页面中的分隔键。
+			key = bytes.Clone(c.page[level-1].node(c.ki[level-1]).key())
+		}
+		if _, err := c.addNode(dp, di, key, nil, n.pgno(), 0); err != nil {
+			return err
+		}
+	} else {
+		if src.nodeSize(si) > dp.remainingSize() {
+			return PageFullError
+		}
+		dp.copyNode(di, src, si)
+	}
+	src.removeNode(si)
+
+	for m := c.transaction.tracked(c.bucketID); m != nil; m = m.next {
+		if m == c || m == dst || m.snum <= level {
+			continue
+		}
+		switch {
+		case m.page[level] == dp && m.ki[level] >= di:
+			m.ki[level]++
+		case m.page[level] == src && m.ki[level] == si:
+			m.page[level], m.ki[level] = dp, di
+			m.ki[level-1] = dst.ki[level-1]
+		case m.page[level] == src && m.ki[level] > si:
+			m.ki[level]--
+		}
+	}
+
+	if si == 0 {
+		// src 位于 dst 的右侧，它新的第一个键成为父页面中的分隔键。
+		return c.parentCursor().updateKey(src.node(0).key())
+	}
+	// dst 位于 src 的右侧，被移动的节点成为 dst 的第一个节点。
+	parent := dst.parentCursor()
+	if dp.flags&p_branch != 0 {
+		// dst 原来的第一个节点改用父页面中原来的分隔键。
+		second := &cursor{}
+		dst.copyTo(second)
+		second.ki[level] = 1
+		if err := second.updateKey(parent.page[level-1].node(parent.ki[level-1]).key()); err != nil {
+			return err
+		}
+	}
+	return parent.updateKey(key)
 }
 
+// mergePage 将游标所在的页面合并到 dst 所在页面的末尾，dst 所在页面必须是它左侧相邻的页面。
+// 合并后释放原页面，从父页面中删除指向它的节点，并重新平衡父页面。
 func (c *cursor) mergePage(dst *cursor) error {
-	return nil
+	level := c.top
+	src, dp := c.page[level], dst.page[level]
+	parent, si := c.page[level-1], c.ki[level-1]
+	offset := dp.nodeCount()
+	for i := 0; i < src.nodeCount(); i++ {
+		if src.flags&p_branch != 0 && i == 0 {
+			// 分支页面的第一个节点的键不一定有效，使用父页面中的分隔键。
+			key := bytes.Clone(parent.node(si).key())
+			if _, err := c.addNode(dp, offset, key, nil, src.node(0).pgno(), 0); err != nil {
+				return err
+			}
+			continue
+		}
+		if src.nodeSize(i) > dp.remainingSize() {
+			return PageFullError
+		}
+		dp.copyNode(offset+i, src, i)
+	}
+	c.fixMerge(level, src, dp, offset, si-1)
+	parent.removeNode(si)
+	c.fixDelete(level-1, parent, si)
+	c.freePage(src)
+
+	// 父页面少了一个节点，从 dst 的父页面开始继续重新平衡。
+	dst.ki[level-1] = si - 1
+	return dst.parentCursor().rebalance()
 }
 
+// copyTo 将游标的位置复制到 dst，dst 与游标共享页面，但拥有独立的页栈。
 func (c *cursor) copyTo(dst *cursor) {
+	dst.transaction, dst.bucket, dst.bucketID, dst.bucketFlag = c.transaction, c.bucket, c.bucketID, c.bucketFlag
+	dst.flags, dst.snum, dst.top = c.flags, c.snum, c.top
+	dst.page = append(dst.page[:0], c.page...)
+	dst.ki = append(dst.ki[:0], c.ki...)
+}
+
+// freePage 释放存储桶中的页面 p，并减少存储桶记录中对应类型的页面计数。
+func (c *cursor) freePage(p *page) {
+	c.transaction.freePages = append(c.transaction.freePages, int(p.id))
+	if p.flags&p_branch != 0 {
+		c.bucket.bucket.branches--
+	} else {
+		c.bucket.bucket.leafs--
+	}
+}
 
+// freeOverflow 释放从 id 开始的 count 个溢出页面。
+func (c *cursor) freeOverflow(id pgno, count int) {
+	for i := 0; i < count; i++ {
+		c.transaction.freePages = append(c.transaction.freePages, int(id)+i)
+	}
+	c.bucket.bucket.overflows -= pgno(count)
 }
 
+// rebalance 在页栈顶部的页面过空时重新平衡 B+ 树，调用方需要先使页栈中的页面成为脏页。
+// 根页面为空时清空整棵树，根页面是只有一个子页面的分支页面时降低树的高度；
+// 其他页面从相邻的页面借一个节点，相邻的页面也不够满时与其合并。
 func (c *cursor) rebalance() error {
-	return nil
+	t, rec := c.transaction, c.bucket.bucket
+	p := c.page[c.top]
+	minKeys := 1
+	if p.flags&p_branch != 0 {
+		minKeys = minPageKeys
+	}
+	room := t.db.pageSize - pageHeaderSize
+	if 1000*(room-p.remainingSize())/room >= fillThreshold && p.nodeCount() >= minKeys {
+		return nil
+	}
+
+	if c.top == 0 {
+		switch {
+		case p.flags&p_leaf != 0 && p.nodeCount() == 0:
+			c.freePage(p)
+			rec.root, rec.depth = p_invalid, 0
+			for m := t.tracked(c.bucketID); m != nil; m = m.next {
+				if m.snum > 0 && m.page[0] == p {
+					m.snum, m.top = 0, 0
+					m.flags &^= c_initialized | c_eof
+				}
+			}
+			c.snum, c.top = 0, 0
+		case p.flags&p_branch != 0 && p.nodeCount() == 1:
+			c.freePage(p)
+			rec.root = p.node(0).pgno()
+			rec.depth--
+			for m := t.tracked(c.bucketID); m != nil; m = m.next {
+				if m != c && m.snum > 1 && m.page[0] == p {
+					m.shrinkRoot()
+				}
+			}
+			if c.snum > 1 {
+				c.shrinkRoot()
+			}
+		}
+		return nil
+	}
+
+	parent, pi := c.page[c.top-1], c.ki[c.top-1]
+	if parent.nodeCount() < 2 {
+		// 父页面之后会被重新平衡。
+		return nil
+	}
+	mn := &cursor{}
+	c.copyTo(mn)
+	mn.snum, mn.top = c.top+1, c.top
+	if pi == 0 {
+		mn.ki[c.top-1] = 1
+	} else {
+		mn.ki[c.top-1] = pi - 1
+	}
+	np, _, err := t.getPage(int(parent.node(mn.ki[c.top-1]).pgno()))
+	if err != nil {
+		return err
+	}
+	mn.page[c.top] = np
+	if err := mn.touch(c.top); err != nil {
+		return err
+	}
+	np = mn.page[c.top]
+
+	if 1000*(room-np.remainingSize())/room >= fillThreshold && np.nodeCount() > minKeys {
+		// 相邻的页面足够满，从它的靠近当前页面的一端借一个节点。
+		if pi == 0 {
+			mn.ki[c.top], c.ki[c.top] = 0, p.nodeCount()
+		} else {
+			mn.ki[c.top], c.ki[c.top] = np.nodeCount()-1, 0
+		}
+		return mn.moveNodeTo(c)
+	}
+	if pi == 0 {
+		// 当前页面是父页面中的第一个页面，把右侧的页面合并进来。
+		return mn.mergePage(c)
+	}
+	return c.mergePage(mn)
 }
 
+// shrinkRoot 从页栈的底部移除已被释放的根页面。
+func (c *cursor) shrinkRoot() {
+	copy(c.page, c.page[1:])
+	copy(c.ki, c.ki[1:])
+	c.snum--
+	c.top--
+}
+
+// del0 删除游标当前指向的节点，并在页面过空时重新平衡 B+ 树。
+// leaf 的所有重复值都会被删除。删除之后游标指向原节点的下一个节点，并被标记为 c_del。
 func (c *cursor) del0(leaf *node) error {
+	entries := uint64(1)
+	if leaf.flags&dupNode != 0 {
+		n, err := c.count()
+		if err != nil {
+			return err
+		}
+		entries = uint64(n)
+	}
+	key := bytes.Clone(leaf.key())
+	if err := c.touchAll(); err != nil {
+		return err
+	}
+	p, index := c.page[c.top], c.ki[c.top]
+	if err := c.deleteNode(); err != nil {
+		return err
+	}
+	c.bucket.bucket.entries -= entries
+	c.fixDelete(c.top, p, index)
+	if err := c.rebalance(); err != nil {
+		return err
+	}
+
+	// 页面合并或移动节点之后重新定位到原节点的下一个节点。
+	if _, err := c.set(key, 0); err == NotFoundError {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	c.flags |= c_del
 	return nil
 }
-func (c *cursor) splitPage(newKey []byte, newData []byte, newpgno int, nflags int) error {
+
+// splitPage 分裂页栈顶部的页面，并在分裂后的页面中插入节点，返回叶子节点的数据区。
+// 页面中的节点连同新节点一起分配到原页面和新的右侧页面，左侧页面按 c.bucket.fillPercent() 填充；
+// 设置 Append 或 AppendDup 且新节点位于页面末尾时，没有指定 FillPercent 的存储桶的原页面保持已满，
+// 右侧页面只包含新节点。
+// 右侧页面的第一个键作为分隔键插入父页面，父页面已满时继续分裂父页面，根页面分裂时树的深度加一。
+// 调用方需要先对新节点调用 fixInsert。分裂之后游标的页栈顶部指向新节点。
+func (c *cursor) splitPage(key []byte, data []byte, child pgno, flags int) ([]byte, error) {
+	t, db := c.transaction, c.transaction.db
+	if c.top == 0 {
+		if err := c.growRoot(); err != nil {
+			return nil, err
+		}
+	}
+	p, index := c.page[c.top], c.ki[c.top]
+	old := (*page)(unsafe.Pointer(&bytes.Clone(p.bytes(db.pageSize))[0]))
+	sizes := make([]int, old.nodeCount()+1)
+	for j := range sizes {
+		switch {
+		case j < index:
+			sizes[j] = old.nodeSize(j)
+		case j > index:
+			sizes[j] = old.nodeSize(j - 1)
+		case p.flags&p_branch != 0:
+			sizes[j] = db.BranchSize(key)
+		default:
+			sizes[j] = db.LeafSize(key, data)
+		}
+	}
+	split := splitIndex(sizes, db.pageSize-pageHeaderSize, c.bucket.fillPercent())
+	appending := flags&(Append|AppendDup) != 0 && index == old.nodeCount()
+	if appending && c.bucket.FillPercent == 0 {
+		split = len(sizes) - 1
+	}
+
+	kind := p.flags & (p_branch | p_leaf)
+	rp, err := c.newPage(kind, 1)
+	if err != nil {
+		return nil, err
+	}
+	p.init(kind, db.pageSize)
+	var v []byte
+	var target *page
+	var ti int
+	for j := range sizes {
+		dst, di := p, j
+		if j >= split {
+			dst, di = rp, j-split
+		}
+		switch {
+		case j < index:
+			dst.copyNode(di, old, j)
+		case j > index:
+			dst.copyNode(di, old, j-1)
+		default:
+			if v, err = c.addNode(dst, di, key, data, child, flags); err != nil {
+				return nil, err
+			}
+			target, ti = dst, di
+		}
+	}
+
+	// 在父页面中插入指向右侧页面的节点。
+	sep := rp.node(0).key()
+	c.snum--
+	c.top--
+	c.ki[c.top]++
+	parent, pindex := c.page[c.top], c.ki[c.top]
+	c.fixInsert(c.top, parent, pindex)
+	if _, err = c.addNode(parent, pindex, sep, nil, rp.id, 0); err == PageFullError {
+		pflags := 0
+		if appending {
+			pflags = Append
+		}
+		_, err = c.splitPage(sep, nil, rp.id, pflags)
+	}
+	// 父页面分裂时根页面可能已经增加了一层，页栈的深度以 c.snum 为准。
+	c.snum++
+	c.top = c.snum - 1
+	if err != nil {
+		return nil, err
+	}
+
+	c.fixSplit(c.top, p, rp, split)
+	for m := t.tracked(c.bucketID); m != nil; m = m.next {
+		if m != c && m.snum > c.top && m.page[c.top] == rp {
+			// 父页面分裂后右侧页面可能位于另一个父页面中。
+			m.page[c.top-1], m.ki[c.top-1] = c.page[c.top-1], c.ki[c.top-1]
+		}
+	}
+	c.page[c.top], c.ki[c.top] = target, ti
+	if kind == p_leaf {
+		// 父页面分裂后页栈中更高层的位置不一定指向新节点所在的页面，重新查找新节点。
+		if _, err := c.set(key, 0); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// growRoot 创建一个只指向当前根页面的分支页面作为新的根页面，树的深度加一。
+// 同一存储桶中从当前根页面开始的页栈都在底部加入新的根页面。
+func (c *cursor) growRoot() error {
+	p := c.page[0]
+	root, err := c.newPage(p_branch, 1)
+	if err != nil {
+		return err
+	}
+	root.insertNode(0, nil, 0).setPgno(p.id)
+	c.bucket.bucket.root = root.id
+	c.bucket.bucket.depth++
+	for m := c.transaction.tracked(c.bucketID); m != nil; m = m.next {
+		if m != c && m.snum > 0 && m.page[0] == p {
+			m.page, m.ki = append([]*page{root}, m.page...), append([]int{0}, m.ki...)
+			m.snum++
+			m.top++
+		}
+	}
+	c.page, c.ki = append([]*page{root}, c.page...), append([]int{0}, c.ki...)
+	c.snum++
+	c.top++
 	return nil
 }
-func (c *cursor) drop0(subs int) error {
+
+// splitIndex 返回分裂时留在左侧页面的节点数量，sizes 为各节点占用的字节数，room 为页面的可用空间。
+// 左侧页面的填充不超过 room*fill，两侧都至少保留一个节点，右侧页面放不下时将更多的节点留在左侧。
+func splitIndex(sizes []int, room int, fill float64) int {
+	limit := int(float64(room) * fill)
+	split, used := 0, 0
+	for split < len(sizes)-1 && (split == 0 || used+sizes[split] <= limit) {
+		used += sizes[split]
+		split++
+	}
+	right := 0
+	for _, size := range sizes[split:] {
+		right += size
+	}
+	for right > room && split < len(sizes)-1 {
+		right -= sizes[split]
+		split++
+	}
+	return split
+}
+
+// drop0 释放存储桶中的所有页面，包括溢出页面、重复值子树以及子存储桶的页面。
+// 存储桶记录由调用方重置。
+func (c *cursor) drop0() error {
+	if c.bucket.inline != nil {
+		return c.freeNodes((*page)(unsafe.Pointer(&c.bucket.inline[0])))
+	}
+	return c.freeTree(c.bucket.bucket.root)
+}
+
+// freeTree 释放以页面 id 为根的树中的所有页面，以及叶子节点引用的页面。
+func (c *cursor) freeTree(id pgno) error {
+	if id == 0 || id == p_invalid {
+		return nil
+	}
+	t := c.transaction
+	p, _, err := t.getPage(int(id))
+	if err != nil {
+		return err
+	}
+	if p.flags&p_branch != 0 {
+		for i := 0; i < p.nodeCount(); i++ {
+			if err := c.freeTree(p.node(i).pgno()); err != nil {
+				return err
+			}
+		}
+	} else if err := c.freeNodes(p); err != nil {
+		return err
+	}
+	t.freePages = append(t.freePages, int(id))
+	return nil
+}
+
+// freeNodes 释放叶子页面 p 中的节点引用的溢出页面、重复值子树以及子存储桶的页面。
+func (c *cursor) freeNodes(p *page) error {
+	t := c.transaction
+	for i := 0; i < p.nodeCount(); i++ {
+		n := p.node(i)
+		switch {
+		case n.flags&bigNode != 0:
+			op, _, err := t.getPage(int(n.overflowPgno()))
+			if err != nil {
+				return err
+			}
+			for j := 0; j < op.overflow; j++ {
+				t.freePages = append(t.freePages, int(n.overflowPgno())+j)
+			}
+		case n.flags&bucketNode != 0:
+			v := n.value()
+			if (*bucket)(unsafe.Pointer(&v[0])).root != 0 {
+				if err := c.freeTree((*bucket)(unsafe.Pointer(&v[0])).root); err != nil {
+					return err
+				}
+			} else if err := c.freeNodes((*page)(unsafe.Pointer(&v[bucketHeaderSize]))); err != nil {
+				// 内联的子存储桶中仍可能包含拥有独立页面的子存储桶。
+				return err
+			}
+		case n.flags&dupNode != 0 && n.flags&subNode == 0:
+			if err := c.freeTree((*bucket)(unsafe.Pointer(&n.value()[0])).root); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
@@ -0,0 +1,340 @@
+package boltdb_go
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 确保插入节点后，同一页面上位于插入点之后的游标向后移动。
+func TestCursor_FixInsert(t *testing.T) {
+	txn, b := newTestTransaction()
+	p, other := &page{}, &page{}
+	c := openTestCursor(txn, b, p, 2)
+	before := openTestCursor(txn, b, p, 1)
+	after := openTestCursor(txn, b, p, 2)
+	elsewhere := openTestCursor(txn, b, other, 5)
+
+	c.fixInsert(0, p, 2)
+	assert.Equal(t, 2, c.ki[0])
+	assert.Equal(t, 1, before.ki[0])
+	assert.Equal(t, 3, after.ki[0])
+	assert.Equal(t, 5, elsewhere.ki[0])
+}
+
+// 确保删除节点后，指向被删除节点的游标被标记，之后的游标向前移动。
+func TestCursor_FixDelete(t *testing.T) {
+	txn, b := newTestTransaction()
+	p := &page{}
+	c, same, after := openTestCursor(txn, b, p, 1), openTestCursor(txn, b, p, 1), openTestCursor(txn, b, p, 3)
+
+	c.fixDelete(0, p, 1)
+	assert.Equal(t, 0, c.flags&c_del)
+	assert.Equal(t, 1, same.ki[0])
+	assert.NotEqual(t, 0, same.flags&c_del)
+	assert.Equal(t, 2, after.ki[0])
+}
+
+// 确保页面分裂后，指向后半部分的游标移动到新页面，父页面中的索引同时加一。
+func TestCursor_FixSplit(t *testing.T) {
+	txn, b := newTestTransaction()
+	parent, p, rp := &page{}, &page{}, &page{}
+	c, left, right := openTestCursor(txn, b, p, 0), openTestCursor(txn, b, p, 3), openTestCursor(txn, b, p, 6)
+	for _, m := range []*cursor{c, left, right} {
+		m.page = []*page{parent, m.page[0]}
+		m.ki = []int{4, m.ki[0]}
+		m.snum, m.top = 2, 1
+	}
+
+	c.fixSplit(1, p, rp, 5)
+	assert.Equal(t, []*page{parent, p}, left.page)
+	assert.Equal(t, []int{4, 3}, left.ki)
+	assert.Equal(t, []*page{parent, rp}, right.page)
+	assert.Equal(t, []int{5, 1}, right.ki)
+}
+
+// 确保页面合并后，指向原页面的游标移动到目标页面。
+func TestCursor_FixMerge(t *testing.T) {
+	txn, b := newTestTransaction()
+	parent, src, dst := &page{}, &page{}, &page{}
+	c, m := openTestCursor(txn, b, dst, 0), openTestCursor(txn, b, src, 2)
+	m.page, m.ki, m.snum, m.top = []*page{parent, src}, []int{3, 2}, 2, 1
+
+	c.fixMerge(1, src, dst, 4, 2)
+	assert.Equal(t, []*page{parent, dst}, m.page)
+	assert.Equal(t, []int{2, 6}, m.ki)
+}
+
+// 确保关闭的游标不再被修正。
+func TestCursor_Close(t *testing.T) {
+	txn, b := newTestTransaction()
+	p := &page{}
+	c, closed := openTestCursor(txn, b, p, 0), openTestCursor(txn, b, p, 3)
+	closed.Close()
+	closed.page, closed.ki, closed.snum = []*page{p}, []int{3}, 1
+
+	c.fixInsert(0, p, 0)
+	assert.Equal(t, 3, closed.ki[0])
+	assert.Equal(t, c, txn.tracked(b.id))
+	assert.Nil(t, c.next)
+}
+
+func newTestTransaction() (*transaction, *Bucket) {
+	txn := &transaction{db: &DB{pageSize: 4096}}
+	b := &Bucket{transaction: txn, id: mainBucket, bucket: &bucket{}}
+	return txn, b
+}
+
+// openTestCursor 打开一个写事务中的游标，并将其定位到页面 p 的第 index 个节点。
+func openTestCursor(txn *transaction, b *Bucket, p *page, index int) *cursor {
+	c, _ := txn.Cursor(b)
+	m := c.(*cursor)
+	m.page, m.ki, m.snum, m.top = []*page{p}, []int{index}, 1, 0
+	m.flags |= c_initialized
+	return m
+}
+
+// 确保真实的游标可以跨越叶子页面双向移动，Set 只匹配完全相同的键，SetRange 返回第一个不小于 key 的键。
+func TestCursor_Navigate(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		txn, _ := db.Transaction(nil, 0)
+		b, _ := txn.Bucket("", 0)
+		const n = 500
+		for i := 0; i < n; i++ {
+			assert.NoError(t, b.Put([]byte(fmt.Sprintf("%04d", 2*i)), []byte(fmt.Sprint(i)), 0))
+		}
+		assert.NoError(t, txn.Commit())
+
+		txn, _ = db.Transaction(nil, ReadOnly)
+		defer txn.Abort()
+		b, _ = txn.Bucket("", 0)
+		assert.Greater(t, b.Stats().LeafPageCount, 1)
+		c, _ := b.Cursor()
+		defer c.Close()
+
+		assert.NoError(t, c.First())
+		count := 0
+		for k, _, err := c.Current(); err == nil; k, _, err = c.NextNoDup() {
+			assert.Equal(t, fmt.Sprintf("%04d", 2*count), string(k))
+			count++
+		}
+		assert.Equal(t, n, count)
+		_, _, err := c.Next()
+		assert.Equal(t, NotFoundError, err)
+		k, _, err := c.Pre()
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("%04d", 2*(n-1)), string(k))
+
+		assert.NoError(t, c.Last())
+		count = 0
+		for _, _, err := c.Current(); err == nil; _, _, err = c.PreNoDup() {
+			count++
+		}
+		assert.Equal(t, n, count)
+
+		k, v, err := c.Set([]byte("0100"))
+		assert.NoError(t, err)
+		assert.Equal(t, "0100", string(k))
+		assert.Equal(t, "50", string(v))
+		_, _, err = c.Set([]byte("0101"))
+		assert.Equal(t, NotFoundError, err)
+
+		// 每个奇数键都位于两个相邻的键之间，其中一些位于叶子页面的末尾。
+		for i := 0; i < n-1; i++ {
+			k, _, err := c.SetRange([]byte(fmt.Sprintf("%04d", 2*i+1)))
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("%04d", 2*i+2), string(k))
+		}
+		_, _, err = c.SetRange([]byte("9999"))
+		assert.Equal(t, NotFoundError, err)
+	})
+}
+
+// 确保 Iterator 在真实的存储桶上按顺序遍历跨越多个叶子页面的键。
+func TestCursor_Iterator(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		txn, _ := db.Transaction(nil, 0)
+		defer txn.Abort()
+		b, _ := txn.Bucket("", 0)
+		var want []string
+		for i := 0; i < 300; i++ {
+			k := fmt.Sprintf("%03d", i)
+			assert.NoError(t, b.Put([]byte(k), []byte(k), 0))
+			want = append(want, k+"="+k)
+		}
+
+		it := b.Iterator()
+		assert.Equal(t, want, collect(it.All()))
+		got := collect(it.Reverse())
+		slices.Reverse(got)
+		assert.Equal(t, want, got)
+		assert.Equal(t, want[100:200], collect(it.Prefix([]byte("1"))))
+		assert.Equal(t, want[150:250], collect(it.Range([]byte("150"), []byte("250"))))
+		assert.NoError(t, it.Err())
+	})
+}
+
+// 确保 DupSort 存储桶中的重复值保存在子页面或子树中，Count 返回重复值的数量，
+// 游标可以在重复值之间以及键之间正向和反向移动，删除重复值后页面被正确释放。
+func TestCursor_Dups(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		value := func(i int) []byte { return []byte(fmt.Sprintf("value%015d", i)) }
+		txn, _ := db.Transaction(nil, 0)
+		main, _ := txn.Bucket("", 0)
+		b, err := main.CreateBucket("dups", DupSort)
+		assert.NoError(t, err)
+		for _, v := range []string{"3", "1", "2"} {
+			assert.NoError(t, b.Put([]byte("a"), []byte(v), 0))
+		}
+		assert.NoError(t, b.Put([]byte("b"), []byte("1"), 0))
+		for i := 299; i >= 0; i-- {
+			assert.NoError(t, b.Put([]byte("c"), value(i), 0))
+		}
+		assert.NoError(t, b.Put([]byte("a"), []byte("2"), 0))
+		assert.Equal(t, KeyExistError, b.Put([]byte("a"), []byte("2"), NoDupData))
+		assert.Equal(t, BadValueSizeError, b.Put([]byte("a"), make([]byte, MaxKeySize+1), 0))
+		assert.NoError(t, txn.Commit())
+
+		txn, _ = db.Transaction(nil, ReadOnly)
+		b, _ = txn.Bucket("dups", DupSort)
+		assert.Equal(t, 304, b.Stats().EntryCount)
+		c, _ := b.Cursor()
+		var want []string
+		for _, k := range []string{"a=1", "a=2", "a=3", "b=1"} {
+			want = append(want, k)
+		}
+		for i := 0; i < 300; i++ {
+			want = append(want, "c="+string(value(i)))
+		}
+		var got []string
+		assert.NoError(t, c.First())
+		for k, v, err := c.Current(); err == nil; k, v, err = c.Next() {
+			got = append(got, string(k)+"="+string(v))
+		}
+		assert.Equal(t, want, got)
+		got = got[:0]
+		assert.NoError(t, c.Last())
+		for k, v, err := c.Current(); err == nil; k, v, err = c.Pre() {
+			got = append(got, string(k)+"="+string(v))
+		}
+		slices.Reverse(got)
+		assert.Equal(t, want, got)
+
+		for key, count := range map[string]int{"a": 3, "b": 1, "c": 300} {
+			_, _, err := c.Set([]byte(key))
+			assert.NoError(t, err)
+			n, err := c.Count()
+			assert.NoError(t, err)
+			assert.Equal(t, count, n)
+		}
+		_, _, err = c.Set([]byte("c"))
+		assert.NoError(t, err)
+		assert.NoError(t, c.LastDup())
+		_, v, _ := c.Current()
+		assert.Equal(t, value(299), v)
+		_, v, err = c.PreDup()
+		assert.NoError(t, err)
+		assert.Equal(t, value(298), v)
+		assert.NoError(t, c.FirstDup())
+		_, v, err = c.NextDup()
+		assert.NoError(t, err)
+		assert.Equal(t, value(1), v)
+		_, _, err = c.Set([]byte("b"))
+		assert.NoError(t, err)
+		_, _, err = c.NextDup()
+		assert.Equal(t, NotFoundError, err)
+		k, _, err := c.NextNoDup()
+		assert.NoError(t, err)
+		assert.Equal(t, "c", string(k))
+		c.Close()
+		errs, err := txn.Check()
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+		txn.Abort()
+
+		// 通过游标逐个删除 c 的重复值，只保留每 100 个中的一个。
+		txn, _ = db.Transaction(nil, 0)
+		b, _ = txn.Bucket("dups", 0)
+		c, _ = b.Cursor()
+		_, _, err = c.Set([]byte("c"))
+		assert.NoError(t, err)
+		for i := 0; i < 300; i++ {
+			_, v, err := c.Current()
+			assert.NoError(t, err)
+			assert.Equal(t, value(i), v)
+			if i%100 != 0 {
+				assert.NoError(t, c.Del(0))
+				assert.Equal(t, NotFoundError, c.Del(0))
+			}
+			c.NextDup()
+		}
+		n, _ := c.Count()
+		assert.Equal(t, 3, n)
+		c.Close()
+		assert.NoError(t, b.Delete([]byte("a"), []byte("1")))
+		assert.NoError(t, b.Delete([]byte("a"), []byte("3")))
+		assert.Equal(t, NotFoundError, b.Delete([]byte("a"), []byte("3")))
+		v, err = b.Get([]byte("a"))
+		assert.NoError(t, err)
+		assert.Equal(t, "2", string(v))
+		assert.Equal(t, 5, b.Stats().EntryCount)
+		assert.NoError(t, txn.Commit())
+
+		txn, _ = db.Transaction(nil, 0)
+		b, _ = txn.Bucket("dups", 0)
+		assert.Equal(t, []string{"c=" + string(value(0)), "c=" + string(value(100)), "c=" + string(value(200))},
+			collect(b.Iterator().Dups([]byte("c"))))
+		assert.NoError(t, b.Delete([]byte("c"), nil))
+		assert.Equal(t, 2, b.Stats().EntryCount)
+		assert.NoError(t, txn.Commit())
+
+		txn, _ = db.Transaction(nil, ReadOnly)
+		defer txn.Abort()
+		errs, err = txn.Check()
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+	})
+}
+
+// 确保事务的 context 被取消后，游标在跨越页面时中止遍历并返回 ctx.Err()。
+func TestCursor_Context(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		txn, _ := db.Transaction(nil, 0)
+		b, _ := txn.Bucket("", 0)
+		for i := 0; i < 1000; i++ {
+			assert.NoError(t, b.Put([]byte(fmt.Sprintf("%04d", i)), []byte("value"), 0))
+		}
+		assert.NoError(t, txn.Commit())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		txn, _ = db.TransactionContext(ctx, nil, ReadOnly)
+		defer txn.Abort()
+		b, _ = txn.Bucket("", 0)
+		c, _ := b.Cursor()
+		defer c.Close()
+		assert.NoError(t, c.First())
+		n := 0
+		var err error
+		for ; err == nil; _, _, err = c.Next() {
+			if n++; n == 10 {
+				cancel()
+			}
+		}
+		assert.Equal(t, context.Canceled, err)
+		assert.Less(t, n, 1000)
+		_, _, err = c.SetRange([]byte("9999"))
+		assert.Equal(t, context.Canceled, err)
+	})
+}
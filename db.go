@@ -1,16 +1,18 @@
 package boltdb_go
 
 import (
+	"context"
 	"os"
 	"sync"
 	"syscall"
+	"time"
 	"unsafe"
 )
 
-// 定义DB相关的选项常量。
+// 定义DB相关的选项常量，各选项占用不同的位，可以按位组合。
 const (
 	// NoSync 表示禁用数据库同步操作。
-	NoSync = iota
+	NoSync = 1 << iota
 	// NoMetaSync 表示仅同步数据库数据，而不同步元数据。
 	NoMetaSync
 	// DupSort 表示开启键值对的重复排序功能。
@@ -19,10 +21,22 @@ const (
 	IntegerKey
 	// IntegerDupKey 表示重复键为整数类型。
 	IntegerDupKey
+	// DebugMemory 表示开启内存调试模式：事务返回的键和值被复制到受保护的内存中，
+	// 事务结束后这些内存变为不可访问，之后对它们的任何访问都会立即导致程序崩溃。
+	DebugMemory
 )
 
+// changeableFlags 是数据库打开后仍可以通过 SetFlags 修改的选项。
+const changeableFlags = NoSync | NoMetaSync | DebugMemory
+
 var DatabaseAlreadyOpenError = &Error{"database already open", nil}
 
+// WriterBusyError 表示写锁被其他写事务持有，且在允许的时间内没有获得写锁。
+var WriterBusyError = &Error{"writer lock is held by another transaction", nil}
+
+// flockRetryInterval 是等待其他进程释放文件写锁时的重试间隔。
+const flockRetryInterval = 10 * time.Millisecond
+
 // DB 结构体实现了DB接口，是Boltdb数据库的具体实现。
 type DB struct {
 	sync.Mutex
@@ -30,12 +44,25 @@ type DB struct {
 	file     *os.File
 	metafile *os.File
 	data     []byte
+	maps     [][]byte /**< earlier memory maps still referenced by read transactions */
 	buf      []byte
 	m0       *meta
 	m1       *meta
 	pageSize int
-	readers  []*reader
-	buckets  []*Bucket
+	readers  []*reader     /**< slots of the reader table, mapped from the lock file */
+	rmutex   sync.Mutex    /**< protects readers */
+	lockfile *os.File      /**< lock file shared by all processes using the database */
+	lockdata []byte        /**< memory map of the lock file */
+	mmutex   sync.RWMutex  /**< protects data, maps and size; readers of data take RLock */
+	writer   chan struct{} /**< write transaction lock, held by the current writer */
+	timeout  time.Duration /**< max time to wait for the writer lock, 0 waits forever */
+	maxDirty int           /**< max bytes of dirty pages a write txn keeps in memory, 0 is unlimited */
+	flags    int           /**< DB options set by SetFlags */
+	readOnly bool          /**< opened by OpenReadOnly */
+	wmutex   sync.Mutex    /**< protects watchers */
+	watchers []*Watcher    /**< subscribers of committed changes */
+	prepared *preparedTxn  /**< in-doubt prepared transaction found at Open */
+	buckets  []*bucket
 	//xbuckets       []*bucketx /**< array of static DB info */
 	bucketFlags     []int /**< array of flags from MDB_db.md_flags */
 	path            string
@@ -56,10 +83,23 @@ type DB struct {
 
 // NewDB 创建并返回一个新的Boltdb数据库实例。
 func NewDB() *DB {
-	return &DB{}
+	return &DB{writer: make(chan struct{}, 1)}
 }
 
+// Open 以读写方式打开数据库文件，文件不存在时以权限 mode 创建。
+// 只有空文件会被初始化；一个 meta 页面无效时使用另一个，两个都无效时返回 InvalidError，文件不会被修改。
 func (db *DB) Open(path string, mode os.FileMode) error {
+	return db.open(path, mode, false)
+}
+
+// OpenReadOnly 以只读方式打开已存在的数据库文件，不会修改文件；读者表所在的锁文件仍然需要可写。
+// 只读打开不会阻塞其他进程的写事务，只读事务读取开始时的快照。
+// 只读打开的数据库上开始写事务返回 ReadOnlyError。
+func (db *DB) OpenReadOnly(path string) error {
+	return db.open(path, 0666, true)
+}
+
+func (db *DB) open(path string, mode os.FileMode, readOnly bool) error {
 	var err error
 	db.Lock()
 	defer db.Unlock()
@@ -68,86 +108,126 @@ func (db *DB) Open(path string, mode os.FileMode) error {
 	if db.opened {
 		return DatabaseAlreadyOpenError
 	}
-	db.path = path
-	if db.file, err = os.OpenFile(db.path, os.O_RDWR|os.O_CREATE, mode); err != nil {
-		db.Close()
-		return err
-	}
-	if db.metafile, err = os.OpenFile(db.path, os.O_RDWR|os.O_SYNC, mode); err != nil {
-		db.Close()
-		return err
-	}
-
-	var m, m0, m1 *meta
-	var buf [pageHeaderSize + int(unsafe.Sizeof(meta{}))]byte
-	if _, err = db.file.ReadAt(buf[:], 0); err == nil {
-		if m0, _ = db.page(buf[:], 0).meta(); m0 != nil {
-			db.pageSize = int(m0.free.pad)
+	db.path, db.readOnly = path, readOnly
+	if readOnly {
+		// 只读打开不在数据文件上持有锁，否则会阻塞其他进程的写事务；
+		// 只读事务通过读者表登记其快照，写事务不会复用快照中的页面。
+		if db.file, err = os.Open(db.path); err != nil {
+			db.close()
+			return err
+		}
+	} else {
+		if db.file, err = os.OpenFile(db.path, os.O_RDWR|os.O_CREATE, mode); err != nil {
+			db.close()
+			return err
+		}
+		if db.metafile, err = os.OpenFile(db.path, os.O_RDWR|os.O_SYNC, mode); err != nil {
+			db.close()
+			return err
 		}
-	}
-	if _, err = db.file.ReadAt(buf[:], int64(db.pageSize)); err == nil {
-		m1, _ = db.page(buf[:], 0).meta()
 	}
 
-	if m0 != nil && m1 != nil {
-		if m0.txnid > m1.txnid {
-			m = m0
-		} else {
-			m = m1
-		}
+	info, err := db.file.Stat()
+	if err != nil {
+		db.close()
+		return err
 	}
-	// Initialize the page size for new environments.
-	if m == nil {
+	if info.Size() == 0 && !readOnly {
+		// Initialize the page size for new environments.
 		if err = db.init(); err != nil {
-			db.Close()
+			db.close()
+			return err
+		}
+	} else {
+		pageSize, _, errs := readMeta(db.file)
+		if errs[0] == VersionMismatchError || errs[1] == VersionMismatchError {
+			// 其他版本创建的文件不能被重新初始化。
+			db.close()
+			return VersionMismatchError
 		}
+		if pageSize == 0 {
+			db.close()
+			return InvalidError
+		}
+		db.pageSize = pageSize
 	}
 	// Initialize db fields.
 	db.buf = make([]byte, db.pageSize)
 	db.maxPageDataSize = ((db.pageSize - pageHeaderSize) / int(unsafe.Sizeof(pgno(0)))) - 1
 	db.maxNodeSize = (((db.pageSize - pageHeaderSize) / minKeyCount) & -2) - int(unsafe.Sizeof(indx(0)))
 	if err = db.mmap(); err != nil {
-		db.Close()
+		db.close()
+		return err
+	}
+	if err = db.openReaders(mode); err != nil {
+		db.close()
 		return err
 	}
 	db.buf = make([]byte, db.pageSize)
+	if err = db.loadPrepared(); err != nil {
+		db.close()
+		return err
+	}
 	db.opened = true
 	return nil
 }
 
-// mmap函数用于将数据库文件映射到内存中。
-// 它首先检查文件大小是否足够，然后尝试将文件映射到内存，并初始化相关的页面数据。
-// 参数:
-// - db *DB: 表示数据库的实例，包含文件句柄和页面大小等信息。
-// 返回值:
-// - error: 如果映射过程中遇到错误，则返回错误信息；否则返回nil。
+// mmap 将数据库文件映射到内存中，并初始化两个 meta 页面。
+// 映射的大小至少为 DefaultMapSize，映射中超出文件末尾的部分不能访问，读取页面前需要检查 db.size。
 func (db *DB) mmap() error {
-	var err error
-	var size int
-
-	// 检查文件大小是否足够。
-	if info, err := os.Stat(db.file.Name()); err != nil {
-		return err // 无法获取文件状态时返回错误。
-	} else if info.Size() < int64(db.pageSize*2) {
-		return &Error{"file size is too small", nil} // 文件大小太小，不满足要求。
-	} else {
-		size = int(info.Size()) // 文件大小满足要求，记录大小。
+	info, err := db.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < int64(db.pageSize*2) {
+		return &Error{"file size is too small", nil}
+	}
+	db.size = int(info.Size())
+	if err := db.remap(max(db.size, DefaultMapSize)); err != nil {
+		return err
 	}
 
-	// 尝试将文件映射到内存。
-	if db.data, err = syscall.Mmap(int(db.file.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED); err != nil {
-		return err // 映射文件到内存失败。
+	// 初始化meta0和meta1页面。只要有一个 meta 有效就可以使用数据库，
+	// 无效的 meta 不会被选中，并在下一次提交时被覆盖。
+	_, e0 := db.page(db.data, 0).meta()
+	_, e1 := db.page(db.data, 1).meta()
+	if e0 != nil && e1 != nil {
+		return &Error{"meta error", e0}
 	}
+	db.m0 = (*meta)(db.page(db.data, 0).data())
+	db.m1 = (*meta)(db.page(db.data, 1).data())
+	return nil
+}
 
-	// 初始化meta0和meta1页面。
-	if db.m0, err = db.page(db.data, 0).meta(); err != nil {
-		return &Error{"meta0 error", err} // 初始化meta0页面失败。
+// remap 将数据库文件重新映射为 size 字节。
+// 原有的映射可能仍被只读事务引用，因此保留到数据库关闭时才解除。
+func (db *DB) remap(size int) error {
+	data, err := syscall.Mmap(int(db.file.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return err
 	}
-	if db.m1, err = db.page(db.data, 1).meta(); err != nil {
-		return &Error{"meta1 error", err} // 初始化meta1页面失败。
+	if db.data != nil {
+		db.maps = append(db.maps, db.data)
 	}
+	db.data, db.mmapSize = data, size
+	return nil
+}
 
-	return nil // 映射和初始化成功，返回nil。
+// grow 保证内存映射包含文件的前 size 个字节，调用方需要持有 mmutex。
+// 文件可能被其他进程扩展，db.size 小于 size 时重新获取文件的大小；
+// 映射不够大时将其扩大为原来的两倍，减少重新映射的次数。
+func (db *DB) grow(size int) error {
+	if size > db.size {
+		info, err := db.file.Stat()
+		if err != nil {
+			return err
+		}
+		db.size = int(info.Size())
+	}
+	if db.size > len(db.data) {
+		return db.remap(max(db.size, 2*len(db.data)))
+	}
+	return nil
 }
 
 // init creates a new database file and initializes its meta pages.
@@ -177,8 +257,25 @@ func (db *DB) init() error {
 	return nil // 初始化成功则返回nil。
 }
 
+// close 解除内存映射并关闭数据文件和锁文件，不修改 opened 等其他状态。
 func (db *DB) close() {
-	//TODO
+	for _, data := range db.maps {
+		syscall.Munmap(data)
+	}
+	db.maps = nil
+	if db.data != nil {
+		syscall.Munmap(db.data)
+		db.data, db.m0, db.m1 = nil, nil, nil
+	}
+	if db.metafile != nil {
+		db.metafile.Close()
+		db.metafile = nil
+	}
+	db.closeReaders()
+	if db.file != nil {
+		db.file.Close()
+		db.file = nil
+	}
 }
 
 // page 根据当前页面大小，从给定字节数组中检索页面引用。
@@ -222,133 +319,175 @@ func (db *DB) freeAllDirtyPages(p *page) {
 	*/
 }
 
+// sync 将数据文件的修改同步到磁盘，设置了 NoSync 时只有 force 为 true 才同步。
 func (db *DB) sync(force bool) error {
-	/*
-			int rc = 0;
-			if (force || !F_ISSET(env->me_flags, MDB_NOSYNC)) {
-				if (env->me_flags & MDB_WRITEMAP) {
-					int flags = ((env->me_flags & MDB_MAPASYNC) && !force)
-						? MS_ASYNC : MS_SYNC;
-					if (MDB_MSYNC(env->me_map, env->me_mapsize, flags))
-						rc = ErrCode();
-		#ifdef _WIN32
-					else if (flags == MS_SYNC && MDB_FDATASYNC(env->me_fd))
-						rc = ErrCode();
-		#endif
-				} else {
-					if (MDB_FDATASYNC(env->me_fd))
-						rc = ErrCode();
-				}
-			}
-			return rc;
-	*/
+	if force || db.flags&NoSync == 0 {
+		return db.file.Sync()
+	}
 	return nil
 }
 
+// Transaction 开始一个新的事务，flags 为 ReadOnly 时开始只读事务。
+// 同一时刻只能有一个写事务，其他写事务会等待当前写事务结束。
 func (db *DB) Transaction(parent *transaction, flags int) (*transaction, error) {
-	/*
-		MDB_txn *txn;
-		MDB_ntxn *ntxn;
-		int rc, size, tsize = sizeof(MDB_txn);
+	return db.TransactionContext(context.Background(), parent, flags)
+}
 
-		if (env->me_flags & MDB_FATAL_ERROR) {
-			DPUTS("environment had fatal error, must shutdown!");
-			return MDB_PANIC;
+// TransactionContext 与 Transaction 相同，但事务与 ctx 关联。
+// 等待写锁时 ctx 被取消会放弃等待并返回 ctx.Err()；
+// 事务中的长时间遍历同样会检查 ctx，并在其被取消后中止。
+func (db *DB) TransactionContext(ctx context.Context, parent *transaction, flags int) (*transaction, error) {
+	return db.begin(ctx, parent, flags, db.timeout)
+}
+
+// TryTransaction 与 Transaction 相同，但写锁被占用时不等待，立即返回 WriterBusyError。
+func (db *DB) TryTransaction(parent *transaction, flags int) (*transaction, error) {
+	return db.begin(context.Background(), parent, flags, -1)
+}
+
+// SetLockTimeout 设置写事务等待写锁的最长时间，超时后返回 WriterBusyError。
+// d 为 0 时一直等待，这也是默认行为。
+func (db *DB) SetLockTimeout(d time.Duration) error {
+	if d < 0 {
+		return InvalidArgumentError
+	}
+	db.timeout = d
+	return nil
+}
+
+// SetMaxDirtySize 设置写事务在内存中保留的脏页的最大字节数，size 为 0 时不限制，这也是默认行为。
+// 超过时写操作开始前把不被游标引用的脏页写入文件，之后再修改这些页面时重新读入内存，
+// 因此一个写事务可以写入比内存大得多的数据。设置在之后开始的写事务中生效。
+func (db *DB) SetMaxDirtySize(size int) error {
+	if size < 0 {
+		return InvalidArgumentError
+	}
+	db.maxDirty = size
+	return nil
+}
+
+// begin 开始一个新的事务，timeout 为等待写锁的最长时间：
+// 小于 0 表示不等待，等于 0 表示一直等待。
+func (db *DB) begin(ctx context.Context, parent *transaction, flags int, timeout time.Duration) (*transaction, error) {
+	if !db.opened {
+		return nil, InvalidArgumentError
+	}
+	if parent != nil {
+		// TODO: 支持嵌套事务。
+		return nil, InvalidArgumentError
+	}
+	t := &transaction{db: db, flags: flags & ReadOnly, ctx: ctx}
+	t.watching = t.writable() && db.watching()
+	if db.flags&DebugMemory != 0 {
+		t.arena = &guardArena{}
+	}
+	if t.writable() {
+		if err := db.lockWriter(ctx, timeout); err != nil {
+			return nil, err
 		}
-		if ((env->me_flags & MDB_RDONLY) && !(flags & MDB_RDONLY))
-			return EACCES;
-		if (parent) {
-			// Nested transactions: Max 1 child, write txns only, no writemap
-			if (parent->mt_child ||
-				(flags & MDB_RDONLY) ||
-				(parent->mt_flags & (MDB_TXN_RDONLY|MDB_TXN_ERROR)) ||
-				(env->me_flags & MDB_WRITEMAP))
-			{
-				return (parent->mt_flags & MDB_TXN_RDONLY) ? EINVAL : MDB_BAD_TXN;
-			}
-			tsize = sizeof(MDB_ntxn);
+		if db.prepared != nil {
+			db.unlockWriter()
+			return nil, InDoubtError
 		}
-		size = tsize + env->me_maxdbs * (sizeof(MDB_db)+1);
-		if (!(flags & MDB_RDONLY))
-			size += env->me_maxdbs * sizeof(MDB_cursor *);
+		db.transaction = t
+	}
+	if err := t.renew(); err != nil {
+		t.Abort()
+		return nil, err
+	}
+	return t, nil
+}
 
-		if ((txn = calloc(1, size)) == NULL) {
-			DPRINTF(("calloc: %s", strerror(ErrCode())));
-			return ENOMEM;
+// lockWriter 获取写锁，ctx 被取消时放弃等待并返回 ctx.Err()。
+// 写锁分为两层：进程内的写事务通过 db.writer 互斥，
+// 不同进程的写事务通过数据文件上的 flock 互斥。
+// timeout 小于 0 时不等待，大于 0 时最多等待 timeout，获取失败均返回 WriterBusyError。
+func (db *DB) lockWriter(ctx context.Context, timeout time.Duration) error {
+	if db.readOnly {
+		return ReadOnlyError
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	if timeout < 0 {
+		select {
+		case db.writer <- struct{}{}:
+		default:
+			return WriterBusyError
 		}
-		txn->mt_dbs = (MDB_db *) ((char *)txn + tsize);
-		if (flags & MDB_RDONLY) {
-			txn->mt_flags |= MDB_TXN_RDONLY;
-			txn->mt_dbflags = (unsigned char *)(txn->mt_dbs + env->me_maxdbs);
-		} else {
-			txn->mt_cursors = (MDB_cursor **)(txn->mt_dbs + env->me_maxdbs);
-			txn->mt_dbflags = (unsigned char *)(txn->mt_cursors + env->me_maxdbs);
+	} else {
+		select {
+		case db.writer <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-expired:
+			return WriterBusyError
 		}
-		txn->mt_env = env;
-
-		if (parent) {
-			unsigned int i;
-			txn->mt_u.dirty_list = malloc(sizeof(MDB_ID2)*MDB_IDL_UM_SIZE);
-			if (!txn->mt_u.dirty_list ||
-				!(txn->mt_free_pgs = mdb_midl_alloc(MDB_IDL_UM_MAX)))
-			{
-				free(txn->mt_u.dirty_list);
-				free(txn);
-				return ENOMEM;
-			}
-			txn->mt_txnid = parent->mt_txnid;
-			txn->mt_dirty_room = parent->mt_dirty_room;
-			txn->mt_u.dirty_list[0].mid = 0;
-			txn->mt_spill_pgs = NULL;
-			txn->mt_next_pgno = parent->mt_next_pgno;
-			parent->mt_child = txn;
-			txn->mt_parent = parent;
-			txn->mt_numdbs = parent->mt_numdbs;
-			txn->mt_flags = parent->mt_flags;
-			txn->mt_dbxs = parent->mt_dbxs;
-			memcpy(txn->mt_dbs, parent->mt_dbs, txn->mt_numdbs * sizeof(MDB_db));
-			// Copy parent's mt_dbflags, but clear DB_NEW
-			for (i=0; i<txn->mt_numdbs; i++)
-				txn->mt_dbflags[i] = parent->mt_dbflags[i] & ~DB_NEW;
-			rc = 0;
-			ntxn = (MDB_ntxn *)txn;
-			ntxn->mnt_pgstate = env->me_pgstate; // save parent me_pghead & co
-			if (env->me_pghead) {
-				size = MDB_IDL_SIZEOF(env->me_pghead);
-				env->me_pghead = mdb_midl_alloc(env->me_pghead[0]);
-				if (env->me_pghead)
-					memcpy(env->me_pghead, ntxn->mnt_pgstate.mf_pghead, size);
-				else
-					rc = ENOMEM;
-			}
-			if (!rc)
-				rc = mdb_cursor_shadow(parent, txn);
-			if (rc)
-				mdb_txn_reset0(txn, "beginchild-fail");
-		} else {
-			rc = mdb_txn_renew0(txn);
+	}
+
+	for {
+		err := syscall.Flock(int(db.file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			return nil
+		}
+		if err != syscall.EWOULDBLOCK {
+			<-db.writer
+			return err
+		}
+		if timeout < 0 {
+			<-db.writer
+			return WriterBusyError
 		}
-		if (rc)
-			free(txn);
-		else {
-			*ret = txn;
-			DPRINTF(("begin txn %"Z"u%c %p on mdbenv %p, root page %"Z"u",
-				txn->mt_txnid, (txn->mt_flags & MDB_TXN_RDONLY) ? 'r' : 'w',
-				(void *) txn, (void *) env, txn->mt_dbs[MAIN_DBI].md_root));
+		select {
+		case <-ctx.Done():
+			<-db.writer
+			return ctx.Err()
+		case <-expired:
+			<-db.writer
+			return WriterBusyError
+		case <-time.After(flockRetryInterval):
 		}
+	}
+}
 
-		return rc;
-	*/
-	return nil, nil
+// unlockWriter 释放写锁。
+func (db *DB) unlockWriter() {
+	syscall.Flock(int(db.file.Fd()), syscall.LOCK_UN)
+	<-db.writer
 }
 
+// pickMeta 返回当前有效的 meta 页面的索引：两个 meta 都有效时返回事务ID较大的那一个，
+// 否则返回有效的那一个。写事务扩大映射时会替换 db.data，因此读取 meta 页面时持有 mmutex 的读锁。
 func (db *DB) pickMeta() int {
-	/*
-		return (env->me_metas[0]->mm_txnid < env->me_metas[1]->mm_txnid);
-	*/
+	db.mmutex.RLock()
+	defer db.mmutex.RUnlock()
+	if _, err := db.page(db.data, 0).meta(); err != nil {
+		return 1
+	}
+	if _, err := db.page(db.data, 1).meta(); err != nil {
+		return 0
+	}
+	if db.m0.txnid < db.m1.txnid {
+		return 1
+	}
 	return 0
 }
 
+// meta 返回当前有效的 meta。
+func (db *DB) meta() *meta {
+	if db.pickMeta() == 1 {
+		return db.m1
+	}
+	return db.m0
+}
+
 func (db *DB) Create() error {
 	/*
 			MDB_env *e;
@@ -408,16 +547,6 @@ func (db *DB) setMapSize(size int) error {
 	return nil
 }
 
-func (db *DB) setMaxBucketCount(count int) error {
-	/*
-		if (env->me_map)
-			return EINVAL;
-		env->me_maxdbs = dbs + 2; // Named databases + main and free DB
-		return MDB_SUCCESS;
-	*/
-	return nil
-}
-
 func (db *DB) setMaxReaderCount(count int) error {
 	/*
 		if (env->me_map || readers < 1)
@@ -442,168 +571,50 @@ func (db *DB) close0(excl int) {
 
 }
 
+// copyfd 将数据库的一致快照写入文件描述符 handle。
 func (db *DB) copyfd(handle int) error {
-	/*
-			MDB_txn *txn = NULL;
-			int rc;
-			size_t wsize;
-			char *ptr;
-		#ifdef _WIN32
-			DWORD len, w2;
-		#define DO_WRITE(rc, fd, ptr, w2, len)	rc = WriteFile(fd, ptr, w2, &len, NULL)
-		#else
-			ssize_t len;
-			size_t w2;
-		#define DO_WRITE(rc, fd, ptr, w2, len)	len = write(fd, ptr, w2); rc = (len >= 0)
-		#endif
-
-			// Do the lock/unlock of the reader mutex before starting the
-			// write txn.  Otherwise other read txns could block writers.
-			rc = mdb_txn_begin(env, NULL, MDB_RDONLY, &txn);
-			if (rc)
-				return rc;
-
-			if (env->me_txns) {
-				// We must start the actual read txn after blocking writers
-				mdb_txn_reset0(txn, "reset-stage1");
-
-				// Temporarily block writers until we snapshot the meta pages
-				LOCK_MUTEX_W(env);
-
-				rc = mdb_txn_renew0(txn);
-				if (rc) {
-					UNLOCK_MUTEX_W(env);
-					goto leave;
-				}
-			}
-
-			wsize = env->me_psize * 2;
-			ptr = env->me_map;
-			w2 = wsize;
-			while (w2 > 0) {
-				DO_WRITE(rc, fd, ptr, w2, len);
-				if (!rc) {
-					rc = ErrCode();
-					break;
-				} else if (len > 0) {
-					rc = MDB_SUCCESS;
-					ptr += len;
-					w2 -= len;
-					continue;
-				} else {
-					// Non-blocking or async handles are not supported
-					rc = EIO;
-					break;
-				}
-			}
-			if (env->me_txns)
-				UNLOCK_MUTEX_W(env);
-
-			if (rc)
-				goto leave;
-
-			wsize = txn->mt_next_pgno * env->me_psize - wsize;
-			while (wsize > 0) {
-				if (wsize > MAX_WRITE)
-					w2 = MAX_WRITE;
-				else
-					w2 = wsize;
-				DO_WRITE(rc, fd, ptr, w2, len);
-				if (!rc) {
-					rc = ErrCode();
-					break;
-				} else if (len > 0) {
-					rc = MDB_SUCCESS;
-					ptr += len;
-					wsize -= len;
-					continue;
-				} else {
-					rc = EIO;
-					break;
-				}
-			}
-
-		leave:
-			mdb_txn_abort(txn);
-			return rc;
-		}
-
-		int
-		mdb_env_copy(MDB_env *env, const char *path)
-		{
-			int rc, len;
-			char *lpath;
-			HANDLE newfd = INVALID_HANDLE_VALUE;
-
-			if (env->me_flags & MDB_NOSUBDIR) {
-				lpath = (char *)path;
-			} else {
-				len = strlen(path);
-				len += sizeof(DATANAME);
-				lpath = malloc(len);
-				if (!lpath)
-					return ENOMEM;
-				sprintf(lpath, "%s" DATANAME, path);
-			}
-
-			// The destination path must exist, but the destination file must not.
-			// We don't want the OS to cache the writes, since the source data is
-			// already in the OS cache.
-		#ifdef _WIN32
-			newfd = CreateFile(lpath, GENERIC_WRITE, 0, NULL, CREATE_NEW,
-						FILE_FLAG_NO_BUFFERING|FILE_FLAG_WRITE_THROUGH, NULL);
-		#else
-			newfd = open(lpath, O_WRONLY|O_CREAT|O_EXCL, 0666);
-		#endif
-			if (newfd == INVALID_HANDLE_VALUE) {
-				rc = ErrCode();
-				goto leave;
-			}
-
-		#ifdef O_DIRECT
-			// Set O_DIRECT if the file system supports it
-			if ((rc = fcntl(newfd, F_GETFL)) != -1)
-				(void) fcntl(newfd, F_SETFL, rc | O_DIRECT);
-		#endif
-		#ifdef F_NOCACHE	// __APPLE__
-			rc = fcntl(newfd, F_NOCACHE, 1);
-			if (rc) {
-				rc = ErrCode();
-				goto leave;
-			}
-		#endif
+	t, err := db.Transaction(nil, ReadOnly)
+	if err != nil {
+		return err
+	}
+	defer t.Abort()
+	_, err = t.WriteTo(fdWriter(handle))
+	return err
+}
 
-			rc = mdb_env_copyfd(env, newfd);
+// fdWriter 将文件描述符包装为 io.Writer，不接管文件描述符的关闭。
+type fdWriter int
 
-		leave:
-			if (!(env->me_flags & MDB_NOSUBDIR))
-				free(lpath);
-			if (newfd != INVALID_HANDLE_VALUE)
-				if (close(newfd) < 0 && rc == MDB_SUCCESS)
-					rc = ErrCode();
+func (fd fdWriter) Write(b []byte) (int, error) {
+	return syscall.Write(int(fd), b)
+}
 
-			return rc;
-	*/
-	return nil
+// Copy 将数据库的一致快照写入一个新文件，path 已存在时返回错误。
+func (db *DB) Copy(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	if err := db.copyfd(int(f.Fd())); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
 
+// Close 关闭数据库，释放内存映射和文件。调用方需要保证所有事务都已结束。
 func (db *DB) Close() {
-	/*
-		MDB_page *dp;
-
-		if (env == NULL)
-			return;
-
-		VGMEMP_DESTROY(env);
-		while ((dp = env->me_dpages) != NULL) {
-			VGMEMP_DEFINED(&dp->mp_next, sizeof(dp->mp_next));
-			env->me_dpages = dp->mp_next;
-			free(dp);
-		}
-
-		mdb_env_close0(env, 0);
-		free(env);
-	*/
+	db.Lock()
+	defer db.Unlock()
+	if !db.opened {
+		return
+	}
+	db.close()
+	db.opened = false
 }
 
 // Calculate the size of a leaf node.
@@ -617,18 +628,12 @@ func (db *DB) Close() {
 // @param[in] data The data for the node.
 // @return The number of bytes needed to store the node.
 func (db *DB) LeafSize(key []byte, data []byte) int {
-	/*
-		size_t		 sz;
-
-		sz = LEAFSIZE(key, data);
-		if (sz > env->me_nodemax) {
-			// put on overflow page
-			sz -= data->mv_size - sizeof(pgno_t);
-		}
-
-		return EVEN(sz + sizeof(indx_t));
-	*/
-	return 0
+	size := nodeHeaderSize + len(key) + len(data)
+	if size > db.maxNodeSize {
+		// 数据保存在溢出页面中，节点中只保存溢出页面的ID。
+		size -= len(data) - int(unsafe.Sizeof(pgno(0)))
+	}
+	return even(size + int(unsafe.Sizeof(indx(0))))
 }
 
 // Calculate the size of a branch node.
@@ -641,81 +646,62 @@ func (db *DB) LeafSize(key []byte, data []byte) int {
 // @param[in] key The key for the node.
 // @return The number of bytes needed to store the node.
 func (db *DB) BranchSize(key []byte) int {
-	/*
-		size_t		 sz;
-
-		sz = INDXSIZE(key);
-		if (sz > env->me_nodemax) {
-			// put on overflow page
-			// not implemented
-			// sz -= key->size - sizeof(pgno_t);
-		}
-
-		return sz + sizeof(indx_t);
-	*/
-	return 0
+	return even(nodeHeaderSize+len(key)) + int(unsafe.Sizeof(indx(0)))
 }
 
+// SetFlags 打开或关闭数据库选项，只能修改 changeableFlags 中的选项。
+// 选项在之后开始的事务中生效。
 func (db *DB) SetFlags(flag int, onoff bool) error {
-	/*
-		if ((flag & CHANGEABLE) != flag)
-			return EINVAL;
-		if (onoff)
-			env->me_flags |= flag;
-		else
-			env->me_flags &= ~flag;
-		return MDB_SUCCESS;
-	*/
+	if flag&changeableFlags != flag {
+		return InvalidArgumentError
+	}
+	if onoff {
+		db.flags |= flag
+	} else {
+		db.flags &^= flag
+	}
 	return nil
 }
 
+// Stat 返回当前有效 meta 中主存储桶的统计信息。
 func (db *DB) Stat() *Stat {
-	/*
-		int toggle;
-
-		if (env == NULL || arg == NULL)
-			return EINVAL;
+	return db.meta().main.stat(db.pageSize)
+}
 
-		toggle = mdb_env_pick_meta(env);
-		stat := &Stat{}
-		stat->ms_psize = env->me_psize;
-		stat->ms_depth = db->md_depth;
-		stat->ms_branch_pages = db->md_branch_pages;
-		stat->ms_leaf_pages = db->md_leaf_pages;
-		stat->ms_overflow_pages = db->md_overflow_pages;
-		stat->ms_entries = db->md_entries;
-
-		//return mdb_stat0(env, &env->me_metas[toggle]->mm_dbs[MAIN_DBI], stat);
-		return stat
-	*/
-	return nil
+// FreeStat 返回当前有效 meta 中空闲页面存储桶的统计信息。
+func (db *DB) FreeStat() *Stat {
+	return db.meta().free.stat(db.pageSize)
 }
 
+// Info 返回数据库的环境信息以及两个 meta 页面的状态。
 func (db *DB) Info() *Info {
-	/*
-		int toggle;
-
-		if (env == NULL || arg == NULL)
-			return EINVAL;
-
-		toggle = mdb_env_pick_meta(env);
-		arg->me_mapaddr = (env->me_flags & MDB_FIXEDMAP) ? env->me_map : 0;
-		arg->me_mapsize = env->me_mapsize;
-		arg->me_maxreaders = env->me_maxreaders;
-
-		// me_numreaders may be zero if this process never used any readers. Use
-		// the shared numreader count if it exists.
-		arg->me_numreaders = env->me_txns ? env->me_txns->mti_numreaders : env->me_numreaders;
-
-		arg->me_last_pgno = env->me_metas[toggle]->mm_last_pg;
-		arg->me_last_txnid = env->me_metas[toggle]->mm_txnid;
-		return MDB_SUCCESS;
-	*/
-	return nil
+	m := db.meta()
+	db.mmutex.RLock()
+	defer db.mmutex.RUnlock()
+	info := &Info{
+		MapSize:           len(db.data),
+		PageSize:          db.pageSize,
+		LastPageID:        m.pgno,
+		LastTransactionID: m.txnid,
+		MaxReaders:        DefaultReaderCount,
+	}
+	db.rmutex.Lock()
+	for _, r := range db.readers {
+		if r.pid != 0 {
+			info.ReaderCount++
+		}
+	}
+	db.rmutex.Unlock()
+	for i, m := range []*meta{db.m0, db.m1} {
+		info.Metas[i] = m.info(i)
+		_, err := db.page(db.data, i).meta()
+		info.Metas[i].Valid = err == nil
+	}
+	return info
 }
 
 // TODO: Move to bucket.go
-func (db *DB) CloseBucket(b Bucket) {
+func (db *DB) CloseBucket(b *Bucket) {
 	/*
 		char *ptr;
 		if (dbi <= MAIN_DBI || dbi >= env->me_maxdbs)
@@ -727,92 +713,3 @@ func (db *DB) CloseBucket(b Bucket) {
 		free(ptr);
 	*/
 }
-
-// int mdb_reader_list(MDB_env *env, MDB_msg_func *func, void *ctx)
-func (db *DB) getReaderList() error {
-	/*
-		unsigned int i, rdrs;
-		MDB_reader *mr;
-		char buf[64];
-		int rc = 0, first = 1;
-
-		if (!env || !func)
-			return -1;
-		if (!env->me_txns) {
-			return func("(no reader locks)\n", ctx);
-		}
-		rdrs = env->me_txns->mti_numreaders;
-		mr = env->me_txns->mti_readers;
-		for (i=0; i<rdrs; i++) {
-			if (mr[i].mr_pid) {
-				txnid_t	txnid = mr[i].mr_txnid;
-				sprintf(buf, txnid == (txnid_t)-1 ?
-					"%10d %"Z"x -\n" : "%10d %"Z"x %"Z"u\n",
-					(int)mr[i].mr_pid, (size_t)mr[i].mr_tid, txnid);
-				if (first) {
-					first = 0;
-					rc = func("    pid     thread     txnid\n", ctx);
-					if (rc < 0)
-						break;
-				}
-				rc = func(buf, ctx);
-				if (rc < 0)
-					break;
-			}
-		}
-		if (first) {
-			rc = func("(no active readers)\n", ctx);
-		}
-		return rc;
-	*/
-	return nil
-}
-
-// (bool return is whether reader is dead)
-func (db *DB) checkReaders() (bool, error) {
-	/*
-		unsigned int i, j, rdrs;
-		MDB_reader *mr;
-		MDB_PID_T *pids, pid;
-		int count = 0;
-
-		if (!env)
-			return EINVAL;
-		if (dead)
-			*dead = 0;
-		if (!env->me_txns)
-			return MDB_SUCCESS;
-		rdrs = env->me_txns->mti_numreaders;
-		pids = malloc((rdrs+1) * sizeof(MDB_PID_T));
-		if (!pids)
-			return ENOMEM;
-		pids[0] = 0;
-		mr = env->me_txns->mti_readers;
-		for (i=0; i<rdrs; i++) {
-			if (mr[i].mr_pid && mr[i].mr_pid != env->me_pid) {
-				pid = mr[i].mr_pid;
-				if (mdb_pid_insert(pids, pid) == 0) {
-					if (!mdb_reader_pid(env, Pidcheck, pid)) {
-						LOCK_MUTEX_R(env);
-						// Recheck, a new process may have reused pid
-						if (!mdb_reader_pid(env, Pidcheck, pid)) {
-							for (j=i; j<rdrs; j++)
-								if (mr[j].mr_pid == pid) {
-									DPRINTF(("clear stale reader pid %u txn %"Z"d",
-										(unsigned) pid, mr[j].mr_txnid));
-									mr[j].mr_pid = 0;
-									count++;
-								}
-						}
-						UNLOCK_MUTEX_R(env);
-					}
-				}
-			}
-		}
-		free(pids);
-		if (dead)
-			*dead = count;
-		return MDB_SUCCESS;
-	*/
-	return false, nil
-}
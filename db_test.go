@@ -1,9 +1,13 @@
 package boltdb_go
 
 import (
-	"github.com/stretchr/testify/assert"
+	"fmt"
 	"io/ioutil"
+	"os"
 	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestDB_Open(t *testing.T) {
@@ -21,3 +25,171 @@ func WithDB(fn func(*DB, string)) {
 	db := NewDB()
 	fn(db, path)
 }
+
+// 确保打开其他版本创建的文件时返回 VersionMismatchError，并且文件不会被重新初始化。
+func TestDB_OpenVersionMismatch(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		pageSize := db.pageSize
+		txn, _ := db.Transaction(nil, 0)
+		b, _ := txn.Bucket("", 0)
+		assert.NoError(t, b.Put([]byte("k"), []byte("v"), 0))
+		assert.NoError(t, txn.Commit())
+		db.Close()
+
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		for _, off := range []int{0, pageSize} {
+			m := (*meta)(unsafe.Pointer(&data[off+pageHeaderSize]))
+			m.version = Version - 1
+		}
+		assert.NoError(t, os.WriteFile(path, data, 0666))
+
+		db = NewDB()
+		assert.Equal(t, VersionMismatchError, db.Open(path, 0666))
+		after, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, data, after)
+	})
+}
+
+// 确保一个 meta 页面无效时使用另一个，两个都无效时返回 InvalidError，并且文件都不会被重新初始化。
+func TestDB_OpenInvalidMeta(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		pageSize := db.pageSize
+		txn, _ := db.Transaction(nil, 0)
+		b, _ := txn.Bucket("", 0)
+		assert.NoError(t, b.Put([]byte("k"), []byte("v"), 0))
+		assert.NoError(t, txn.Commit())
+		db.Close()
+
+		for i, off := range []int{0, pageSize} {
+			data, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			(*meta)(unsafe.Pointer(&data[off+pageHeaderSize])).magic = 0
+			assert.NoError(t, os.WriteFile(path, data, 0666))
+
+			db = NewDB()
+			if i == 0 {
+				assert.NoError(t, db.Open(path, 0666))
+				info := db.Info()
+				assert.False(t, info.Metas[0].Valid)
+				assert.True(t, info.Metas[1].Valid)
+				txn, _ := db.Transaction(nil, ReadOnly)
+				b, _ := txn.Bucket("", 0)
+				v, err := b.Get([]byte("k"))
+				assert.NoError(t, err)
+				assert.Equal(t, []byte("v"), v)
+				txn.Abort()
+				db.Close()
+			} else {
+				assert.Equal(t, InvalidError, db.Open(path, 0666))
+			}
+			after, err := os.ReadFile(path)
+			assert.NoError(t, err)
+			assert.Equal(t, data, after)
+		}
+	})
+}
+
+// 确保只读打开的数据库可以读取但不能开始写事务，并且不会修改或初始化文件。
+func TestDB_OpenReadOnly(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.Equal(t, InvalidError, db.OpenReadOnly(path))
+		assert.NoError(t, db.Open(path, 0666))
+		txn, _ := db.Transaction(nil, 0)
+		b, _ := txn.Bucket("", 0)
+		assert.NoError(t, b.Put([]byte("k"), []byte("v"), 0))
+		assert.NoError(t, txn.Commit())
+		db.Close()
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+
+		db = NewDB()
+		assert.NoError(t, db.OpenReadOnly(path))
+		_, err = db.Transaction(nil, 0)
+		assert.Equal(t, ReadOnlyError, err)
+		txn, err = db.Transaction(nil, ReadOnly)
+		assert.NoError(t, err)
+		b, _ = txn.Bucket("", 0)
+		v, err := b.Get([]byte("k"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("v"), v)
+		txn.Abort()
+		db.Close()
+
+		after, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, data, after)
+	})
+}
+
+// 确保只读打开的数据库不会阻塞其他打开方式的写事务，只读事务仍然读取其快照。
+func TestDB_OpenReadOnlyWriter(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		txn, _ := db.Transaction(nil, 0)
+		b, _ := txn.Bucket("", 0)
+		assert.NoError(t, b.Put([]byte("k"), []byte("v1"), 0))
+		assert.NoError(t, txn.Commit())
+
+		ro := NewDB()
+		assert.NoError(t, ro.OpenReadOnly(path))
+		defer ro.Close()
+		r, err := ro.Transaction(nil, ReadOnly)
+		assert.NoError(t, err)
+		defer r.Abort()
+
+		for i := 0; i < 5; i++ {
+			txn, err := db.TryTransaction(nil, 0)
+			if !assert.NoError(t, err) {
+				return
+			}
+			b, _ := txn.Bucket("", 0)
+			assert.NoError(t, b.Put([]byte("k"), []byte(fmt.Sprint("v", i+2)), 0))
+			assert.NoError(t, txn.Commit())
+		}
+		b, _ = r.Bucket("", 0)
+		v, err := b.Get([]byte("k"))
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", string(v))
+	})
+}
+
+// 确保写事务扩大内存映射时，并发的只读事务和 Info 读取到一致的映射。
+func TestDB_RemapConcurrentReaders(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		done := make(chan struct{})
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				db.Info()
+				txn, err := db.Transaction(nil, ReadOnly)
+				if !assert.NoError(t, err) {
+					return
+				}
+				txn.Abort()
+			}
+		}()
+		for i := 0; i < 40; i++ {
+			txn, err := db.Transaction(nil, 0)
+			assert.NoError(t, err)
+			b, _ := txn.Bucket("", 0)
+			assert.NoError(t, b.Put([]byte(fmt.Sprint(i)), make([]byte, 100<<10), 0))
+			assert.NoError(t, txn.Commit())
+		}
+		close(done)
+		<-stopped
+		assert.Greater(t, db.Info().MapSize, DefaultMapSize)
+	})
+}
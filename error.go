@@ -26,6 +26,9 @@ var (
 	// KeyExistError 表示键值对已存在，通常在尝试插入重复键时触发。
 	KeyExistError = &Error{"key/Value pairs has existed", nil}
 
+	// BucketExistError 表示同名的存储桶已存在，通常在创建存储桶时触发。
+	BucketExistError = &Error{"bucket already exists", nil}
+
 	// NotFoundError 表示未找到匹配的键值对，常见于查询不存在的键时。
 	NotFoundError = &Error{"no matching key/value pair found", nil}
 
@@ -53,6 +56,9 @@ var (
 	// ReadersFullError 表示环境支持的最大读取器数量已达上限。
 	ReadersFullError = &Error{"environment max-readers limit", nil}
 
+	// ReadOnlyError 表示在只读事务中执行了写操作。
+	ReadOnlyError = &Error{"transaction is read-only", nil}
+
 	// TransactionFullError 表示事务包含过多脏页，可能超出允许的大小限制。
 	TransactionFullError = &Error{"transaction has too many dirty pages - transaction too big", nil}
 
@@ -74,6 +80,33 @@ var (
 	// BadTransactionError 表示事务无法恢复，必须中止。
 	BadTransactionError = &Error{"transaction cannot recover, it must be aborted", nil}
 
+	// InvalidArgumentError 表示传入的参数或标志位组合无效。
+	InvalidArgumentError = &Error{"invalid argument", nil}
+
 	// BadValueSizeError 表示键值对过大、键为空或固定大小重复项（DUPFIXED）尺寸错误。
 	BadValueSizeError = &Error{"too big key/value, key is empty, or wrong DUPFIXED size", nil}
+
+	// DatabaseBusyError 表示数据库中仍有活动的只读事务，无法执行需要独占数据库的操作。
+	DatabaseBusyError = &Error{"database has active read transactions", nil}
+
+	// PreparedError 表示事务已经 Prepare，不能再修改，只能 Commit 或 Abort。
+	PreparedError = &Error{"transaction is prepared", nil}
+
+	// InDoubtError 表示数据库中存在结果未决的已 Prepare 事务，需要先通过 Coordinator.Recover 恢复。
+	InDoubtError = &Error{"database has an in-doubt prepared transaction", nil}
+
+	// DecisionPendingError 表示决定日志中仍有未恢复的提交决定，需要先通过 Coordinator.Recover 恢复。
+	DecisionPendingError = &Error{"coordinator log has an unrecovered decision", nil}
+
+	// ParticipantPendingError 表示决定日志中记录的参与者仍有未恢复的事务，需要把它也传给 Coordinator.Recover。
+	ParticipantPendingError = &Error{"coordinator log has unrecovered participants", nil}
+
+	// NoOlderMetaError 表示另一个 meta 页面不比当前有效的 meta 旧，无法回滚。
+	NoOlderMetaError = &Error{"no older meta page to revert to", nil}
+
+	// PageMismatchError 表示备份文件中的页面与要修复的页面ID或类型不符。
+	PageMismatchError = &Error{"backup page does not match the page id or type", nil}
+
+	// NotLeafPageError 表示页面不是用户存储桶的 B+ 树中的叶子页面。
+	NotLeafPageError = &Error{"page is not a leaf page of a user bucket", nil}
 )
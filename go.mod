@@ -1,6 +1,6 @@
 module boltdb-go
 
-go 1.21.5
+go 1.23
 
 require github.com/stretchr/testify v1.9.0
 
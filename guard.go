@@ -0,0 +1,86 @@
+package boltdb_go
+
+import (
+	"os"
+	"sync"
+	"syscall"
+)
+
+// guardChunkSize 是受保护内存每次向操作系统申请的最小字节数。
+const guardChunkSize = 64 * 1024
+
+// guardQuarantineSize 是已毒化但尚未解除映射的内存的上限。
+// 毒化的内存块先进入隔离区，超过上限时最早的内存块被解除映射，其地址之后可能被复用，
+// 因此只有在最近结束的事务中返回的数据被误用时才能保证立即触发段错误。
+const guardQuarantineSize = 64 << 20
+
+// guardQuarantine 是所有数据库共享的隔离区，按毒化的先后顺序保存内存块。
+var guardQuarantine struct {
+	sync.Mutex
+	chunks [][]byte
+	size   int
+}
+
+// guardArena 是 DebugMemory 模式下保存键和值副本的内存区域。
+// 内存通过匿名 mmap 直接向操作系统申请，事务结束时被设置为不可访问，
+// 因此在事务结束后继续使用这些键和值会立即触发段错误，而不是悄悄读到其他数据。
+type guardArena struct {
+	chunks [][]byte // 已申请的内存块
+	free   []byte   // 当前内存块中尚未使用的部分
+}
+
+// copy 将 b 复制到受保护的内存中并返回副本。
+func (a *guardArena) copy(b []byte) ([]byte, error) {
+	if b == nil {
+		return nil, nil
+	}
+	if len(b) > len(a.free) {
+		size := guardChunkSize
+		if len(b) > size {
+			pageSize := os.Getpagesize()
+			size = (len(b) + pageSize - 1) / pageSize * pageSize
+		}
+		chunk, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+		if err != nil {
+			return nil, err
+		}
+		a.chunks = append(a.chunks, chunk)
+		a.free = chunk
+	}
+	v := a.free[:len(b):len(b)]
+	copy(v, b)
+	a.free = a.free[len(b):]
+	return v, nil
+}
+
+// poison 使所有内存块不可访问，并将它们放入隔离区。
+// 物理内存通过 MADV_DONTNEED 立即归还给操作系统，地址空间在隔离区超过 guardQuarantineSize 时才被释放。
+func (a *guardArena) poison() {
+	for _, chunk := range a.chunks {
+		syscall.Madvise(chunk, syscall.MADV_DONTNEED)
+		if err := syscall.Mprotect(chunk, syscall.PROT_NONE); err != nil {
+			warnf("boltdb: unable to protect released memory: %v\n", err)
+		}
+	}
+	quarantine(a.chunks)
+	a.chunks, a.free = nil, nil
+}
+
+// quarantine 将已毒化的内存块放入隔离区，并解除映射超出 guardQuarantineSize 的最早的内存块。
+func quarantine(chunks [][]byte) {
+	q := &guardQuarantine
+	q.Lock()
+	defer q.Unlock()
+	for _, chunk := range chunks {
+		q.chunks = append(q.chunks, chunk)
+		q.size += len(chunk)
+	}
+	n := 0
+	for ; q.size > guardQuarantineSize && n < len(q.chunks); n++ {
+		if err := syscall.Munmap(q.chunks[n]); err != nil {
+			warnf("boltdb: unable to unmap released memory: %v\n", err)
+		}
+		q.size -= len(q.chunks[n])
+	}
+	q.chunks = append(q.chunks[:0], q.chunks[n:]...)
+}
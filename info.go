@@ -1,9 +1,24 @@
 package boltdb_go
 
+// Info 描述数据库的环境信息。
 type Info struct {
-	MapSize           int
-	LastPageID        int
-	LastTransactionID int
-	MaxReaders        int
-	ReaderCount       int
+	MapSize           int         // 内存映射的大小
+	PageSize          int         // 页面大小
+	LastPageID        int         // 当前有效 meta 中最后一个已使用的页面ID
+	LastTransactionID int         // 当前有效 meta 中最后提交的事务ID
+	MaxReaders        int         // 读者表的最大槽位数量
+	ReaderCount       int         // 正在使用的读者槽位数量
+	Metas             [2]MetaInfo // 两个 meta 页面的状态
+}
+
+// MetaInfo 描述一个 meta 页面。
+type MetaInfo struct {
+	PageID            int    // meta 页面的页面ID，0 或 1
+	LastPageID        int    // meta 记录的最后一个已使用的页面ID
+	LastTransactionID int    // meta 记录的事务ID
+	Magic             uint32 // meta 中的 magic
+	Version           int    // meta 中的文件格式版本
+	FreeRoot          int    // 空闲页面存储桶的根页面ID
+	MainRoot          int    // 主存储桶的根页面ID
+	Valid             bool   // meta 页面是否有效，无效的 meta 不会被使用
 }
@@ -0,0 +1,129 @@
+package boltdb_go
+
+import (
+	"bytes"
+	"context"
+	"iter"
+)
+
+// contextCheckInterval 是遍历时检查 context 是否被取消的间隔步数。
+const contextCheckInterval = 256
+
+// Iterator 在游标之上提供 range-over-func 风格的遍历。
+// 每次 range 循环都会打开一个新的游标，并在循环结束或 break 时自动关闭。
+// 遍历过程中遇到的错误不会中断调用方的代码，而是在循环结束后通过 Err 返回。
+type Iterator struct {
+	ctx     context.Context        // 被取消时中止遍历，可以为 nil
+	open    func() (Cursor, error) // 打开一个新游标
+	compare func(a, b []byte) int  // 键的比较函数，为 nil 时按字节序比较
+	err     error                  // 最近一次遍历遇到的错误
+}
+
+// Iterator 返回指定 Bucket 上的迭代器。
+func (t *transaction) Iterator(b *Bucket) *Iterator {
+	return &Iterator{ctx: t.ctx, open: func() (Cursor, error) { return t.Cursor(b) }, compare: b.compare}
+}
+
+// Err 返回最近一次遍历过程中遇到的错误，正常遍历到末尾时返回 nil。
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// All 按键的升序遍历 Bucket 中的所有键值对。
+func (it *Iterator) All() iter.Seq2[[]byte, []byte] {
+	return it.scan(first, Cursor.Next, nil)
+}
+
+// Reverse 按键的降序遍历 Bucket 中的所有键值对。
+func (it *Iterator) Reverse() iter.Seq2[[]byte, []byte] {
+	return it.scan(last, Cursor.Pre, nil)
+}
+
+// Prefix 按升序遍历所有以 prefix 开头的键值对。
+func (it *Iterator) Prefix(prefix []byte) iter.Seq2[[]byte, []byte] {
+	return it.scan(seek(prefix), Cursor.Next, func(k []byte) bool {
+		return !bytes.HasPrefix(k, prefix)
+	})
+}
+
+// Range 按升序遍历键位于 [start, end) 区间的键值对，区间的边界使用 Bucket 的键比较函数。
+// start 为 nil 时从第一个键开始，end 为 nil 时遍历到最后一个键。
+func (it *Iterator) Range(start, end []byte) iter.Seq2[[]byte, []byte] {
+	compare := it.compare
+	if compare == nil {
+		compare = bytes.Compare
+	}
+	return it.scan(seek(start), Cursor.Next, func(k []byte) bool {
+		return end != nil && compare(k, end) >= 0
+	})
+}
+
+// Dups 遍历 DupSort Bucket 中 key 对应的所有值。
+func (it *Iterator) Dups(key []byte) iter.Seq2[[]byte, []byte] {
+	return it.scan(func(c Cursor) ([]byte, []byte, error) {
+		return c.Set(key)
+	}, Cursor.NextDup, nil)
+}
+
+// scan 是所有遍历方式的公共实现。
+// 参数:
+//   - start: 将游标定位到第一个待返回的键值对。
+//   - step: 将游标移动到下一个待返回的键值对。
+//   - stop: 返回 true 时提前结束遍历，可以为 nil。
+func (it *Iterator) scan(start, step func(Cursor) ([]byte, []byte, error), stop func(k []byte) bool) iter.Seq2[[]byte, []byte] {
+	return func(yield func([]byte, []byte) bool) {
+		it.err = nil
+		c, err := it.open()
+		if err != nil {
+			it.err = err
+			return
+		}
+		defer c.Close()
+
+		k, v, err := start(c)
+		for n := 1; err == nil; k, v, err = step(c) {
+			if stop != nil && stop(k) {
+				return
+			}
+			if !yield(k, v) {
+				return
+			}
+			if it.ctx != nil && n%contextCheckInterval == 0 {
+				if err = it.ctx.Err(); err != nil {
+					break
+				}
+			}
+			n++
+		}
+		// NotFoundError 表示已经遍历到末尾，不视为错误。
+		if err != NotFoundError {
+			it.err = err
+		}
+	}
+}
+
+// first 将游标定位到第一个键值对并返回它。
+func first(c Cursor) ([]byte, []byte, error) {
+	if err := c.First(); err != nil {
+		return nil, nil, err
+	}
+	return c.Current()
+}
+
+// last 将游标定位到最后一个键值对并返回它。
+func last(c Cursor) ([]byte, []byte, error) {
+	if err := c.Last(); err != nil {
+		return nil, nil, err
+	}
+	return c.Current()
+}
+
+// seek 返回一个将游标定位到第一个大于或等于 key 的键值对的函数，key 为 nil 时等同于 first。
+func seek(key []byte) func(Cursor) ([]byte, []byte, error) {
+	if key == nil {
+		return first
+	}
+	return func(c Cursor) ([]byte, []byte, error) {
+		return c.SetRange(key)
+	}
+}
@@ -0,0 +1,202 @@
+package boltdb_go
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIterator_All(t *testing.T) {
+	it, c := newTestIterator("a=1", "b=2", "c=3")
+	assert.Equal(t, []string{"a=1", "b=2", "c=3"}, collect(it.All()))
+	assert.NoError(t, it.Err())
+	assert.True(t, c.closed)
+}
+
+func TestIterator_Reverse(t *testing.T) {
+	it, _ := newTestIterator("a=1", "b=2", "c=3")
+	assert.Equal(t, []string{"c=3", "b=2", "a=1"}, collect(it.Reverse()))
+}
+
+func TestIterator_Prefix(t *testing.T) {
+	it, _ := newTestIterator("aa=1", "ab=2", "b=3", "ba=4")
+	assert.Equal(t, []string{"b=3", "ba=4"}, collect(it.Prefix([]byte("b"))))
+	assert.Empty(t, collect(it.Prefix([]byte("c"))))
+}
+
+func TestIterator_Range(t *testing.T) {
+	it, _ := newTestIterator("a=1", "b=2", "c=3", "d=4")
+	assert.Equal(t, []string{"b=2", "c=3"}, collect(it.Range([]byte("b"), []byte("d"))))
+	assert.Equal(t, []string{"a=1", "b=2"}, collect(it.Range(nil, []byte("c"))))
+	assert.Equal(t, []string{"c=3", "d=4"}, collect(it.Range([]byte("bb"), nil)))
+
+	// 结束边界按 Bucket 的比较函数判断。
+	it, _ = newTestIterator("d=4", "c=3", "b=2", "a=1")
+	it.compare = func(a, b []byte) int { return bytes.Compare(b, a) }
+	assert.Equal(t, []string{"d=4", "c=3"}, collect(it.Range(nil, []byte("b"))))
+}
+
+func TestIterator_Dups(t *testing.T) {
+	it, _ := newTestIterator("a=1", "b=1", "b=2", "b=3", "c=1")
+	assert.Equal(t, []string{"b=1", "b=2", "b=3"}, collect(it.Dups([]byte("b"))))
+}
+
+// 确保在循环中 break 时游标被关闭。
+func TestIterator_Break(t *testing.T) {
+	it, c := newTestIterator("a=1", "b=2", "c=3")
+	for k := range it.All() {
+		if string(k) == "b" {
+			break
+		}
+	}
+	assert.True(t, c.closed)
+	assert.NoError(t, it.Err())
+}
+
+// 确保遍历中途遇到的错误通过 Err 返回。
+func TestIterator_Err(t *testing.T) {
+	it, c := newTestIterator("a=1", "b=2", "c=3")
+	c.failAt = 2
+	assert.Equal(t, []string{"a=1", "b=2"}, collect(it.All()))
+	assert.Equal(t, CorruptedError, it.Err())
+	assert.True(t, c.closed)
+
+	it = &Iterator{open: func() (Cursor, error) { return nil, BadTransactionError }}
+	assert.Empty(t, collect(it.All()))
+	assert.Equal(t, BadTransactionError, it.Err())
+}
+
+// 确保 context 被取消后遍历中止，并通过 Err 返回 ctx.Err()。
+func TestIterator_Context(t *testing.T) {
+	var pairs []string
+	for i := 0; i < 2*contextCheckInterval; i++ {
+		pairs = append(pairs, fmt.Sprintf("%04d=%d", i, i))
+	}
+	it, c := newTestIterator(pairs...)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	it.ctx = ctx
+
+	n := 0
+	for range it.All() {
+		if n++; n == 10 {
+			cancel()
+		}
+	}
+	assert.Equal(t, contextCheckInterval, n)
+	assert.Equal(t, context.Canceled, it.Err())
+	assert.True(t, c.closed)
+}
+
+func collect(seq func(func([]byte, []byte) bool)) []string {
+	var s []string
+	for k, v := range seq {
+		s = append(s, string(k)+"="+string(v))
+	}
+	return s
+}
+
+// newTestIterator 基于内存中的有序键值对创建迭代器，每个参数的格式为 "key=value"。
+func newTestIterator(pairs ...string) (*Iterator, *testCursor) {
+	c := &testCursor{pos: -1, failAt: -1}
+	for _, p := range pairs {
+		kv := bytes.SplitN([]byte(p), []byte("="), 2)
+		c.keys = append(c.keys, kv[0])
+		c.values = append(c.values, kv[1])
+	}
+	return &Iterator{open: func() (Cursor, error) {
+		c.pos, c.closed = -1, false
+		return c, nil
+	}}, c
+}
+
+// testCursor 是基于有序切片的 Cursor 实现，相同的键视为重复值。
+type testCursor struct {
+	keys   [][]byte
+	values [][]byte
+	pos    int
+	failAt int
+	closed bool
+}
+
+func (c *testCursor) move(pos int) ([]byte, []byte, error) {
+	if pos == c.failAt {
+		return nil, nil, CorruptedError
+	}
+	if pos < 0 || pos >= len(c.keys) {
+		return nil, nil, NotFoundError
+	}
+	c.pos = pos
+	return c.keys[pos], c.values[pos], nil
+}
+
+func (c *testCursor) First() error {
+	_, _, err := c.move(0)
+	return err
+}
+
+func (c *testCursor) Last() error {
+	_, _, err := c.move(len(c.keys) - 1)
+	return err
+}
+
+func (c *testCursor) FirstDup() error { return errors.New("not implemented") }
+func (c *testCursor) LastDup() error  { return errors.New("not implemented") }
+
+func (c *testCursor) Get() ([]byte, []byte, error)      { return c.Current() }
+func (c *testCursor) GetRange() ([]byte, []byte, error) { return c.Current() }
+func (c *testCursor) Current() ([]byte, []byte, error)  { return c.move(c.pos) }
+func (c *testCursor) Next() ([]byte, []byte, error)     { return c.move(c.pos + 1) }
+func (c *testCursor) Pre() ([]byte, []byte, error)      { return c.move(c.pos - 1) }
+
+func (c *testCursor) NextDup() ([]byte, []byte, error) {
+	if c.pos+1 < len(c.keys) && !bytes.Equal(c.keys[c.pos], c.keys[c.pos+1]) {
+		return nil, nil, NotFoundError
+	}
+	return c.move(c.pos + 1)
+}
+
+func (c *testCursor) NextNoDup() ([]byte, []byte, error) {
+	return nil, nil, errors.New("not implemented")
+}
+
+func (c *testCursor) PreDup() ([]byte, []byte, error) {
+	return nil, nil, errors.New("not implemented")
+}
+
+func (c *testCursor) PreNoDup() ([]byte, []byte, error) {
+	return nil, nil, errors.New("not implemented")
+}
+
+func (c *testCursor) Set(key []byte) ([]byte, []byte, error) {
+	k, v, err := c.SetRange(key)
+	if err == nil && !bytes.Equal(k, key) {
+		return nil, nil, NotFoundError
+	}
+	return k, v, err
+}
+
+func (c *testCursor) SetRange(key []byte) ([]byte, []byte, error) {
+	for i, k := range c.keys {
+		if bytes.Compare(k, key) >= 0 {
+			return c.move(i)
+		}
+	}
+	return nil, nil, NotFoundError
+}
+
+func (c *testCursor) Count() (int, error) { return 0, errors.New("not implemented") }
+
+func (c *testCursor) IsBucket() bool { return false }
+
+func (c *testCursor) Put(key []byte, data []byte, flags int) error {
+	return errors.New("not implemented")
+}
+
+func (c *testCursor) Del(flags int) error { return errors.New("not implemented") }
+
+func (c *testCursor) Close() { c.closed = true }
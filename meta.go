@@ -1,5 +1,10 @@
 package boltdb_go
 
+import (
+	"io"
+	"unsafe"
+)
+
 var (
 	InValidMetaPageError = &Error{"Invalid meta page", nil}
 )
@@ -15,9 +20,9 @@ type meta struct {
 	version int32
 
 	// free 存储空闲空间管理的相关信息。
-	free Bucket
+	free bucket
 	// main 为数据库的主要桶，用于存储用户数据。
-	main Bucket
+	main bucket
 	//页面号
 	pgno int
 	//事务ID
@@ -47,3 +52,67 @@ func (m *meta) validate() error {
 func (m *meta) read(p *page) error {
 	return nil
 }
+
+// info 返回页面 id 上的 meta 的描述。
+func (m *meta) info(id int) MetaInfo {
+	return MetaInfo{
+		PageID:            id,
+		LastPageID:        m.pgno,
+		LastTransactionID: m.txnid,
+		Magic:             m.magic,
+		Version:           int(m.version),
+		FreeRoot:          int(m.free.root),
+		MainRoot:          int(m.main.root),
+		Valid:             m.validate() == nil,
+	}
+}
+
+// minMetaPageSize 是查找 meta1 时尝试的最小页面大小。
+const minMetaPageSize = 512
+
+// readMeta 从数据库文件 f 中读取两个 meta 页面，返回页面大小、两个 meta 的副本以及它们各自的验证错误。
+// 无效的 meta 对应的副本为 nil。页面大小记录在 meta 中，meta0 无效时依次尝试各个可能的页面大小查找 meta1。
+// 两个 meta 都无效时返回的页面大小为 0。
+func readMeta(f io.ReaderAt) (int, [2]*meta, [2]error) {
+	var metas [2]*meta
+	var errs [2]error
+	read := func(off int64) (*meta, error) {
+		var buf [pageHeaderSize + int(unsafe.Sizeof(meta{}))]byte
+		if _, err := f.ReadAt(buf[:], off); err != nil {
+			if err == io.EOF {
+				err = InValidMetaPageError
+			}
+			return nil, err
+		}
+		m, err := (*page)(unsafe.Pointer(&buf[0])).meta()
+		if err != nil {
+			return nil, err
+		}
+		if m.free.pad < uint32(minMetaPageSize) || m.free.pad > maxPageSize {
+			return nil, InValidMetaPageError
+		}
+		v := *m
+		return &v, nil
+	}
+
+	metas[0], errs[0] = read(0)
+	if metas[0] != nil {
+		pageSize := int(metas[0].free.pad)
+		if metas[1], errs[1] = read(int64(pageSize)); metas[1] != nil && int(metas[1].free.pad) != pageSize {
+			metas[1], errs[1] = nil, InValidMetaPageError
+		}
+		return pageSize, metas, errs
+	}
+	errs[1] = InValidMetaPageError
+	for size := minMetaPageSize; size <= maxPageSize; size *= 2 {
+		m, err := read(int64(size))
+		if m != nil && int(m.free.pad) == size {
+			metas[1], errs[1] = m, nil
+			return size, metas, errs
+		}
+		if err == VersionMismatchError {
+			errs[1] = err
+		}
+	}
+	return 0, metas, errs
+}
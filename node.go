@@ -1,41 +1,102 @@
 package boltdb_go
 
+import "unsafe"
+
 // nodeFlags 定义节点的类型标识。
 const (
-	// bigNode 标识节点为大节点。
+	// bigNode 标识节点为大节点，数据保存在溢出页面中，节点中只保存溢出页面的ID。
 	bigNode = 0x01
 	// subNode 标识节点为子节点。
 	subNode = 0x02
 	// dupNode 标识节点为重复节点。
 	dupNode = 0x04
+	// bucketNode 标识节点的数据是一个子存储桶的记录。
+	bucketNode = 0x08
 )
 
-// node 结构体表示Boltdb数据库中的一个节点。
+// nodeFlags 是写入时由调用方指定、保存在叶子节点上的类型标识，bigNode 由写入的数据大小决定。
+const nodeFlags = subNode | dupNode | bucketNode
+
+// nodeHeaderSize 是节点头部的大小，键和数据紧跟在头部之后。
+const nodeHeaderSize = int(unsafe.Sizeof(node{}))
+
+// node 结构体是页面中一个节点的头部，节点在页面中按 2 字节对齐。
+// 叶子节点的 lo 和 hi 保存数据的大小；分支节点的 lo、hi 和 flags 保存子页面的ID。
 type node struct {
-	lo      int    // 节点的起始位置
-	hi      int    // 节点的结束位置
-	flags   int    // 节点类型标识（如bigNode、subNode、dupNode）
-	keySize int    // 节点中键的大小
-	data    []byte // 节点存储的数据
+	lo      uint16 // 数据大小或子页面ID的低 16 位
+	hi      uint16 // 数据大小或子页面ID的中间 16 位
+	flags   uint16 // 叶子节点的类型标识（如bigNode、subNode、dupNode），分支节点中为子页面ID的高 16 位
+	keySize uint16 // 节点中键的大小
 }
 
 // setFlags 设置节点的类型标识。
 func (n *node) setFlags(f int) {
-	n.flags = f
+	n.flags = uint16(f)
+}
+
+// key 返回节点的键。
+// 空键返回 nil：节点可能位于页面的末尾，此时键的起始位置已经超出页面所在的内存。
+func (n *node) key() []byte {
+	if n.keySize == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Add(unsafe.Pointer(n), nodeHeaderSize)), n.keySize)
+}
+
+// dataSize 返回叶子节点的数据大小，大节点返回溢出页面中数据的大小。
+func (n *node) dataSize() int {
+	return int(n.lo) | int(n.hi)<<16
+}
+
+// setDataSize 设置叶子节点的数据大小。
+func (n *node) setDataSize(size int) {
+	n.lo, n.hi = uint16(size), uint16(size>>16)
+}
+
+// value 返回叶子节点中保存的数据，大节点返回保存溢出页面ID的 8 个字节。
+func (n *node) value() []byte {
+	size := n.dataSize()
+	if n.flags&bigNode != 0 {
+		size = int(unsafe.Sizeof(pgno(0)))
+	}
+	if size == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Add(unsafe.Pointer(n), nodeHeaderSize+int(n.keySize))), size)
+}
+
+// pgno 返回分支节点指向的子页面ID。
+// 页面ID按低位到高位分别保存在 lo、hi 和 flags 中。
+func (n *node) pgno() pgno {
+	return pgno(n.lo) | pgno(n.hi)<<16 | pgno(n.flags)<<32
+}
+
+// setPgno 设置分支节点指向的子页面ID。
+func (n *node) setPgno(id pgno) {
+	n.lo, n.hi, n.flags = uint16(id), uint16(id>>16), uint16(id>>32)
 }
 
-// size 返回节点占用的总字节数。
-// 当前实现返回0，预留方法，可能在后续版本中实现。
-func (n *node) size() int {
-	return 0
+// overflowPgno 返回大节点的数据所在的溢出页面ID。
+func (n *node) overflowPgno() pgno {
+	return *(*pgno)(unsafe.Pointer(&n.value()[0]))
 }
 
-// shrink 函数用于在删除子页面上的一个节点后，紧凑主页面。
-// 参数：
-//
-//	index int - 要删除的节点在子页面中的索引。
-//
-// 该函数没有返回值。
-func (n *node) shrink(index int) {
+// setOverflowPgno 设置大节点的数据所在的溢出页面ID。
+func (n *node) setOverflowPgno(id pgno) {
+	*(*pgno)(unsafe.Pointer(&n.value()[0])) = id
+}
+
+// leafNodeSize 返回叶子节点在页面中占用的字节数，不包括节点在页面中的偏移量。
+func (n *node) leafNodeSize() int {
+	return even(nodeHeaderSize + int(n.keySize) + len(n.value()))
+}
+
+// branchNodeSize 返回分支节点在页面中占用的字节数，不包括节点在页面中的偏移量。
+func (n *node) branchNodeSize() int {
+	return even(nodeHeaderSize + int(n.keySize))
+}
 
+// even 将 n 向上取整为偶数，保证节点头部按 2 字节对齐。
+func even(n int) int {
+	return (n + 1) &^ 1
 }
@@ -5,9 +5,9 @@ import "unsafe"
 const maxPageSize = 0x8000
 const minKeyCount = 2
 
-var _page page
-
-const pageHeaderSize = int(unsafe.Offsetof(_page.ptr))
+// pageHeaderSize 是页面头部的大小，page 结构体只包含头部，页面的数据紧跟在头部之后。
+// 内联页面和子页面可能只有一个头部那么大，结构体不能比头部更大，否则转换时会越过缓冲区的末尾。
+const pageHeaderSize = int(unsafe.Sizeof(page{}))
 
 // MinPageKeys 是页面中键的最小数量。
 const minPageKeys = 2
@@ -44,7 +44,11 @@ type page struct {
 	lower    indx // 页面中数据的起始位置
 	upper    indx // 页面中数据的结束位置
 	overflow int  // 溢出页面的数量
-	ptr      int
+}
+
+// data 返回页面头部之后数据的起始位置。
+func (p *page) data() unsafe.Pointer {
+	return unsafe.Add(unsafe.Pointer(p), pageHeaderSize)
 }
 
 // pageState 结构体定义了一个页面的状态
@@ -68,8 +72,8 @@ func (p *page) meta() (*meta, error) {
 	if (p.flags & p_meta) == 0 { // 注意这里的条件修正，当p_meta标志未设置时执行
 		return nil, InValidMetaPageError
 	}
-	// 将page数据部分转换为meta结构体指针，假设ptr字段存放着meta数据
-	m := (*meta)(unsafe.Pointer(&p.ptr))
+	// 将page数据部分转换为meta结构体指针，meta数据紧跟在页面头部之后
+	m := (*meta)(p.data())
 	// 验证转换得到的meta数据是否有效
 	if err := m.validate(); err != nil {
 		return nil, err
@@ -88,8 +92,8 @@ func (p *page) initMeta(pageSize int) {
 	// 初始化页面标志为p_meta
 	p.flags = p_meta
 
-	// 通过unsafe.Pointer转换，将p.ptr转换为meta类型的指针，并对其进行操作
-	m := (*meta)(unsafe.Pointer(&p.ptr))
+	// 通过unsafe.Pointer转换，将页面头部之后的数据转换为meta类型的指针，并对其进行操作
+	m := (*meta)(p.data())
 
 	// 设置magic数、版本号和pageSize
 	m.magic = magic
@@ -103,12 +107,107 @@ func (p *page) initMeta(pageSize int) {
 }
 
 // nodeCount 返回页面中的节点数量。
-// 当前实现返回0，预留方法，可能在后续版本中实现。
+// 节点的偏移量数组紧跟在页面头部之后，lower 是数组的结束位置。
 func (p *page) nodeCount() int {
-	return 0
+	if int(p.lower) <= pageHeaderSize {
+		return 0
+	}
+	return (int(p.lower) - pageHeaderSize) / int(unsafe.Sizeof(indx(0)))
+}
+
+// offsets 返回页面中节点的偏移量数组，偏移量从页面的起始位置算起。
+func (p *page) offsets() []indx {
+	if p.nodeCount() == 0 {
+		return nil
+	}
+	return unsafe.Slice((*indx)(p.data()), p.nodeCount())
+}
+
+// node 返回页面中第 index 个节点，index 超出范围时返回 nil。
+func (p *page) node(index int) *node {
+	if index < 0 || index >= p.nodeCount() {
+		return nil
+	}
+	return (*node)(unsafe.Add(unsafe.Pointer(p), p.offsets()[index]))
 }
 
 // remainingSize 返回页面中剩余可用的空间大小。
 func (p *page) remainingSize() int {
-	return int(p.upper - p.lower)
+	return int(p.upper) - int(p.lower)
+}
+
+// init 将页面初始化为没有节点的 flags 类型的页面，size 为页面的大小。
+func (p *page) init(flags int, size int) {
+	p.flags = p.flags&p_dirty | flags
+	p.lower, p.upper = indx(pageHeaderSize), indx(size)
+	p.overflow = 0
+}
+
+// nodeSize 返回页面中第 index 个节点占用的字节数，包括它在偏移量数组中的位置。
+func (p *page) nodeSize(index int) int {
+	n := p.node(index)
+	if p.flags&p_branch != 0 {
+		return n.branchNodeSize() + int(unsafe.Sizeof(indx(0)))
+	}
+	return n.leafNodeSize() + int(unsafe.Sizeof(indx(0)))
+}
+
+// usedSize 返回页面中节点占用的字节数，不包括页面头部。
+func (p *page) usedSize() int {
+	size := 0
+	for i := 0; i < p.nodeCount(); i++ {
+		size += p.nodeSize(i)
+	}
+	return size
+}
+
+// insertNode 在页面的第 index 个位置插入一个键为 key、数据区为 dsize 字节的节点并返回它。
+// 调用方需要保证页面有足够的剩余空间，并设置节点的类型标识和数据。
+func (p *page) insertNode(index int, key []byte, dsize int) *node {
+	size := even(nodeHeaderSize + len(key) + dsize)
+	count := p.nodeCount()
+	p.upper -= indx(size)
+	p.lower += indx(unsafe.Sizeof(indx(0)))
+	offsets := p.offsets()
+	copy(offsets[index+1:], offsets[index:count])
+	offsets[index] = p.upper
+
+	n := (*node)(unsafe.Add(unsafe.Pointer(p), p.upper))
+	*n = node{keySize: uint16(len(key))}
+	copy(n.key(), key)
+	return n
+}
+
+// copyNode 将页面 src 中的第 i 个节点原样复制到页面的第 index 个位置。
+func (p *page) copyNode(index int, src *page, i int) {
+	n := src.node(i)
+	size := src.nodeSize(i) - int(unsafe.Sizeof(indx(0)))
+	m := p.insertNode(index, n.key(), size-nodeHeaderSize-int(n.keySize))
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(m)), size), unsafe.Slice((*byte)(unsafe.Pointer(n)), size))
+}
+
+// removeNode 删除页面中的第 index 个节点，并将位于它之前的节点数据向后移动以回收空间。
+func (p *page) removeNode(index int) {
+	offsets := p.offsets()
+	off := offsets[index]
+	size := p.nodeSize(index) - int(unsafe.Sizeof(indx(0)))
+	for i := range offsets {
+		if offsets[i] < off {
+			offsets[i] += indx(size)
+		}
+	}
+	copy(offsets[index:], offsets[index+1:])
+	if off > p.upper {
+		base := unsafe.Pointer(p)
+		src := unsafe.Slice((*byte)(unsafe.Add(base, p.upper)), int(off)-int(p.upper))
+		dst := unsafe.Slice((*byte)(unsafe.Add(base, int(p.upper)+size)), len(src))
+		copy(dst, src)
+	}
+	p.lower -= indx(unsafe.Sizeof(indx(0)))
+	p.upper += indx(size)
+}
+
+// bytes 返回页面所在的 size 字节的内存。
+func (p *page) bytes(size int) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(p)), size)
 }
@@ -0,0 +1,121 @@
+package boltdb_go
+
+import "unsafe"
+
+// PageInfo 描述数据库文件中的一个页面。
+type PageInfo struct {
+	ID       int    // 页面ID
+	Type     string // 页面类型：meta、branch、leaf、overflow、free 或 leaked
+	Flags    int    // 页面头部中的标志位
+	Overflow int    // 溢出页面占用的连续页面数量
+	Lower    int    // 页面中空闲空间的起始偏移
+	Upper    int    // 页面中空闲空间的结束偏移
+	Bucket   string // 页面所属的存储桶，与 CheckError.Bucket 的格式相同，不属于任何存储桶时为空
+
+	Data  []byte     // 页面的原始内容，只由 Page 返回，只在事务内有效
+	Nodes []NodeInfo // 分支页面和叶子页面中的节点，只由 Page 返回
+	Meta  *MetaInfo  // meta 页面的内容，只由 Page 返回
+}
+
+// NodeInfo 描述页面中的一个节点。
+type NodeInfo struct {
+	Flags  int    // 叶子节点的标志位，分支节点为 0
+	Key    []byte // 节点的键
+	Size   int    // 节点数据的大小
+	Value  []byte // 叶子节点中内联保存的数据，大节点为 nil
+	PageID int    // 分支节点指向的子页面ID，或大节点数据所在的溢出页面ID，其他节点为 -1
+}
+
+// Pages 返回事务快照中的所有页面及其类型、填充情况和所属的存储桶。
+// 溢出页面的后续页面没有页面头部，它们的类型为 overflow，并继承第一个页面的所属存储桶。
+func (t *transaction) Pages() ([]PageInfo, error) {
+	c, err := t.check()
+	if err != nil {
+		return nil, err
+	}
+	pages := make([]PageInfo, 0, t.nextPageNumber)
+	for id := 0; id < t.nextPageNumber; id++ {
+		p, _, err := t.getPage(id)
+		if err != nil {
+			return nil, err
+		}
+		info := t.pageInfo(p, id, c)
+		pages = append(pages, info)
+		if info.Type == "overflow" {
+			for i := 1; i < info.Overflow && id+1 < t.nextPageNumber; i++ {
+				id++
+				pages = append(pages, PageInfo{ID: id, Type: "overflow", Bucket: info.Bucket})
+			}
+		}
+	}
+	return pages, nil
+}
+
+// Page 返回页面 id 的详细内容，包括原始数据、解码后的节点以及 meta 字段。
+func (t *transaction) Page(id int) (*PageInfo, error) {
+	c, err := t.check()
+	if err != nil {
+		return nil, err
+	}
+	p, _, err := t.getPage(id)
+	if err != nil {
+		return nil, err
+	}
+	info := t.pageInfo(p, id, c)
+	info.Data = unsafe.Slice((*byte)(unsafe.Pointer(p)), t.db.pageSize)
+	switch {
+	case id < 2 && p.flags&p_meta != 0:
+		m := (*meta)(p.data()).info(id)
+		info.Meta = &m
+	case p.flags&(p_branch|p_leaf) != 0:
+		for i := 0; i < p.nodeCount(); i++ {
+			info.Nodes = append(info.Nodes, nodeInfo(p, p.node(i)))
+		}
+	}
+	return &info, nil
+}
+
+// pageInfo 返回页面头部的描述，c 提供页面的归属。
+func (t *transaction) pageInfo(p *page, id int, c *checker) PageInfo {
+	info := PageInfo{
+		ID:     id,
+		Flags:  p.flags,
+		Lower:  int(p.lower),
+		Upper:  int(p.upper),
+		Bucket: c.owner[pgno(id)],
+	}
+	switch {
+	case id < 2:
+		info.Type = "meta"
+	case info.Bucket == "" && c.free[pgno(id)]:
+		info.Type = "free"
+	case info.Bucket == "":
+		info.Type = "leaked"
+	case p.flags&p_branch != 0:
+		info.Type = "branch"
+	case p.flags&p_leaf != 0:
+		info.Type = "leaf"
+	case p.flags&p_overflow != 0:
+		info.Type = "overflow"
+		info.Overflow = p.overflow
+	default:
+		info.Type = "unknown"
+	}
+	return info
+}
+
+// nodeInfo 返回页面 p 中节点 n 的描述。
+func nodeInfo(p *page, n *node) NodeInfo {
+	info := NodeInfo{Key: n.key(), PageID: -1}
+	if p.flags&p_branch != 0 {
+		info.PageID = int(n.pgno())
+		return info
+	}
+	info.Flags, info.Size = int(n.flags), n.dataSize()
+	if n.flags&bigNode != 0 {
+		info.PageID = int(n.overflowPgno())
+	} else {
+		info.Value = n.value()
+	}
+	return info
+}
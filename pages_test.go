@@ -0,0 +1,43 @@
+package boltdb_go
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 确保 Pages 列出 meta 页面和未被引用的页面，Page 解码 meta 字段。
+func TestTransaction_Pages(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		txn, _ := db.Transaction(nil, 0)
+		txn.nextPageNumber++
+		assert.NoError(t, txn.Commit())
+
+		// 提交后文件没有增长，重新映射以便读取新分配的页面。
+		assert.NoError(t, db.file.Truncate(int64(3*db.pageSize)))
+		db.close()
+		db = NewDB()
+		assert.NoError(t, db.Open(path, 0666))
+
+		txn, _ = db.Transaction(nil, ReadOnly)
+		defer txn.Abort()
+		pages, err := txn.Pages()
+		assert.NoError(t, err)
+		var types []string
+		for _, p := range pages {
+			types = append(types, p.Type)
+		}
+		assert.Equal(t, []string{"meta", "meta", "leaked"}, types)
+
+		p, err := txn.Page(1)
+		assert.NoError(t, err)
+		assert.Equal(t, txn.id, p.Meta.LastTransactionID)
+		assert.Equal(t, 2, p.Meta.LastPageID)
+		assert.True(t, p.Meta.Valid)
+		assert.Len(t, p.Data, db.pageSize)
+
+		_, err = txn.Page(3)
+		assert.Equal(t, PageNotFoundError, err)
+	})
+}
@@ -1,5 +1,221 @@
 package boltdb_go
 
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// reader 是读者表中的一个槽位，记录只读事务正在使用的快照。
+// 读者表保存在数据文件旁边的锁文件中，由所有打开该数据库的进程共享，因此槽位只能包含固定大小的字段。
 type reader struct {
-	txnid int
+	pid   int   // 占用槽位的进程ID，为 0 表示槽位空闲
+	tid   int   // 获取槽位的线程ID
+	txnid int   // 正在读取的事务ID，为 -1 表示事务已重置，暂不持有快照
+	since int64 // 获取当前快照的时间，Unix 纳秒
+}
+
+// readerSize 是锁文件中一个读者槽位的大小。
+const readerSize = int(unsafe.Sizeof(reader{}))
+
+// ReaderInfo 描述读者表中一个正在使用的槽位。
+type ReaderInfo struct {
+	PID           int           // 占用槽位的进程ID
+	Thread        int           // 获取槽位的线程ID
+	TransactionID int           // 正在读取的事务ID，为 -1 表示事务已重置，暂不持有快照
+	Age           time.Duration // 持有快照的时间，未持有快照时为 0
+	Lag           int           // 当前有效 meta 的事务ID与 TransactionID 之差，未持有快照时为 0
+}
+
+// lockPath 返回数据库的锁文件路径。
+func (db *DB) lockPath() string {
+	return db.path + "-lock"
+}
+
+// openReaders 打开锁文件并映射其中的读者表，锁文件不存在时以权限 mode 创建。
+// 异常退出的进程不会释放其槽位，因此打开时清除属于已经退出的进程的槽位。
+func (db *DB) openReaders(mode os.FileMode) error {
+	f, err := os.OpenFile(db.lockPath(), os.O_RDWR|os.O_CREATE, mode)
+	if err != nil {
+		return err
+	}
+	size := DefaultReaderCount * readerSize
+	info, err := f.Stat()
+	if err == nil && info.Size() < int64(size) {
+		// 新建的锁文件中所有槽位都是空闲的。
+		err = f.Truncate(int64(size))
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	db.lockfile, db.lockdata = f, data
+	db.readers = make([]*reader, DefaultReaderCount)
+	for i := range db.readers {
+		db.readers[i] = (*reader)(unsafe.Pointer(&data[i*readerSize]))
+	}
+	// 打开期间读者表还没有被其他事务使用（reopen 的调用方持有 rmutex），只需要锁文件上的锁。
+	unlock, err := db.lockReaders()
+	if err != nil {
+		db.closeReaders()
+		return err
+	}
+	db.clearStaleReaders()
+	unlock()
+	return nil
+}
+
+// closeReaders 解除读者表的映射并关闭锁文件。
+func (db *DB) closeReaders() {
+	db.readers = nil
+	if db.lockdata != nil {
+		syscall.Munmap(db.lockdata)
+		db.lockdata = nil
+	}
+	if db.lockfile != nil {
+		db.lockfile.Close()
+		db.lockfile = nil
+	}
+}
+
+// lockReaders 获取锁文件上的排他锁，在分配或清除槽位时阻止其他进程修改读者表，调用方需要持有 rmutex。
+func (db *DB) lockReaders() (func(), error) {
+	fd := int(db.lockfile.Fd())
+	if err := syscall.Flock(fd, syscall.LOCK_EX); err != nil {
+		return nil, err
+	}
+	return func() { syscall.Flock(fd, syscall.LOCK_UN) }, nil
+}
+
+// acquireReader 为只读事务分配一个读者槽位。
+// 没有空闲的槽位时先清除属于已经退出的进程的槽位再重试一次，仍然没有时返回 ReadersFullError。
+func (db *DB) acquireReader() (*reader, error) {
+	db.rmutex.Lock()
+	defer db.rmutex.Unlock()
+	unlock, err := db.lockReaders()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	for retry := true; ; retry = false {
+		for _, r := range db.readers {
+			if r.pid == 0 {
+				r.tid, r.txnid, r.since = syscall.Gettid(), -1, 0
+				r.pid = os.Getpid()
+				return r, nil
+			}
+		}
+		if !retry || db.clearStaleReaders() == 0 {
+			return nil, ReadersFullError
+		}
+	}
+}
+
+// releaseReader 释放读者槽位，使其可以被其他只读事务复用。
+func (db *DB) releaseReader(r *reader) {
+	db.rmutex.Lock()
+	defer db.rmutex.Unlock()
+	r.txnid, r.pid = -1, 0
+}
+
+// setReader 记录读者槽位 r 正在读取事务 txnid 的快照，txnid 为 -1 表示读者不再持有快照。
+func (db *DB) setReader(r *reader, txnid int) {
+	db.rmutex.Lock()
+	defer db.rmutex.Unlock()
+	r.txnid, r.since = txnid, 0
+	if txnid != -1 {
+		r.since = time.Now().UnixNano()
+	}
+}
+
+// Readers 返回读者表中所有正在使用的槽位，包括其他进程中的读者。
+// 其他进程中的读者可能在读取期间改变状态，返回的结果只是一个近似的快照。
+// 持有的快照落后于最新事务越多，写事务就越无法复用这些快照之后释放的页面，文件会因此不断增长。
+func (db *DB) Readers() []ReaderInfo {
+	last := db.meta().txnid
+	now := time.Now()
+	db.rmutex.Lock()
+	defer db.rmutex.Unlock()
+	var readers []ReaderInfo
+	for _, r := range db.readers {
+		if r.pid == 0 {
+			continue
+		}
+		info := ReaderInfo{PID: r.pid, Thread: r.tid, TransactionID: r.txnid}
+		if r.txnid != -1 {
+			info.Age, info.Lag = now.Sub(time.Unix(0, r.since)), last-r.txnid
+		}
+		readers = append(readers, info)
+	}
+	return readers
+}
+
+// ReaderList 以 mdb_reader_list 的格式逐行输出读者表，每行调用一次 fn。
+func (db *DB) ReaderList(fn func(msg string) error) error {
+	readers := db.Readers()
+	if len(readers) == 0 {
+		return fn("(no active readers)\n")
+	}
+	if err := fn("    pid     thread     txnid\n"); err != nil {
+		return err
+	}
+	for _, r := range readers {
+		msg := fmt.Sprintf("%10d %x %d\n", r.PID, r.Thread, r.TransactionID)
+		if r.TransactionID == -1 {
+			msg = fmt.Sprintf("%10d %x -\n", r.PID, r.Thread)
+		}
+		if err := fn(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckReaders 清除属于已经退出的进程的读者槽位，返回清除的槽位数量。
+// 当前进程的槽位总是有效的，不会被清除。
+func (db *DB) CheckReaders() (int, error) {
+	db.rmutex.Lock()
+	defer db.rmutex.Unlock()
+	unlock, err := db.lockReaders()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	return db.clearStaleReaders(), nil
+}
+
+// clearStaleReaders 清除属于已经退出的进程的读者槽位，返回清除的槽位数量。
+// 调用方需要持有 rmutex 以及 lockReaders 获取的锁。
+func (db *DB) clearStaleReaders() int {
+	self := os.Getpid()
+	alive := map[int]bool{self: true}
+	count := 0
+	for _, r := range db.readers {
+		if r.pid == 0 {
+			continue
+		}
+		ok, checked := alive[r.pid]
+		if !checked {
+			ok = processAlive(r.pid)
+			alive[r.pid] = ok
+		}
+		if !ok {
+			r.txnid, r.pid = -1, 0
+			count++
+		}
+	}
+	return count
+}
+
+// processAlive 返回进程 pid 是否存在，没有权限向其发送信号的进程也视为存在。
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
 }
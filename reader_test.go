@@ -0,0 +1,119 @@
+package boltdb_go
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 确保读者表列出持有快照和已重置的读者，并按 mdb_reader_list 的格式输出。
+func TestDB_Readers(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		var out string
+		list := func(msg string) error { out += msg; return nil }
+		assert.NoError(t, db.ReaderList(list))
+		assert.Equal(t, "(no active readers)\n", out)
+
+		txn, err := db.Transaction(nil, ReadOnly)
+		assert.NoError(t, err)
+		defer txn.Abort()
+		reset, err := db.Transaction(nil, ReadOnly)
+		assert.NoError(t, err)
+		defer reset.Abort()
+		reset.Reset()
+
+		readers := db.Readers()
+		if assert.Len(t, readers, 2) {
+			assert.Equal(t, txn.id, readers[0].TransactionID)
+			assert.Equal(t, db.meta().txnid-txn.id, readers[0].Lag)
+			assert.Equal(t, -1, readers[1].TransactionID)
+			assert.Zero(t, readers[1].Age)
+		}
+		out = ""
+		assert.NoError(t, db.ReaderList(list))
+		assert.Regexp(t, `^    pid     thread     txnid\n +\d+ [0-9a-f]+ \d+\n +\d+ [0-9a-f]+ -\n$`, out)
+	})
+}
+
+// 确保 CheckReaders 只清除已经退出的进程的槽位。
+func TestDB_CheckReaders(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		txn, err := db.Transaction(nil, ReadOnly)
+		assert.NoError(t, err)
+		defer txn.Abort()
+		// 模拟已经退出的进程留下的槽位。
+		db.readers[5].pid, db.readers[5].txnid = 1<<30, 1
+		db.readers[6].pid, db.readers[6].txnid = 1<<30, 2
+		assert.Len(t, db.Readers(), 3)
+
+		n, err := db.CheckReaders()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+		assert.Len(t, db.Readers(), 1)
+	})
+}
+
+// 确保读者表保存在锁文件中，同一个数据库的其他实例可以看到读者，释放的槽位也对其他实例可见。
+func TestDB_ReadersShared(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		other := NewDB()
+		assert.NoError(t, other.OpenReadOnly(path))
+		defer other.Close()
+		assert.Empty(t, other.Readers())
+
+		txn, err := db.Transaction(nil, ReadOnly)
+		assert.NoError(t, err)
+		readers := other.Readers()
+		if assert.Len(t, readers, 1) {
+			assert.Equal(t, os.Getpid(), readers[0].PID)
+			assert.Equal(t, txn.id, readers[0].TransactionID)
+		}
+		// 另一个实例分配的槽位不会与已占用的槽位冲突。
+		r, err := other.Transaction(nil, ReadOnly)
+		assert.NoError(t, err)
+		assert.Len(t, db.Readers(), 2)
+		r.Abort()
+		txn.Abort()
+		assert.Empty(t, other.Readers())
+	})
+}
+
+// 确保打开锁文件时以及读者表已满时清除已经退出的进程留下的槽位。
+func TestDB_StaleReaders(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		for _, r := range db.readers {
+			r.pid, r.txnid = 1<<30, 1
+		}
+		db.Close()
+
+		db = NewDB()
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		assert.Empty(t, db.Readers())
+
+		for _, r := range db.readers {
+			r.pid, r.txnid = 1<<30, 1
+		}
+		txn, err := db.Transaction(nil, ReadOnly)
+		assert.NoError(t, err)
+		txn.Abort()
+		assert.Empty(t, db.Readers())
+
+		for _, r := range db.readers {
+			r.pid, r.txnid = os.Getpid(), 1
+		}
+		_, err = db.Transaction(nil, ReadOnly)
+		assert.Equal(t, ReadersFullError, err)
+		for _, r := range db.readers {
+			r.pid, r.txnid = 0, -1
+		}
+	})
+}
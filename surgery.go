@@ -0,0 +1,254 @@
+package boltdb_go
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// 本文件中的方法直接修改数据库文件，用于在损坏后恢复数据。
+// 它们都需要独占数据库：执行期间持有写锁，并且不能有活动的只读事务，否则返回 DatabaseBusyError。
+// 修改之后应当再运行一次 Check 确认结果。
+
+// RevertMeta 用较旧的 meta 页面覆盖当前有效的 meta 页面，回滚最后一次提交。
+// 写事务只会复用在较旧的 meta 之前就已释放的页面，因此较旧的 meta 引用的页面仍然完整；
+// 最后一次提交写入的页面不再被引用，可以通过 RebuildFreeList 回收。
+// 只有一个 meta 页面有效时，最后一次提交的 meta 已经损坏，用有效的 meta 覆盖它。
+// 两个 meta 的事务ID相同（已经回滚过一次）时返回 NoOlderMetaError。
+func (db *DB) RevertMeta() error {
+	return db.exclusive(func() error {
+		return revertMeta(db.file)
+	})
+}
+
+// RevertMetaFile 与 RevertMeta 相同，但直接修改数据库文件 path 而不打开数据库，用于无法正常打开的文件。
+// 有其他写事务时返回 WriterBusyError，有其他进程正在读取数据库时返回 DatabaseBusyError。
+func RevertMetaFile(path string) error {
+	return rawExclusive(path, revertMeta)
+}
+
+// revertMeta 在数据库文件 f 上执行 RevertMeta，写入后同步文件。
+func revertMeta(f *os.File) error {
+	pageSize, metas, errs := readMeta(f)
+	if errs[0] == VersionMismatchError || errs[1] == VersionMismatchError {
+		return VersionMismatchError
+	}
+	if pageSize == 0 {
+		return InvalidError
+	}
+	// 被覆盖的是最后一次提交的 meta，或者无效的那一个。
+	cur := 0
+	if metas[0] != nil && (metas[1] == nil || metas[1].txnid > metas[0].txnid) {
+		cur = 1
+	}
+	old := 1 - cur
+	if metas[cur] != nil && metas[old].txnid >= metas[cur].txnid {
+		return NoOlderMetaError
+	}
+	buf := make([]byte, pageSize)
+	if _, err := f.ReadAt(buf, int64(old*pageSize)); err != nil {
+		return err
+	}
+	(*page)(unsafe.Pointer(&buf[0])).id = pgno(cur)
+	if _, err := f.WriteAt(buf, int64(cur*pageSize)); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// CopyPage 从备份文件 src 中复制页面 id 到数据库，溢出页面连同它的后续页面一起复制。
+// 备份必须是页面大小相同的数据库文件，其中的页面必须是页面ID相同的分支、叶子或溢出页面，
+// 否则返回 PageMismatchError；meta 页面不能复制，需要使用 RevertMeta。
+func (db *DB) CopyPage(src io.ReaderAt, id int) error {
+	return db.exclusive(func() error {
+		return copyPageTo(db.file, src, id)
+	})
+}
+
+// CopyPageFile 与 CopyPage 相同，但直接修改数据库文件 path 而不打开数据库，用于无法正常打开的文件。
+// 有其他写事务时返回 WriterBusyError，有其他进程正在读取数据库时返回 DatabaseBusyError。
+func CopyPageFile(path string, src io.ReaderAt, id int) error {
+	return rawExclusive(path, func(f *os.File) error {
+		return copyPageTo(f, src, id)
+	})
+}
+
+// copyPageTo 在数据库文件 f 上执行 CopyPage，写入后同步文件。
+func copyPageTo(f *os.File, src io.ReaderAt, id int) error {
+	pageSize, metas, _ := readMeta(f)
+	m := metas[0]
+	if m == nil || metas[1] != nil && metas[1].txnid > m.txnid {
+		m = metas[1]
+	}
+	if m == nil {
+		return InvalidError
+	}
+	if srcPageSize, _, _ := readMeta(src); srcPageSize != pageSize {
+		return PageMismatchError
+	}
+	last := m.pgno
+	if id < 2 || id > last {
+		return PageNotFoundError
+	}
+
+	buf := make([]byte, pageSize)
+	if _, err := src.ReadAt(buf, int64(id)*int64(pageSize)); err != nil {
+		return err
+	}
+	p := (*page)(unsafe.Pointer(&buf[0]))
+	if int(p.id) != id || p.flags&p_meta != 0 || p.flags&(p_branch|p_leaf|p_overflow) == 0 {
+		return PageMismatchError
+	}
+	if p.flags&p_overflow != 0 {
+		if p.overflow < 1 || id+p.overflow-1 > last {
+			return PageMismatchError
+		}
+		buf = make([]byte, p.overflow*pageSize)
+		if _, err := src.ReadAt(buf, int64(id)*int64(pageSize)); err != nil {
+			return err
+		}
+	}
+	if _, err := f.WriteAt(buf, int64(id)*int64(pageSize)); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// rawExclusive 以读写方式打开已存在的数据库文件 path 并调用 fn，不读取或初始化 meta。
+// 只读打开的数据库不在数据文件上持有锁，因此除了文件上的写锁，执行期间还持有读者表的锁，
+// 确认没有存活的进程正在读取数据库，并阻止新的只读事务开始。
+func rawExclusive(path string, fn func(f *os.File) error) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		if err == syscall.EWOULDBLOCK {
+			return WriterBusyError
+		}
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	db := &DB{path: path}
+	if err := db.openReaders(info.Mode().Perm()); err != nil {
+		return err
+	}
+	defer db.closeReaders()
+	db.rmutex.Lock()
+	defer db.rmutex.Unlock()
+	unlock, err := db.lockReaders()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	for _, r := range db.readers {
+		if r.pid != 0 && processAlive(r.pid) {
+			return DatabaseBusyError
+		}
+	}
+	if err := fn(f); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// RebuildFreeList 丢弃现有的空闲列表，把 [2, 最后一个页面] 中不能从主存储桶访问的页面作为新的空闲列表，
+// 在一个写事务中提交，返回新的空闲列表中的页面ID。旧空闲列表本身占用的页面也包含在内。
+func (db *DB) RebuildFreeList() ([]int, error) {
+	t, err := db.Transaction(nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	err = db.withoutReaders(func() error {
+		c := newChecker(t)
+		c.tree(t.buckets[mainBucket], "main")
+		if err := t.Context().Err(); err != nil {
+			return err
+		}
+		for id := 2; id < t.nextPageNumber; id++ {
+			if _, ok := c.owner[pgno(id)]; !ok {
+				ids = append(ids, id)
+			}
+		}
+		// 清空空闲页面存储桶，新的空闲列表作为本事务释放的页面由 saveFreeList 写入。
+		free := t.buckets[freeBucket]
+		*free = bucket{pad: free.pad, flags: free.flags, root: p_invalid}
+		t.freePages = append(t.freePages[:0], ids...)
+		return t.Commit()
+	})
+	if err != nil {
+		t.Abort()
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ClearLeaf 把用户存储桶中损坏的叶子页面 id 清空为没有节点的叶子页面，并重新统计它所在的存储桶的计数：
+// 页面所在 B+ 树的记录，以及页面位于重复值子树中时其所在存储桶的记录。
+// 被清空的页面中的键值对、子存储桶和溢出页面全部丢失，不再被引用的页面可以通过 RebuildFreeList 回收。
+// 页面不是用户存储桶的叶子页面时返回 NotLeafPageError。
+func (db *DB) ClearLeaf(id int) error {
+	t, err := db.Transaction(nil, 0)
+	if err != nil {
+		return err
+	}
+	err = db.withoutReaders(func() error {
+		c, err := t.check()
+		if err != nil {
+			return err
+		}
+		rec, ok := c.leafs[pgno(id)]
+		if !ok || c.owner[pgno(id)] == "free" {
+			return NotLeafPageError
+		}
+
+		buf := make([]byte, db.pageSize)
+		p := db.page(buf, 0)
+		p.id, p.flags = pgno(id), p_leaf
+		p.lower, p.upper = indx(pageHeaderSize), indx(db.pageSize)
+		if _, err := db.file.WriteAt(buf, int64(id)*int64(db.pageSize)); err != nil {
+			return err
+		}
+
+		// 先修正页面所在的树，再修正包含它的存储桶，后者的条目数包含前者的条目数。
+		for ; rec != nil; rec = c.parents[rec] {
+			n := newChecker(t).count(rec, c.owner[pgno(id)])
+			fixed := *rec
+			fixed.depth, fixed.branches, fixed.leafs, fixed.overflows, fixed.entries = n.depth, n.branches, n.leafs, n.overflows, n.entries
+			if err := db.writeBucket(rec, &fixed); err != nil {
+				return err
+			}
+		}
+		// 直接写入文件的页面和记录必须在 meta 引用它们之前持久化，即使设置了 NoSync。
+		if err := db.file.Sync(); err != nil {
+			return err
+		}
+		return t.Commit()
+	})
+	if err != nil {
+		t.Abort()
+	}
+	return err
+}
+
+// writeBucket 把存储桶记录 rec 更新为 v。位于内存映射中的记录直接写入文件中的对应位置，
+// 其他记录（主存储桶和空闲页面存储桶）是事务中的副本，在提交时写入 meta。
+func (db *DB) writeBucket(rec, v *bucket) error {
+	db.mmutex.RLock()
+	defer db.mmutex.RUnlock()
+	base := uintptr(unsafe.Pointer(unsafe.SliceData(db.data)))
+	addr := uintptr(unsafe.Pointer(rec))
+	if addr < base || addr >= base+uintptr(len(db.data)) {
+		*rec = *v
+		return nil
+	}
+	b := unsafe.Slice((*byte)(unsafe.Pointer(v)), bucketHeaderSize)
+	_, err := db.file.WriteAt(b, int64(addr-base))
+	return err
+}
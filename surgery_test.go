@@ -0,0 +1,129 @@
+package boltdb_go
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestPage 在数据文件中写入一个只有页面头部的页面。
+func writeTestPage(t *testing.T, db *DB, id int, flags int) []byte {
+	buf := make([]byte, db.pageSize)
+	p := db.page(buf, 0)
+	p.id, p.flags = pgno(id), flags
+	p.lower, p.upper = indx(pageHeaderSize), indx(db.pageSize)
+	_, err := db.file.WriteAt(buf, int64(id*db.pageSize))
+	assert.NoError(t, err)
+	return buf
+}
+
+// 确保 RevertMeta 回滚最后一次提交，并且只能回滚一次。
+func TestDB_RevertMeta(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		for i := 0; i < 2; i++ {
+			txn, _ := db.Transaction(nil, 0)
+			assert.NoError(t, txn.Commit())
+		}
+		assert.Equal(t, 2, db.Info().LastTransactionID)
+
+		txn, _ := db.Transaction(nil, ReadOnly)
+		assert.Equal(t, DatabaseBusyError, db.RevertMeta())
+		txn.Abort()
+
+		assert.NoError(t, db.RevertMeta())
+		info := db.Info()
+		assert.Equal(t, 1, info.LastTransactionID)
+		assert.Equal(t, info.Metas[0].LastTransactionID, info.Metas[1].LastTransactionID)
+		assert.Equal(t, NoOlderMetaError, db.RevertMeta())
+
+		// 回滚之后仍然可以正常提交。
+		txn, _ = db.Transaction(nil, 0)
+		assert.NoError(t, txn.Commit())
+		assert.Equal(t, 2, db.Info().LastTransactionID)
+	})
+}
+
+// 确保 CopyPage 从备份中恢复页面，并拒绝 meta 页面和超出范围的页面。
+func TestDB_CopyPage(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		txn, _ := db.Transaction(nil, 0)
+		txn.nextPageNumber++
+		assert.NoError(t, txn.Commit())
+		page := writeTestPage(t, db, 2, p_leaf)
+
+		backup := path + ".backup"
+		b, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(backup, b, 0666))
+		defer os.Remove(backup)
+		src, err := os.Open(backup)
+		assert.NoError(t, err)
+		defer src.Close()
+
+		_, err = db.file.WriteAt(make([]byte, db.pageSize), int64(2*db.pageSize))
+		assert.NoError(t, err)
+		assert.NoError(t, db.CopyPage(src, 2))
+		b, err = os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, page, b[2*db.pageSize:3*db.pageSize])
+
+		assert.Equal(t, PageNotFoundError, db.CopyPage(src, 1))
+		assert.Equal(t, PageNotFoundError, db.CopyPage(src, 3))
+	})
+}
+
+// 确保 RebuildFreeList 把不可访问的页面作为新的空闲列表。
+func TestDB_RebuildFreeList(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		txn, _ := db.Transaction(nil, 0)
+		txn.nextPageNumber += 2
+		assert.NoError(t, txn.Commit())
+
+		ids, err := db.RebuildFreeList()
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 3}, ids)
+		assert.Equal(t, 2, db.Info().LastTransactionID)
+		// 新的空闲列表作为一条记录保存在空闲页面存储桶中，所有页面都可以找到归属。
+		assert.Equal(t, uint64(1), db.meta().free.entries)
+		errs, err := db.Check()
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+	})
+}
+
+// 确保 ClearLeaf 清空叶子页面并修正存储桶记录中的计数，拒绝不是叶子页面的页面。
+func TestDB_ClearLeaf(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		txn, _ := db.Transaction(nil, 0)
+		main := txn.buckets[mainBucket]
+		main.root, main.depth, main.leafs, main.entries = 2, 1, 1, 5
+		txn.nextPageNumber++
+		assert.NoError(t, txn.Commit())
+		writeTestPage(t, db, 2, p_leaf|p_dirty)
+		db.Close()
+
+		// 重新打开数据库，使内存映射包含新写入的页面。
+		db = NewDB()
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		errs, err := db.Check()
+		assert.NoError(t, err)
+		assert.Len(t, errs, 1)
+
+		assert.Equal(t, NotLeafPageError, db.ClearLeaf(0))
+		assert.NoError(t, db.ClearLeaf(2))
+		errs, err = db.Check()
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+		assert.Equal(t, uint64(0), db.meta().main.entries)
+		assert.Equal(t, p_leaf, db.page(db.data, 2).flags)
+	})
+}
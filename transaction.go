@@ -1,5 +1,43 @@
 package boltdb_go
 
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"sort"
+	"unsafe"
+)
+
+// 事务的标志位。
+const (
+	// ReadOnly 表示只读事务，只读事务中的写操作会返回 ReadOnlyError。
+	ReadOnly = 0x20000
+)
+
+// 事务内部的状态标志位。
+const (
+	// txnFinished 表示事务已经结束或被重置，不再持有快照。
+	txnFinished = 0x01
+)
+
+// Put 的标志位。
+const (
+	// NoOverwrite 表示键已存在时不覆盖，返回 KeyExistError。
+	NoOverwrite = 0x10
+	// NoDupData 表示 DupSort 存储桶中相同的键值对已存在时返回 KeyExistError。
+	NoDupData = 0x20
+	// Current 表示覆盖游标当前位置的键值对，键必须与当前键相同。
+	Current = 0x40
+	// Reserve 表示只在页面中预留数据空间，由调用方直接写入返回的切片。
+	Reserve = 0x10000
+	// Append 表示键按顺序追加到存储桶末尾，键小于或等于最后一个键时返回 KeyExistError。
+	Append = 0x20000
+	// AppendDup 表示值按顺序追加到 DupSort 存储桶中当前键的末尾。
+	AppendDup = 0x40000
+)
+
 // Transaction 接口定义了Boltdb数据库事务的基本操作。
 type Transaction interface {
 	// （待补充具体的Transaction接口方法声明）
@@ -13,6 +51,24 @@ type transaction struct {
 	flags int
 	// db 指向当前事务所属的Boltdb数据库实例。
 	db *DB
+	// data 是事务开始时数据库的内存映射，事务中的页面都从这里读取。
+	data []byte
+	// size 是事务开始时数据文件的大小，超出文件末尾的页面不能从内存映射中读取。
+	size int
+	// ctx 为开始事务时传入的 context，长时间的遍历会检查它是否已被取消。
+	ctx context.Context
+	// arena 在 DebugMemory 模式下保存返回给调用方的键和值，事务结束时被毒化。
+	arena *guardArena
+	// commitHooks 存储写事务持久化提交后按注册顺序执行的回调。
+	commitHooks []func() error
+	// rollbackHooks 存储写事务回滚后按注册顺序执行的回调。
+	rollbackHooks []func() error
+	// watching 表示事务开始时存在订阅者，需要记录事务中的修改。
+	watching bool
+	// events 存储事务中的修改，提交后发布给订阅者。
+	events []Event
+	// prepared 是 Prepare 持久化的提交记录，不为 nil 时事务只能 Commit 或 Abort。
+	prepared *preparedTxn
 	// parent 指向当前事务的父级事务（如果存在）。
 	parent *transaction
 	// child 指向当前事务的子级事务（如果存在）。
@@ -21,22 +77,34 @@ type transaction struct {
 	nextPageNumber int
 	// freePages 存储当前事务中已释放的页面列表。
 	freePages []int
-	// spillPages 存储当前事务中溢出的页面列表。
-	spillPages []int
+	// reclaimed 存储从空闲列表中取出、尚未分配的页面，按页面ID升序排列。
+	reclaimed []pgno
+	// lastReclaimed 是已经取出的空闲列表记录的最大事务ID，这些记录在提交时被删除。
+	lastReclaimed int
+	// savingFreeList 表示正在写入空闲列表，此时分配页面不再从空闲列表中取出新的记录。
+	savingFreeList bool
+	// spillPages 存储当前事务中已经写入文件、不再保留在脏页列表中的页面。
+	spillPages map[pgno]bool
 	// dirtyList 存储当前事务中被修改但尚未同步到磁盘的页面列表。
-	dirtyList []int
+	dirtyList []*page
+	// dirtyPages 按页面ID索引 dirtyList 中的页面。
+	dirtyPages map[pgno]*page
 	// reader 提供对数据库底层数据的读取访问。
 	reader *reader
-	// buckets 存储当前事务涉及的所有桶的引用。
-	buckets []*Bucket
+	// buckets 存储当前事务涉及的所有桶的记录。
+	buckets []*bucket
+	// metaBuckets 存储从 meta 复制的 free 和 main 存储桶记录，Renew 时直接复用。
+	metaBuckets [2]bucket
+	// handles 存储当前事务中已打开的存储桶句柄。
+	handles []*Bucket
 	// bucketFlags 存储与各个桶关联的标志位信息。
 	bucketFlags []int
 	// TODO: 待实现bucketxs字段，用于存储与事务关联的扩展桶列表。
 	//bucketxs []*bucketx
-	// cursor 存储当前事务创建的所有游标对象。
+	// cursor 按存储桶索引存储写事务中打开的游标链表，链表通过 cursor.next 连接。
 	cursor []*cursor
 	// Implicit from slices? TODO: MDB_dbi mt_numdbs
-	// mt_dirty_room 存储当前事务的脏数据空间大小。
+	// mt_dirty_room 是脏页列表中还可以容纳的页面数量，DB 没有限制脏页大小时不使用。
 	mt_dirty_room int
 }
 
@@ -49,39 +117,158 @@ type ntxn struct {
 	pageState   pageState
 }
 
-// allocPage 为事务分配一个新的页面。
-// 该函数不接受参数，也不返回任何值。
-func (t *transaction) allocPage() {
+// allocPage 为事务分配 count 个连续的新页面，并将其加入脏页列表。
+// 优先复用空闲列表中回收的页面，没有足够的连续页面时从文件末尾分配。
+// 返回的页面在事务提交时才会写入文件。
+func (t *transaction) allocPage(count int) (*page, error) {
+	id, err := t.reclaim(count)
+	if err != nil {
+		return nil, err
+	}
+	if id == 0 {
+		id = t.nextPageNumber
+		t.nextPageNumber += count
+	}
+	buf := make([]byte, count*t.db.pageSize)
+	p := t.db.page(buf, 0)
+	p.id = pgno(id)
+	if count > 1 {
+		p.flags = p_overflow
+		p.overflow = count
+	}
+	p.flags |= p_dirty
+	t.dirty(p)
+	t.mt_dirty_room -= count
+	return p, nil
+}
+
+// reclaim 从回收的页面中取出 count 个连续的页面，返回第一个页面的ID，没有足够的连续页面时返回 0。
+// 回收的页面不足时按事务ID的顺序从空闲列表中取出新的记录。
+func (t *transaction) reclaim(count int) (int, error) {
+	for {
+		for i := 0; i+count <= len(t.reclaimed); i++ {
+			if t.reclaimed[i+count-1] == t.reclaimed[i]+pgno(count-1) {
+				id := t.reclaimed[i]
+				t.reclaimed = slices.Delete(t.reclaimed, i, i+count)
+				return int(id), nil
+			}
+		}
+		if t.savingFreeList {
+			return 0, nil
+		}
+		ok, err := t.loadFreeList()
+		if err != nil || !ok {
+			return 0, err
+		}
+	}
+}
 
+// loadFreeList 从空闲列表中取出下一条可以复用的记录，把其中的页面加入 reclaimed。
+// 只有事务ID小于 oldest 的记录可以复用，没有这样的记录时返回 false。
+func (t *transaction) loadFreeList() (bool, error) {
+	oldest := t.oldest()
+	if t.lastReclaimed+1 >= oldest {
+		return false, nil
+	}
+	c := t.newCursor(t.freeBucket())
+	defer c.Close()
+	k, v, err := c.SetRange(binary.BigEndian.AppendUint64(nil, uint64(t.lastReclaimed+1)))
+	if err != nil && err != NotFoundError {
+		return false, err
+	}
+	if err == NotFoundError || int(binary.BigEndian.Uint64(k)) >= oldest {
+		// oldest 之前的记录都已经取出。
+		t.lastReclaimed = oldest - 1
+		return false, nil
+	}
+	size := int(unsafe.Sizeof(pgno(0)))
+	ids := make([]pgno, len(v)/size)
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(unsafe.SliceData(ids))), len(ids)*size), v)
+	if len(ids) == 0 || int(ids[0]) != len(ids)-1 {
+		return false, CorruptedError
+	}
+	t.lastReclaimed = int(binary.BigEndian.Uint64(k))
+	t.reclaimed = append(t.reclaimed, ids[1:]...)
+	slices.Sort(t.reclaimed)
+	return true, nil
 }
 
-// oldest 方法
-// 获取最旧的事务信息
-// 参数: 无
-// 返回值: 返回最旧事务的索引编号，本示例中总是返回 0
+// oldest 返回可以复用的空闲列表记录的事务ID上界：事务ID小于它的记录中的页面不再被任何快照引用。
+// 上界不超过 t.id-1，上一个事务释放的页面仍被较旧的 meta 引用，最新的 meta 损坏时还可以回退到它。
 func (t *transaction) oldest() int {
-	return 0
+	oldest := t.id - 1
+	t.db.rmutex.Lock()
+	defer t.db.rmutex.Unlock()
+	for _, r := range t.db.readers {
+		if r.pid != 0 && r.txnid != -1 && r.txnid < oldest {
+			oldest = r.txnid
+		}
+	}
+	return oldest
 }
 
-// dirty标记一个页面为脏页。
-// 参数：
-//
-//	t *transaction：表示当前事务。
-//	p *page：需要被标记为脏页的页面指针。
+// dirty 把页面 p 加入脏页列表。
 func (t *transaction) dirty(p *page) {
+	t.dirtyList = append(t.dirtyList, p)
+	if t.dirtyPages == nil {
+		t.dirtyPages = make(map[pgno]*page)
+	}
+	t.dirtyPages[p.id] = p
+}
 
+// spill 在脏页列表已满时，把不被任何游标引用的脏页写入文件并移出脏页列表，使写事务占用的内存有上限。
+// c 是正在执行写操作的游标，它可能没有加入游标链表。写出的页面仍属于当前事务，
+// 之后从内存映射中读取，再次修改时由 unspill 读回内存，而不是复制到新的页面中。
+func (t *transaction) spill(c *cursor) error {
+	if t.db.maxDirty == 0 || t.mt_dirty_room > 0 {
+		return nil
+	}
+	keep := make(map[*page]bool)
+	c.keep(keep)
+	for _, head := range t.cursor {
+		for m := head; m != nil; m = m.next {
+			m.keep(keep)
+		}
+	}
+	if t.spillPages == nil {
+		t.spillPages = make(map[pgno]bool)
+	}
+	end := 0
+	dirty := t.dirtyList[:0]
+	for _, p := range t.dirtyList {
+		if keep[p] {
+			dirty = append(dirty, p)
+			continue
+		}
+		count, err := t.writePage(p)
+		if err != nil {
+			return err
+		}
+		delete(t.dirtyPages, p.id)
+		t.spillPages[p.id] = true
+		t.mt_dirty_room += count
+		end = max(end, (int(p.id)+count)*t.db.pageSize)
+	}
+	clear(t.dirtyList[len(dirty):])
+	t.dirtyList = dirty
+	// 写出的页面可能位于事务开始时的文件末尾之后，扩大内存映射以便读取它们。
+	t.db.mmutex.Lock()
+	defer t.db.mmutex.Unlock()
+	err := t.db.grow(end)
+	t.data, t.size = t.db.data, t.db.size
+	return err
 }
 
-// unspill函数用于将页面p的内容"倾倒"出来，并返回一个新的页面。
-// 这是一个示例函数，当前实现返回nil，表示未完成或不需要实际执行倾倒操作。
-//
-// 参数:
-// p *page - 指向需要进行倾倒操作的页面的指针。
-//
-// 返回值:
-// *page - 函数应该返回一个指向新页面的指针，但当前实现返回nil。
+// unspill 把当前事务中已经写入文件的页面 p 读回内存，使其重新成为脏页，页面ID不变。
 func (t *transaction) unspill(p *page) *page {
-	return nil
+	buf := make([]byte, t.db.pageSize)
+	copy(buf, p.bytes(t.db.pageSize))
+	np := t.db.page(buf, 0)
+	np.flags |= p_dirty
+	delete(t.spillPages, p.id)
+	t.dirty(np)
+	t.mt_dirty_room--
+	return np
 }
 
 // shadow 方法将当前事务（源）的某些属性或状态复制到另一个事务（目标）中。
@@ -104,19 +291,77 @@ func (t *transaction) shadow(dst *transaction) error {
 //
 //	无
 func (t *transaction) closeCursors(merge bool) {
+	for id := range t.cursor {
+		for c := t.cursor[id]; c != nil; c = t.cursor[id] {
+			c.Close()
+		}
+	}
+	t.cursor = nil
+}
 
+// Renew 为一个已经 Reset 的只读事务获取数据库最新的快照。
+// 事务复用原有的读者槽位和内存，不需要重新分配。
+func (t *transaction) Renew() error {
+	if t.writable() || t.flags&txnFinished == 0 {
+		return InvalidArgumentError
+	}
+	return t.renew()
 }
 
-// Renew 方法用于续费当前事务。
-// 参数:
-//
-//	无
-//
-// 返回值:
-//
-//	无
-func (t *transaction) Renew() {
+// renew 从当前有效的 meta 中获取快照，初始化事务的状态。
+func (t *transaction) renew() error {
+	m := t.db.meta()
+	if t.writable() {
+		t.id = m.txnid + 1
+	} else {
+		if t.reader == nil {
+			r, err := t.db.acquireReader()
+			if err != nil {
+				return err
+			}
+			t.reader = r
+		}
+		// 写事务可能在读取 meta 和登记快照之间提交并复用快照中的页面，
+		// 因此登记之后再次检查 meta，直到登记的快照仍然是最新的。
+		for {
+			t.db.setReader(t.reader, m.txnid)
+			latest := t.db.meta()
+			if latest.txnid == m.txnid {
+				break
+			}
+			m = latest
+		}
+		t.id = m.txnid
+	}
+	t.nextPageNumber = m.pgno + 1
+	if t.writable() && t.db.maxDirty > 0 {
+		t.mt_dirty_room = max(t.db.maxDirty/t.db.pageSize, 1)
+	}
+	t.db.mmutex.Lock()
+	err := t.db.grow(t.nextPageNumber * t.db.pageSize)
+	t.data, t.size = t.db.data, t.db.size
+	t.db.mmutex.Unlock()
+	if err != nil {
+		return err
+	}
+	t.metaBuckets[freeBucket], t.metaBuckets[mainBucket] = m.free, m.main
+	t.buckets = append(t.buckets[:0], &t.metaBuckets[freeBucket], &t.metaBuckets[mainBucket])
+	t.bucketFlags = append(t.bucketFlags[:0], int(m.free.flags), int(m.main.flags))
+	t.flags &^= txnFinished
+	return nil
+}
+
+// writable 返回当前事务是否为写事务。
+func (t *transaction) writable() bool {
+	return t.flags&ReadOnly == 0
+}
 
+// Context 返回事务关联的 context。
+func (t *transaction) Context() context.Context {
+	if t.ctx == nil {
+		return context.Background()
+	}
+	return t.ctx
 }
 
 // DB 返回当前事务关联的数据库。
@@ -135,65 +380,694 @@ func (t *transaction) updateBuckets(keep bool) {
 
 }
 
+// reset 释放事务持有的快照和游标，act 说明触发重置的操作。
+// 只读事务保留读者槽位以及已分配的内存，以便 Renew 复用；写事务释放写锁。
 func (t *transaction) reset(act string) {
-
+	t.closeCursors(false)
+	if t.arena != nil {
+		t.arena.poison()
+	}
+	t.handles = t.handles[:0]
+	t.buckets = t.buckets[:0]
+	t.bucketFlags = t.bucketFlags[:0]
+	if t.writable() {
+		t.dirtyList = t.dirtyList[:0]
+		clear(t.dirtyPages)
+		t.freePages = t.freePages[:0]
+		t.reclaimed, t.lastReclaimed = t.reclaimed[:0], 0
+		clear(t.spillPages)
+		t.events = t.events[:0]
+		t.db.transaction = nil
+		t.db.unlockWriter()
+	} else if t.reader != nil {
+		t.db.setReader(t.reader, -1)
+	}
+	t.flags |= txnFinished
 }
 
+// Reset 释放只读事务持有的快照，但保留读者槽位，之后可以通过 Renew 重新使用该事务。
+// 对写事务调用 Reset 不起作用。
 func (t *transaction) Reset() {
-
+	if t.writable() || t.flags&txnFinished != 0 {
+		return
+	}
+	t.reset("reset")
 }
 
+// Abort 放弃事务中的所有修改并释放事务占用的资源，包括只读事务的读者槽位。
+// 写事务回滚后执行通过 OnRollback 注册的回调。
 func (t *transaction) Abort() {
+	finished := t.flags&txnFinished != 0
+	if !finished {
+		t.reset("abort")
+	}
+	if t.prepared != nil {
+		t.db.clearPrepared()
+		t.prepared = nil
+	}
+	if t.reader != nil {
+		t.db.releaseReader(t.reader)
+		t.reader = nil
+	}
+	if !finished && t.writable() {
+		hooks := t.rollbackHooks
+		t.commitHooks, t.rollbackHooks = nil, nil
+		runHooks("rollback", hooks)
+	}
+}
+
+// Commit 提交写事务中的所有修改，并在数据和 meta 都写入磁盘后执行通过 OnCommit 注册的回调。
+// 提交失败时事务被回滚。只读事务的 Commit 等同于 Abort。
+func (t *transaction) Commit() error {
+	if t.flags&txnFinished != 0 {
+		return BadTransactionError
+	}
+	if !t.writable() {
+		t.Abort()
+		return nil
+	}
+	if t.prepared != nil {
+		if err := t.db.writeMeta(t.prepared.meta); err != nil {
+			// 提交记录保留在磁盘上，数据库进入未决状态，由 Coordinator.Recover 决定其结果。
+			t.db.Lock()
+			t.db.prepared, t.prepared = t.prepared, nil
+			t.db.Unlock()
+			t.Abort()
+			return err
+		}
+		t.db.clearPrepared()
+		t.prepared = nil
+	} else if err := t.commit(); err != nil {
+		t.Abort()
+		return err
+	}
+	hooks := t.commitHooks
+	t.commitHooks, t.rollbackHooks = nil, nil
+	t.db.publish(t.events)
+	t.reset("commit")
+	runHooks("commit", hooks)
+	return nil
+}
 
+// commit 依次写回子存储桶记录和空闲列表，写入并同步脏页，最后写入 meta。
+func (t *transaction) commit() error {
+	if err := t.spillBuckets(); err != nil {
+		return err
+	}
+	if err := t.saveFreeList(); err != nil {
+		return err
+	}
+	if err := t.flush(false); err != nil {
+		return err
+	}
+	if err := t.db.sync(false); err != nil {
+		return err
+	}
+	return t.writeMeta()
 }
 
+// OnCommit 注册一个在写事务持久化提交后执行的回调，多个回调按注册顺序执行。
+// 回调在写锁释放之后执行，其返回的错误或引发的 panic 会被报告，但不会影响已提交的数据。
+func (t *transaction) OnCommit(fn func() error) error {
+	if !t.writable() {
+		return ReadOnlyError
+	}
+	t.commitHooks = append(t.commitHooks, fn)
+	return nil
+}
+
+// OnRollback 注册一个在写事务回滚或提交失败后执行的回调，多个回调按注册顺序执行。
+func (t *transaction) OnRollback(fn func() error) error {
+	if !t.writable() {
+		return ReadOnlyError
+	}
+	t.rollbackHooks = append(t.rollbackHooks, fn)
+	return nil
+}
+
+// runHooks 按顺序执行回调，单个回调的错误或 panic 不会影响后续回调的执行。
+func runHooks(name string, hooks []func() error) {
+	for _, fn := range hooks {
+		if err := runHook(fn); err != nil {
+			warnf("boltdb: %s hook failed: %v\n", name, err)
+		}
+	}
+}
+
+// runHook 执行回调，并将回调引发的 panic 转换为错误。
+func runHook(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn()
+}
+
+// freeBucket 返回事务的空闲页面存储桶。
+func (t *transaction) freeBucket() *Bucket {
+	return &Bucket{
+		transaction: t,
+		id:          freeBucket,
+		bucket:      t.buckets[freeBucket],
+		flags:       t.bucketFlags[freeBucket],
+		compare:     keyCompare(t.bucketFlags[freeBucket]),
+	}
+}
+
+// saveFreeList 删除事务中取出的空闲列表记录，再将事务中释放的页面和取出后未分配的页面写入空闲页面存储桶，
+// 键为事务ID，值为页面ID数组，第一个元素是页面数量。
+// 修改空闲页面存储桶本身会复制其中的页面并释放原页面，也会分配回收的页面，
+// 因此重复写入直到释放的页面和未分配的页面都不再变化。
 func (t *transaction) saveFreeList() error {
+	if len(t.freePages) == 0 && len(t.reclaimed) == 0 && t.lastReclaimed == 0 {
+		return nil
+	}
+	t.savingFreeList = true
+	defer func() { t.savingFreeList = false }()
+	b := t.freeBucket()
+	for {
+		dropped, err := t.dropReclaimed(b)
+		if err != nil {
+			return err
+		}
+		if !dropped {
+			break
+		}
+	}
+	key := binary.BigEndian.AppendUint64(nil, uint64(t.id))
+	for free, reclaimed := -1, -1; free != len(t.freePages) || reclaimed != len(t.reclaimed); {
+		free, reclaimed = len(t.freePages), len(t.reclaimed)
+		ids := make([]pgno, 1, free+reclaimed+1)
+		for _, id := range t.freePages {
+			ids = append(ids, pgno(id))
+		}
+		ids = append(ids, t.reclaimed...)
+		ids[0] = pgno(len(ids) - 1)
+		slices.Sort(ids[1:])
+		if len(ids) == 1 {
+			// 之前写入的记录中的页面已经全部被分配。
+			if err := t.Delete(b, key, nil); err != nil && err != NotFoundError {
+				return err
+			}
+			continue
+		}
+		value := unsafe.Slice((*byte)(unsafe.Pointer(&ids[0])), len(ids)*int(unsafe.Sizeof(pgno(0))))
+		if _, err := t.put(b, key, value, 0); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (t *transaction) flush(keep bool) {
+// dropReclaimed 删除空闲页面存储桶中第一条已经取出的记录，没有这样的记录时返回 false。
+func (t *transaction) dropReclaimed(b *Bucket) (bool, error) {
+	c := t.newCursor(b)
+	defer c.Close()
+	if err := c.First(); err == NotFoundError {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	k, _, err := c.Current()
+	if err != nil || int(binary.BigEndian.Uint64(k)) > t.lastReclaimed {
+		return false, err
+	}
+	return true, c.Del(0)
+}
 
+// flush 将脏页写入数据文件，keep 为 true 时保留脏页列表。
+func (t *transaction) flush(keep bool) error {
+	for _, p := range t.dirtyList {
+		if _, err := t.writePage(p); err != nil {
+			return err
+		}
+	}
+	t.db.mmutex.Lock()
+	t.db.size = max(t.db.size, t.nextPageNumber*t.db.pageSize)
+	t.db.mmutex.Unlock()
+	if !keep {
+		t.dirtyList = t.dirtyList[:0]
+		clear(t.dirtyPages)
+	}
+	return nil
+}
+
+// writePage 清除脏页 p 的脏页标志并将其写入数据文件，返回页面占用的页面数量。
+func (t *transaction) writePage(p *page) (int, error) {
+	count := 1
+	if p.flags&p_overflow != 0 {
+		count = p.overflow
+	}
+	p.flags &^= p_dirty
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(p)), count*t.db.pageSize)
+	_, err := t.db.file.WriteAt(buf, int64(p.id)*int64(t.db.pageSize))
+	return count, err
 }
 
+// writeMeta 将事务的存储桶记录和事务ID写入较旧的 meta 页面，使其成为新的有效 meta。
+// meta 文件以 O_SYNC 方式打开，写入返回时 meta 已持久化。
 func (t *transaction) writeMeta() error {
-	return nil
+	return t.db.writeMeta(t.metaPage())
+}
+
+// metaPage 返回包含事务的存储桶记录和事务ID的 meta 页面。
+func (t *transaction) metaPage() []byte {
+	buf := make([]byte, t.db.pageSize)
+	p := t.db.page(buf, 0)
+	p.flags = p_meta
+
+	m := (*meta)(p.data())
+	*m = *t.db.meta()
+	m.free, m.main = *t.buckets[freeBucket], *t.buckets[mainBucket]
+	m.pgno = t.nextPageNumber - 1
+	m.txnid = t.id
+	return buf
 }
 
+// getPage 返回事务中页面ID为 id 的页面，以及页面所在的层级：
+// 0 表示页面位于内存映射中，1 表示页面是当前事务中的脏页。
 func (t *transaction) getPage(id int) (*page, int, error) {
-	return nil, 0, nil
+	if p, ok := t.dirtyPages[pgno(id)]; ok {
+		return p, 1, nil
+	}
+	if id < 0 || id >= t.nextPageNumber || (id+1)*t.db.pageSize > min(t.size, len(t.data)) {
+		return nil, 0, PageNotFoundError
+	}
+	return t.db.page(t.data, id), 0, nil
+}
+
+// walk 以深度优先的顺序遍历以 root 为根的子树中的所有页面。
+// 访问每个页面之前都会检查事务的 context，context 被取消时返回 ctx.Err()。
+func (t *transaction) walk(root pgno, fn func(p *page, depth int) error) error {
+	if root == p_invalid || root == 0 {
+		return nil
+	}
+	return t.walkPage(root, 0, fn)
 }
 
-func (t *transaction) readNode(lefe *node, data []byte) error {
+func (t *transaction) walkPage(id pgno, depth int, fn func(p *page, depth int) error) error {
+	if err := t.Context().Err(); err != nil {
+		return err
+	}
+	p, _, err := t.getPage(int(id))
+	if err != nil {
+		return err
+	}
+	if err := fn(p, depth); err != nil {
+		return err
+	}
+	if p.flags&p_branch != 0 {
+		for i := 0; i < p.nodeCount(); i++ {
+			if err := t.walkPage(p.node(i).pgno(), depth+1, fn); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
-func (t *transaction) Get(bucket Bucket, key []byte) ([]byte, error) {
-	return nil, nil
+// walkStat 遍历存储桶的所有页面，重新统计其页面数量和深度。
+func (t *transaction) walkStat(b *Bucket) (*Stat, error) {
+	s := &Stat{PageSize: t.db.pageSize, EntryCount: int(b.bucket.entries)}
+	err := t.walk(b.bucket.root, func(p *page, depth int) error {
+		if depth+1 > s.Depth {
+			s.Depth = depth + 1
+		}
+		switch {
+		case p.flags&p_branch != 0:
+			s.BranchPageCount++
+		case p.flags&p_leaf != 0:
+			s.LeafPageCount++
+			for i := 0; i < p.nodeCount(); i++ {
+				if n := p.node(i); n.flags&bigNode != 0 {
+					if op, _, err := t.getPage(int(n.overflowPgno())); err == nil {
+						s.OverflowPageCount += op.overflow
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
-func (t *transaction) Cursor(b Bucket) error {
-	return nil
+// readNode 返回叶子节点的数据，大节点的数据从溢出页面中读取。
+func (t *transaction) readNode(n *node) ([]byte, error) {
+	if n.flags&bigNode == 0 {
+		return n.value(), nil
+	}
+	id := int(n.overflowPgno())
+	p, level, err := t.getPage(id)
+	if err != nil {
+		return nil, err
+	}
+	if p.flags&p_overflow == 0 {
+		return nil, CorruptedError
+	}
+	if level == 0 && (id+p.overflow)*t.db.pageSize > min(t.size, len(t.data)) {
+		return nil, PageNotFoundError
+	}
+	size := n.dataSize()
+	if pageHeaderSize+size > p.overflow*t.db.pageSize {
+		return nil, CorruptedError
+	}
+	return p.bytes(pageHeaderSize + size)[pageHeaderSize:], nil
+}
+
+// Get 返回存储桶中 key 对应的值，DupSort 存储桶中返回第一个重复值。
+func (t *transaction) Get(b *Bucket, key []byte) ([]byte, error) {
+	c := t.newCursor(b)
+	defer c.Close()
+	_, v, err := c.Set(key)
+	return v, err
 }
 
+// newCursor 创建一个不加入游标链表的游标，DupSort 存储桶同时创建子游标。
+func (t *transaction) newCursor(b *Bucket) *cursor {
+	c := &cursor{}
+	if b.flags&DupSort != 0 {
+		c.init(t, b, &xcursor{})
+	} else {
+		c.init(t, b, nil)
+	}
+	return c
+}
+
+// guard 在 DebugMemory 模式下将键和值复制到受保护的内存中，否则原样返回。
+func (t *transaction) guard(key []byte, value []byte) ([]byte, []byte, error) {
+	if t.arena == nil {
+		return key, value, nil
+	}
+	k, err := t.arena.copy(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	v, err := t.arena.copy(value)
+	if err != nil {
+		return nil, nil, err
+	}
+	return k, v, nil
+}
+
+// Cursor 为指定的存储桶创建一个游标，调用方使用完毕后需调用 Close。
+func (t *transaction) Cursor(b *Bucket) (Cursor, error) {
+	c := t.newCursor(b)
+	if t.writable() {
+		t.track(c)
+	}
+	return c, nil
+}
+
+// track 将游标加入其存储桶的游标链表，使其在其他游标修改树时得到修正。
+func (t *transaction) track(c *cursor) {
+	for len(t.cursor) <= c.bucketID {
+		t.cursor = append(t.cursor, nil)
+	}
+	c.next = t.cursor[c.bucketID]
+	t.cursor[c.bucketID] = c
+}
+
+// untrack 将游标从其存储桶的游标链表中移除。
+func (t *transaction) untrack(c *cursor) {
+	if c.bucketID >= len(t.cursor) {
+		return
+	}
+	for pp := &t.cursor[c.bucketID]; *pp != nil; pp = &(*pp).next {
+		if *pp == c {
+			*pp = c.next
+			c.next = nil
+			return
+		}
+	}
+}
+
+// tracked 返回存储桶 id 的游标链表的头部。
+func (t *transaction) tracked(id int) *cursor {
+	if id >= len(t.cursor) {
+		return nil
+	}
+	return t.cursor[id]
+}
+
+// Delete 删除存储桶中 key 对应的键值对。
+// DupSort 存储桶中 data 为 nil 时删除 key 的所有重复值，否则只删除与 data 相同的重复值。
+// key 是子存储桶时返回 InCompatibleError，子存储桶需要通过 DeleteBucket 删除。
 func (t *transaction) Delete(b *Bucket, key []byte, data []byte) error {
-	return nil
+	if !t.writable() {
+		return ReadOnlyError
+	}
+	if t.prepared != nil {
+		return PreparedError
+	}
+	c := t.newCursor(b)
+	defer c.Close()
+	_, v, err := c.Set(key)
+	if err != nil {
+		return err
+	}
+	flags := NoDupData
+	if data != nil && c.xcursor != nil {
+		if !c.dup() {
+			// 键只有一个值，值匹配时删除整个键。
+			if b.compare(v, data) != 0 {
+				return NotFoundError
+			}
+		} else if _, _, err := c.xcursor.cursor.Set(data); err != nil {
+			return err
+		} else {
+			flags = 0
+		}
+	}
+	return c.Del(flags)
 }
-func (t *transaction) Put(b Bucket, key []byte, data []byte, flags int) error {
-	return nil
+
+// Put 向存储桶写入一个键值对，flags 为 Put 标志位的组合。
+// key 是子存储桶时返回 InCompatibleError。
+func (t *transaction) Put(b *Bucket, key []byte, data []byte, flags int) error {
+	if flags&(Reserve|nodeFlags) != 0 {
+		// 节点的类型标识只能由存储桶内部设置。
+		return InvalidArgumentError
+	}
+	_, err := t.put(b, key, data, flags)
+	return err
+}
+
+// Reserve 在存储桶中为 key 预留 size 字节的数据空间，并返回页面内对应的可写切片。
+// 返回的切片只在当前事务的下一次写操作之前有效。
+func (t *transaction) Reserve(b *Bucket, key []byte, size int, flags int) ([]byte, error) {
+	if size < 0 {
+		return nil, BadValueSizeError
+	}
+	if flags&nodeFlags != 0 {
+		return nil, InvalidArgumentError
+	}
+	return t.put(b, key, make([]byte, size), flags|Reserve)
 }
 
+// put 是 Put 和 Reserve 的公共实现，返回值在页面中的数据区。
+func (t *transaction) put(b *Bucket, key []byte, data []byte, flags int) ([]byte, error) {
+	if !t.writable() {
+		return nil, ReadOnlyError
+	}
+	if t.prepared != nil {
+		return nil, PreparedError
+	}
+	if len(key) == 0 || len(key) > MaxKeySize || uint64(len(data)) > MaxDataSize {
+		return nil, BadValueSizeError
+	}
+	if flags&Current != 0 {
+		// Current 只能用于已定位的游标。
+		return nil, InvalidArgumentError
+	}
+	c := t.newCursor(b)
+	defer c.Close()
+	return c.put(key, data, flags)
+}
+
+// Bucket 返回名为 name 的顶层存储桶句柄，name 为空时返回主存储桶。
+// 同一事务中多次打开同一个存储桶会返回同一个句柄。
+// flags 只能包含存储桶的标志位，不为 0 时必须与存储桶的标志位一致，否则返回 InCompatibleError。
 func (t *transaction) Bucket(name string, flags int) (*Bucket, error) {
-	return nil, nil
+	if flags&^bucketFlags != 0 {
+		return nil, InvalidArgumentError
+	}
+	b, err := t.bucket(name)
+	if err != nil {
+		return nil, err
+	}
+	if flags != 0 && flags != b.flags {
+		// flags 不为 0 时必须与存储桶创建时的标志位一致。
+		return nil, InCompatibleError
+	}
+	return b, nil
+}
+
+// bucket 返回名为 name 的顶层存储桶句柄，name 为空时返回主存储桶。
+func (t *transaction) bucket(name string) (*Bucket, error) {
+	if name != "" {
+		main, err := t.bucket("")
+		if err != nil {
+			return nil, err
+		}
+		return main.Bucket(name)
+	}
+	if b := t.handle(nil, ""); b != nil {
+		return b, nil
+	}
+	if len(t.buckets) <= mainBucket {
+		return nil, BadTransactionError
+	}
+
+	// 主存储桶的记录保存在 meta 中。
+	b := &Bucket{
+		transaction: t,
+		id:          mainBucket,
+		bucket:      t.buckets[mainBucket],
+		flags:       t.bucketFlags[mainBucket],
+		compare:     keyCompare(t.bucketFlags[mainBucket]),
+	}
+	t.handles = append(t.handles, b)
+	return b, nil
 }
 
-func (t *transaction) Stat(b Bucket) *Stat {
+// handle 返回当前事务中已打开的、父存储桶为 parent 且名为 name 的句柄。
+func (t *transaction) handle(parent *Bucket, name string) *Bucket {
+	for _, b := range t.handles {
+		if b.parent == parent && b.name == name {
+			return b
+		}
+	}
 	return nil
 }
 
-func (t *transaction) BucketFlags(b Bucket) (int, error) {
-	return 0, nil
+// openBucket 根据父存储桶叶子节点中的值打开子存储桶。
+// 值以存储桶记录开头，内联存储桶的数据页紧跟在记录之后。
+func (t *transaction) openBucket(parent *Bucket, name string, value []byte) (*Bucket, error) {
+	if len(value) < bucketHeaderSize {
+		return nil, InCompatibleError
+	}
+	rec := &bucket{}
+	*rec = *(*bucket)(unsafe.Pointer(&value[0]))
+
+	b := &Bucket{
+		transaction: t,
+		id:          len(t.buckets),
+		bucket:      rec,
+		parent:      parent,
+		name:        name,
+		flags:       int(rec.flags),
+		compare:     keyCompare(int(rec.flags)),
+	}
+	if rec.root == 0 {
+		if len(value) < bucketHeaderSize+pageHeaderSize {
+			return nil, CorruptedError
+		}
+		// 复制内联页面，使其在原页面被修改后依然有效。
+		b.inline = append([]byte(nil), value[bucketHeaderSize:]...)
+	}
+	t.buckets = append(t.buckets, rec)
+	t.bucketFlags = append(t.bucketFlags, b.flags)
+	t.handles = append(t.handles, b)
+	return b, nil
+}
+
+// closeBucket 从当前事务中移除存储桶句柄及其所有子存储桶的句柄。
+func (t *transaction) closeBucket(b *Bucket) {
+	handles := t.handles[:0]
+	for _, h := range t.handles {
+		if !h.descendantOf(b) {
+			handles = append(handles, h)
+		}
+	}
+	t.handles = handles
+}
+
+// spillBuckets 将所有打开的子存储桶记录写回其父存储桶。
+// 越深的存储桶越先写回，保证父存储桶写回时已包含子存储桶的最新记录。
+func (t *transaction) spillBuckets() error {
+	handles := append([]*Bucket(nil), t.handles...)
+	sort.SliceStable(handles, func(i, j int) bool {
+		return handles[i].depth() > handles[j].depth()
+	})
+	for _, b := range handles {
+		if b.parent == nil {
+			continue
+		}
+		value, err := b.value()
+		if err != nil {
+			return err
+		}
+		if v, err := t.Get(b.parent, []byte(b.name)); err == nil && bytes.Equal(v, value) {
+			// 没有修改过的存储桶不需要写回，避免复制父存储桶的页面。
+			continue
+		}
+		if _, err := t.put(b.parent, []byte(b.name), value, bucketNode); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (t *transaction) Drop(b Bucket, del int) error {
+// Stat 返回存储桶的统计信息。
+func (t *transaction) Stat(b *Bucket) *Stat {
+	return b.bucket.stat(t.db.pageSize)
+}
+
+// BucketFlags 返回存储桶的标志位。
+func (t *transaction) BucketFlags(b *Bucket) (int, error) {
+	return b.flags, nil
+}
+
+// Drop 释放存储桶中的所有页面并清空它，del 不为 0 时同时从父存储桶中删除它的记录，主存储桶不能被删除。
+// 存储桶中的子存储桶随之被删除，它们在当前事务中的句柄不再有效。
+func (t *transaction) Drop(b *Bucket, del int) error {
+	if !t.writable() {
+		return ReadOnlyError
+	}
+	if t.prepared != nil {
+		return PreparedError
+	}
+	if del != 0 && b.parent == nil {
+		return InvalidArgumentError
+	}
+	// 先写回打开的子存储桶，使页面中的记录指向它们最新的根页面。
+	if err := t.spillBuckets(); err != nil {
+		return err
+	}
+	c := t.newCursor(b)
+	if err := c.drop0(); err != nil {
+		return err
+	}
+	rec := b.bucket
+	*rec = bucket{flags: rec.flags, sequence: rec.sequence, root: p_invalid}
+	b.inline = nil
+	for m := t.tracked(b.id); m != nil; m = m.next {
+		m.snum, m.top = 0, 0
+		m.flags &^= c_initialized | c_eof | c_del
+	}
+
+	if del == 0 {
+		handles := t.handles[:0]
+		for _, h := range t.handles {
+			if h == b || !h.descendantOf(b) {
+				handles = append(handles, h)
+			}
+		}
+		t.handles = handles
+		return nil
+	}
+	pc := t.newCursor(b.parent)
+	defer pc.Close()
+	if _, _, err := pc.Set([]byte(b.name)); err != nil {
+		return err
+	}
+	if err := pc.del0(pc.page[pc.top].node(pc.ki[pc.top])); err != nil {
+		return err
+	}
+	t.closeBucket(b)
 	return nil
 }
@@ -0,0 +1,317 @@
+package boltdb_go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 确保重置后的只读事务可以复用原有的读者槽位重新获取快照。
+func TestTransaction_ResetRenew(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		txn, err := db.Transaction(nil, ReadOnly)
+		assert.NoError(t, err)
+		r := txn.reader
+
+		txn.Reset()
+		assert.Equal(t, -1, r.txnid)
+		assert.NotEqual(t, 0, r.pid)
+
+		assert.NoError(t, txn.Renew())
+		assert.Equal(t, r, txn.reader)
+		assert.Equal(t, txn.id, r.txnid)
+		assert.Len(t, db.Readers(), 1)
+
+		txn.Abort()
+		assert.Equal(t, 0, r.pid)
+	})
+}
+
+// 确保只有已重置的只读事务可以 Renew。
+func TestTransaction_RenewInvalid(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		txn, _ := db.Transaction(nil, ReadOnly)
+		assert.Equal(t, InvalidArgumentError, txn.Renew())
+		txn.Abort()
+
+		txn, _ = db.Transaction(nil, 0)
+		txn.Reset()
+		assert.Equal(t, InvalidArgumentError, txn.Renew())
+		txn.Abort()
+	})
+}
+
+// 确保等待写锁时可以通过 context 取消。
+func TestTransaction_ContextCancel(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		w, err := db.Transaction(nil, 0)
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		txn, err := db.TransactionContext(ctx, nil, 0)
+		assert.Nil(t, txn)
+		assert.Equal(t, context.DeadlineExceeded, err)
+
+		// 只读事务不需要等待写锁。
+		txn, err = db.TransactionContext(ctx, nil, ReadOnly)
+		assert.NoError(t, err)
+		txn.Abort()
+
+		w.Abort()
+		txn, err = db.TransactionContext(context.Background(), nil, 0)
+		assert.NoError(t, err)
+		txn.Abort()
+	})
+}
+
+// 确保写锁被占用时 TryTransaction 立即返回 WriterBusyError。
+func TestDB_TryTransaction(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		w, err := db.TryTransaction(nil, 0)
+		assert.NoError(t, err)
+
+		txn, err := db.TryTransaction(nil, 0)
+		assert.Nil(t, txn)
+		assert.Equal(t, WriterBusyError, err)
+
+		w.Abort()
+		txn, err = db.TryTransaction(nil, 0)
+		assert.NoError(t, err)
+		txn.Abort()
+	})
+}
+
+// 确保等待写锁超时后返回 WriterBusyError，包括写锁被其他进程持有的情况。
+func TestDB_LockTimeout(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		assert.NoError(t, db.SetLockTimeout(10*time.Millisecond))
+		w, _ := db.Transaction(nil, 0)
+		txn, err := db.Transaction(nil, 0)
+		assert.Nil(t, txn)
+		assert.Equal(t, WriterBusyError, err)
+		w.Abort()
+
+		// 通过另一个文件描述符模拟其他进程持有的文件锁。
+		f, err := os.Open(path)
+		assert.NoError(t, err)
+		defer f.Close()
+		assert.NoError(t, syscall.Flock(int(f.Fd()), syscall.LOCK_EX))
+		txn, err = db.Transaction(nil, 0)
+		assert.Nil(t, txn)
+		assert.Equal(t, WriterBusyError, err)
+		txn, err = db.Transaction(nil, ReadOnly)
+		assert.NoError(t, err)
+		txn.Abort()
+
+		assert.NoError(t, syscall.Flock(int(f.Fd()), syscall.LOCK_UN))
+		txn, err = db.Transaction(nil, 0)
+		assert.NoError(t, err)
+		txn.Abort()
+	})
+}
+
+// 确保 DebugMemory 模式下事务结束后访问其返回的数据会立即失败。
+func TestTransaction_DebugMemory(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		assert.Equal(t, InvalidArgumentError, db.SetFlags(DupSort, true))
+		assert.NoError(t, db.SetFlags(DebugMemory, true))
+
+		txn, _ := db.Transaction(nil, ReadOnly)
+		k, v, err := txn.guard([]byte("foo"), []byte("bar"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("foo"), k)
+		assert.Equal(t, []byte("bar"), v)
+		txn.Abort()
+
+		defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+		var b byte
+		assert.Panics(t, func() { b = k[0] })
+		assert.Panics(t, func() { b = v[0] })
+		assert.Zero(t, b)
+	})
+}
+
+// 确保毒化的内存在隔离区中不超过 guardQuarantineSize，超出的部分被解除映射。
+func TestTransaction_DebugMemoryQuarantine(t *testing.T) {
+	for i := 0; i < 2*guardQuarantineSize/guardChunkSize; i++ {
+		a := &guardArena{}
+		_, err := a.copy([]byte("value"))
+		assert.NoError(t, err)
+		a.poison()
+	}
+	guardQuarantine.Lock()
+	defer guardQuarantine.Unlock()
+	assert.LessOrEqual(t, guardQuarantine.size, guardQuarantineSize)
+	assert.Equal(t, guardQuarantineSize/guardChunkSize, len(guardQuarantine.chunks))
+}
+
+// 确保提交后按注册顺序执行 OnCommit 回调，回调的错误和 panic 不影响提交结果。
+func TestTransaction_OnCommit(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		var calls []string
+		txn, _ := db.Transaction(nil, 0)
+		id := txn.id
+		txn.OnCommit(func() error { calls = append(calls, "a"); return nil })
+		txn.OnCommit(func() error { panic("boom") })
+		txn.OnCommit(func() error { calls = append(calls, "b"); return errors.New("failed") })
+		txn.OnCommit(func() error { calls = append(calls, "c"); return nil })
+		txn.OnRollback(func() error { calls = append(calls, "rollback"); return nil })
+		assert.NoError(t, txn.Commit())
+		assert.Equal(t, []string{"a", "b", "c"}, calls)
+		assert.Equal(t, BadTransactionError, txn.Commit())
+
+		// 提交后的事务ID对新事务可见，写锁已释放。
+		txn, err := db.TryTransaction(nil, ReadOnly)
+		assert.NoError(t, err)
+		assert.Equal(t, id, txn.id)
+		assert.Equal(t, ReadOnlyError, txn.OnCommit(func() error { return nil }))
+		txn.Abort()
+	})
+}
+
+// 确保回滚后按注册顺序执行 OnRollback 回调，且只执行一次。
+func TestTransaction_OnRollback(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		var calls []string
+		txn, _ := db.Transaction(nil, 0)
+		txn.OnCommit(func() error { calls = append(calls, "commit"); return nil })
+		txn.OnRollback(func() error { calls = append(calls, "a"); return nil })
+		txn.OnRollback(func() error { calls = append(calls, "b"); return nil })
+		txn.Abort()
+		txn.Abort()
+		assert.Equal(t, []string{"a", "b"}, calls)
+	})
+}
+
+// 确保写事务复用空闲列表中的页面，大量的小事务不会使文件不断增长。
+func TestTransaction_ReuseFreePages(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		for i := 0; i < 2000; i++ {
+			txn, err := db.Transaction(nil, 0)
+			assert.NoError(t, err)
+			b, _ := txn.Bucket("", 0)
+			assert.NoError(t, b.Put([]byte(fmt.Sprintf("%04d", i)), make([]byte, 100), 0))
+			assert.NoError(t, txn.Commit())
+		}
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Less(t, info.Size(), int64(200*db.pageSize))
+
+		txn, _ := db.Transaction(nil, ReadOnly)
+		defer txn.Abort()
+		errs, err := txn.Check()
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+	})
+}
+
+// 确保快照被只读事务持有时，其中的页面不会被写事务复用。
+func TestTransaction_ReuseFreePagesReader(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		put := func(value string) {
+			txn, err := db.Transaction(nil, 0)
+			assert.NoError(t, err)
+			b, _ := txn.Bucket("", 0)
+			for i := 0; i < 100; i++ {
+				assert.NoError(t, b.Put([]byte(fmt.Sprintf("%04d", i)), []byte(value), 0))
+			}
+			assert.NoError(t, txn.Commit())
+		}
+		put("old")
+		r, err := db.Transaction(nil, ReadOnly)
+		assert.NoError(t, err)
+		defer r.Abort()
+		for i := 0; i < 20; i++ {
+			put(fmt.Sprint("new", i))
+		}
+		b, _ := r.Bucket("", 0)
+		for i := 0; i < 100; i++ {
+			v, err := b.Get([]byte(fmt.Sprintf("%04d", i)))
+			assert.NoError(t, err)
+			assert.Equal(t, "old", string(v))
+		}
+	})
+}
+
+// 确保限制脏页大小的写事务把脏页写入文件后仍能继续修改它们，提交的结果与全部保留在内存中时相同。
+func TestTransaction_Spill(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		defer db.Close()
+		assert.Equal(t, InvalidArgumentError, db.SetMaxDirtySize(-1))
+		assert.NoError(t, db.SetMaxDirtySize(8*db.pageSize))
+		txn, err := db.Transaction(nil, 0)
+		assert.NoError(t, err)
+		main, _ := txn.Bucket("", 0)
+		b, err := main.CreateBucket("b", 0)
+		assert.NoError(t, err)
+		dups, err := main.CreateBucket("dups", DupSort)
+		assert.NoError(t, err)
+		c, _ := txn.Cursor(b)
+		defer c.Close()
+
+		maxDirty := 0
+		for i := 0; i < 3000; i++ {
+			// 乱序写入使之前写出的页面再次被修改。
+			k := []byte(fmt.Sprintf("%04d", i*7919%3000))
+			assert.NoError(t, b.Put(k, []byte(fmt.Sprint("value", i*7919%3000)), 0))
+			assert.NoError(t, dups.Put([]byte(fmt.Sprint(i%3)), k, 0))
+			if i == 1000 {
+				_, _, err := c.Set([]byte("2000"))
+				assert.NoError(t, err)
+			}
+			maxDirty = max(maxDirty, len(txn.dirtyList))
+		}
+		assert.Less(t, maxDirty, 40)
+		assert.NotEmpty(t, txn.spillPages)
+		k, v, err := c.Next()
+		assert.NoError(t, err)
+		assert.Equal(t, "2001", string(k))
+		assert.Equal(t, "value2001", string(v))
+		for i := 0; i < 3000; i += 2 {
+			assert.NoError(t, b.Delete([]byte(fmt.Sprintf("%04d", i)), nil))
+		}
+		assert.NoError(t, txn.Commit())
+
+		txn, _ = db.Transaction(nil, ReadOnly)
+		defer txn.Abort()
+		errs, err := txn.Check()
+		assert.NoError(t, err)
+		assert.Empty(t, errs)
+		b, _ = txn.Bucket("b", 0)
+		assert.Equal(t, 1500, b.Stats().EntryCount)
+		v, err = b.Get([]byte("2999"))
+		assert.NoError(t, err)
+		assert.Equal(t, "value2999", string(v))
+		_, err = b.Get([]byte("2998"))
+		assert.Equal(t, NotFoundError, err)
+		dups, _ = txn.Bucket("dups", 0)
+		dc, _ := txn.Cursor(dups)
+		defer dc.Close()
+		_, _, err = dc.Set([]byte("1"))
+		assert.NoError(t, err)
+		n, err := dc.Count()
+		assert.NoError(t, err)
+		assert.Equal(t, 1000, n)
+	})
+}
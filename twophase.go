@@ -0,0 +1,341 @@
+package boltdb_go
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// preparedTxn 是一个已 Prepare 事务的提交记录：全局事务ID和提交时要写入的 meta 页面。
+type preparedTxn struct {
+	gid  string
+	meta []byte
+}
+
+// Prepare 执行两阶段提交的第一阶段：写入并同步事务的所有脏页，
+// 并将全局事务ID gid 和待写入的 meta 页面持久化为提交记录，但不写入 meta。
+// 之后事务只能 Commit 或 Abort：Commit 写入 meta 并删除提交记录，Abort 删除提交记录。
+// Prepare 失败时事务被回滚。
+func (t *transaction) Prepare(gid string) error {
+	if t.flags&txnFinished != 0 {
+		return BadTransactionError
+	}
+	if !t.writable() {
+		return ReadOnlyError
+	}
+	if t.prepared != nil {
+		return PreparedError
+	}
+	if gid == "" || strings.ContainsRune(gid, '\n') {
+		return InvalidArgumentError
+	}
+	if err := t.prepare(gid); err != nil {
+		t.Abort()
+		return err
+	}
+	return nil
+}
+
+func (t *transaction) prepare(gid string) error {
+	if err := t.spillBuckets(); err != nil {
+		return err
+	}
+	if err := t.saveFreeList(); err != nil {
+		return err
+	}
+	if err := t.flush(false); err != nil {
+		return err
+	}
+	// 即使设置了 NoSync，已 Prepare 的数据也必须持久化。
+	if err := t.db.sync(true); err != nil {
+		return err
+	}
+	pt := &preparedTxn{gid: gid, meta: t.metaPage()}
+	if err := t.db.writePrepared(pt); err != nil {
+		return err
+	}
+	t.prepared = pt
+	return nil
+}
+
+// InDoubt 返回打开数据库时发现的、结果未决的已 Prepare 事务的全局事务ID。
+// 存在未决事务时不能开始写事务，直到通过 Coordinator.Recover 或 Resolve 决定其结果。
+func (db *DB) InDoubt() (gid string, ok bool) {
+	db.Lock()
+	defer db.Unlock()
+	if db.prepared == nil {
+		return "", false
+	}
+	return db.prepared.gid, true
+}
+
+// Resolve 决定未决事务的结果：commit 为 true 时写入其 meta 使其生效，否则丢弃该事务。
+// 没有未决事务或 gid 不匹配时返回 InvalidArgumentError。
+func (db *DB) Resolve(gid string, commit bool) error {
+	if err := db.lockWriter(context.Background(), db.timeout); err != nil {
+		return err
+	}
+	defer db.unlockWriter()
+	db.Lock()
+	defer db.Unlock()
+	if db.prepared == nil || db.prepared.gid != gid {
+		return InvalidArgumentError
+	}
+	if commit {
+		if err := db.writeMeta(db.prepared.meta); err != nil {
+			return err
+		}
+	}
+	db.clearPrepared()
+	db.prepared = nil
+	return nil
+}
+
+// writeMeta 将 meta 页面写入较旧的 meta 位置，使其成为新的有效 meta。
+func (db *DB) writeMeta(buf []byte) error {
+	toggle := 1 - db.pickMeta()
+	db.page(buf, 0).id = pgno(toggle)
+	_, err := db.metafile.WriteAt(buf, int64(toggle*db.pageSize))
+	return err
+}
+
+// preparedPath 返回数据库的提交记录文件路径。
+func (db *DB) preparedPath() string {
+	return db.path + "-prepared"
+}
+
+// writePrepared 原子地写入提交记录：全局事务ID一行，之后是 meta 页面。
+func (db *DB) writePrepared(pt *preparedTxn) error {
+	var buf bytes.Buffer
+	buf.WriteString(pt.gid)
+	buf.WriteByte('\n')
+	buf.Write(pt.meta)
+	return writeFileSync(db.preparedPath(), buf.Bytes())
+}
+
+// clearPrepared 删除提交记录。
+func (db *DB) clearPrepared() {
+	if err := os.Remove(db.preparedPath()); err != nil && !os.IsNotExist(err) {
+		warnf("boltdb: remove prepared record: %v\n", err)
+	}
+}
+
+// loadPrepared 在打开数据库时读取提交记录。
+// 记录中的事务已经生效时（写入 meta 之后、删除记录之前崩溃）直接删除记录。
+func (db *DB) loadPrepared() error {
+	b, err := os.ReadFile(db.preparedPath())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	i := bytes.IndexByte(b, '\n')
+	if i <= 0 || len(b)-i-1 != db.pageSize {
+		return InvalidError
+	}
+	pt := &preparedTxn{gid: string(b[:i]), meta: b[i+1:]}
+	m, err := db.page(pt.meta, 0).meta()
+	if err != nil {
+		return err
+	}
+	if m.txnid <= db.meta().txnid {
+		// 只读打开时不修改文件，记录留给下一次读写打开时删除。
+		if !db.readOnly {
+			db.clearPrepared()
+		}
+		return nil
+	}
+	db.prepared = pt
+	return nil
+}
+
+// Coordinator 协调多个数据库上的两阶段提交。
+// 所有事务 Prepare 成功后，提交的决定先被持久化到日志文件，然后逐个提交；
+// 若在此过程中崩溃，重新打开数据库后调用 Recover 根据日志完成或回滚未决事务。
+// 同一个日志文件只能由一个 Coordinator 使用。
+type Coordinator struct {
+	sync.Mutex
+	path string
+	seq  uint64
+}
+
+// NewCoordinator 创建一个使用 path 作为决定日志的协调者。
+func NewCoordinator(path string) *Coordinator {
+	return &Coordinator{path: path}
+}
+
+// Commit 原子地提交多个数据库上的写事务：要么全部生效，要么全部回滚。
+// 任一事务 Prepare 失败时所有事务被回滚；决定提交之后的失败由 Recover 完成。
+// 决定日志已经存在时，其中的决定尚未恢复，不能被覆盖，所有事务被回滚并返回 DecisionPendingError。
+func (c *Coordinator) Commit(txns ...*transaction) error {
+	c.Lock()
+	defer c.Unlock()
+	c.seq++
+	gid := fmt.Sprintf("%d-%d-%d", os.Getpid(), time.Now().UnixNano(), c.seq)
+
+	abort := func() {
+		for _, t := range txns {
+			t.Abort()
+		}
+	}
+	if _, err := os.Lstat(c.path); err == nil {
+		abort()
+		return DecisionPendingError
+	} else if !os.IsNotExist(err) {
+		abort()
+		return err
+	}
+	// 决定日志在 gid 之后逐行记录参与者的绝对路径，Recover 据此确认所有参与者都已恢复。
+	log := gid + "\n"
+	for _, t := range txns {
+		path, err := filepath.Abs(t.db.path)
+		if err != nil {
+			abort()
+			return err
+		}
+		log += path + "\n"
+	}
+	for _, t := range txns {
+		if err := t.Prepare(gid); err != nil {
+			abort()
+			return err
+		}
+	}
+	if err := createFileSync(c.path, []byte(log)); err != nil {
+		abort()
+		if os.IsExist(err) {
+			return DecisionPendingError
+		}
+		return err
+	}
+
+	var err error
+	for _, t := range txns {
+		if e := t.Commit(); e != nil && err == nil {
+			err = e
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(c.path)
+}
+
+// Recover 根据决定日志处理数据库中的未决事务：日志中记录了提交决定的事务被提交，
+// 其余的未决事务被回滚。某个数据库处理失败时仍然继续处理其余的数据库，并返回第一个错误。
+// 只有日志中记录的所有参与者都不再有该决定的提交记录时才删除日志；
+// 有参与者没有传入且仍未恢复时保留日志并返回 ParticipantPendingError，之后可以再次调用 Recover。
+func (c *Coordinator) Recover(dbs ...*DB) error {
+	c.Lock()
+	defer c.Unlock()
+	decided, participants, err := c.decided()
+	if err != nil {
+		return err
+	}
+	for _, db := range dbs {
+		gid, ok := db.InDoubt()
+		if !ok {
+			continue
+		}
+		if e := db.Resolve(gid, gid == decided); e != nil && err == nil {
+			err = e
+		}
+	}
+	if err != nil {
+		return err
+	}
+	for _, path := range participants {
+		gid, err := preparedGID(path + "-prepared")
+		if err != nil {
+			return err
+		}
+		if gid == decided {
+			return ParticipantPendingError
+		}
+	}
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// decided 返回决定日志中记录的全局事务ID和参与者路径，没有日志时返回空字符串。
+func (c *Coordinator) decided() (string, []string, error) {
+	f, err := os.Open(c.path)
+	if os.IsNotExist(err) {
+		return "", nil, nil
+	} else if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	if !s.Scan() {
+		return "", nil, s.Err()
+	}
+	gid := s.Text()
+	var participants []string
+	for s.Scan() {
+		participants = append(participants, s.Text())
+	}
+	return gid, participants, s.Err()
+}
+
+// preparedGID 返回提交记录文件中的全局事务ID，文件不存在时返回空字符串。
+func preparedGID(path string) (string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	gid, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil {
+		return "", InvalidError
+	}
+	return gid[:len(gid)-1], nil
+}
+
+// writeFileSync 通过临时文件和重命名原子地写入文件，并同步文件及其所在目录。
+func writeFileSync(path string, b []byte) error {
+	return writeFile(path, b, os.Rename)
+}
+
+// createFileSync 与 writeFileSync 相同，但文件已经存在时不覆盖它，返回的错误满足 os.IsExist。
+func createFileSync(path string, b []byte) error {
+	return writeFile(path, b, os.Link)
+}
+
+// writeFile 把 b 写入临时文件并同步，再通过 install 将其安装为 path，最后同步所在目录。
+func writeFile(path string, b []byte, install func(oldpath, newpath string) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := install(f.Name(), path); err != nil {
+		return err
+	}
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package boltdb_go
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 确保 Coordinator 在所有数据库上提交事务，并清理提交记录和决定日志。
+func TestCoordinator_Commit(t *testing.T) {
+	WithDB(func(db0 *DB, path0 string) {
+		WithDB(func(db1 *DB, path1 string) {
+			assert.NoError(t, db0.Open(path0, 0666))
+			assert.NoError(t, db1.Open(path1, 0666))
+			c := NewCoordinator(path0 + "-coordinator")
+
+			t0, _ := db0.Transaction(nil, 0)
+			t1, _ := db1.Transaction(nil, 0)
+			assert.NoError(t, c.Commit(t0, t1))
+			assert.Equal(t, t0.id, db0.meta().txnid)
+			assert.Equal(t, t1.id, db1.meta().txnid)
+			assert.NoFileExists(t, db0.preparedPath())
+			assert.NoFileExists(t, c.path)
+
+			// 已 Prepare 的事务不能再修改，Abort 后不留下提交记录。
+			txn, _ := db0.Transaction(nil, 0)
+			assert.NoError(t, txn.Prepare("g"))
+			assert.FileExists(t, db0.preparedPath())
+			assert.Equal(t, PreparedError, txn.Prepare("g"))
+			txn.Abort()
+			assert.NoFileExists(t, db0.preparedPath())
+		})
+	})
+}
+
+// 确保 Prepare 之后崩溃的事务在重新打开后处于未决状态，并按照决定日志提交或回滚。
+func TestCoordinator_Recover(t *testing.T) {
+	for _, commit := range []bool{true, false} {
+		WithDB(func(db *DB, path string) {
+			assert.NoError(t, db.Open(path, 0666))
+			c := NewCoordinator(path + "-coordinator")
+			txn, _ := db.Transaction(nil, 0)
+			id := txn.id
+			assert.NoError(t, txn.Prepare("g1"))
+			if commit {
+				assert.NoError(t, writeFileSync(c.path, []byte("g1\n")))
+			}
+			// 关闭文件模拟崩溃，提交记录保留在磁盘上。
+			db.close()
+
+			db = NewDB()
+			assert.NoError(t, db.Open(path, 0666))
+			gid, ok := db.InDoubt()
+			assert.True(t, ok)
+			assert.Equal(t, "g1", gid)
+			_, err := db.Transaction(nil, 0)
+			assert.Equal(t, InDoubtError, err)
+
+			assert.NoError(t, c.Recover(db))
+			_, ok = db.InDoubt()
+			assert.False(t, ok)
+			if commit {
+				assert.Equal(t, id, db.meta().txnid)
+			} else {
+				assert.Equal(t, id-1, db.meta().txnid)
+			}
+			_, err = os.Stat(db.preparedPath())
+			assert.True(t, os.IsNotExist(err))
+
+			txn, err = db.Transaction(nil, 0)
+			assert.NoError(t, err)
+			txn.Abort()
+		})
+	}
+}
+
+// 确保决定日志存在时 Commit 不覆盖它，而是回滚所有事务。
+func TestCoordinator_CommitPendingDecision(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		c := NewCoordinator(path + "-coordinator")
+		assert.NoError(t, writeFileSync(c.path, []byte("g1\n")))
+
+		txn, _ := db.Transaction(nil, 0)
+		id := txn.id
+		assert.Equal(t, DecisionPendingError, c.Commit(txn))
+		assert.Equal(t, id-1, db.meta().txnid)
+		assert.NoFileExists(t, db.preparedPath())
+		decided, _, err := c.decided()
+		assert.NoError(t, err)
+		assert.Equal(t, "g1", decided)
+	})
+}
+
+// 确保 Recover 在一个数据库处理失败后仍然处理其余的数据库，并保留决定日志以便重试。
+func TestCoordinator_RecoverPartial(t *testing.T) {
+	WithDB(func(db0 *DB, path0 string) {
+		WithDB(func(db1 *DB, path1 string) {
+			assert.NoError(t, db0.Open(path0, 0666))
+			assert.NoError(t, db1.Open(path1, 0666))
+			c := NewCoordinator(path0 + "-coordinator")
+			t0, _ := db0.Transaction(nil, 0)
+			t1, _ := db1.Transaction(nil, 0)
+			assert.NoError(t, t0.Prepare("g1"))
+			assert.NoError(t, t1.Prepare("g1"))
+			assert.NoError(t, writeFileSync(c.path, []byte("g1\n")))
+			db0.close()
+			db1.close()
+
+			db0, db1 = NewDB(), NewDB()
+			assert.NoError(t, db0.Open(path0, 0666))
+			assert.NoError(t, db1.Open(path1, 0666))
+			defer db0.Close()
+			defer db1.Close()
+
+			// 通过另一个文件描述符持有 db0 的文件锁，使其无法恢复。
+			f, err := os.Open(path0)
+			assert.NoError(t, err)
+			defer f.Close()
+			assert.NoError(t, syscall.Flock(int(f.Fd()), syscall.LOCK_EX))
+			assert.NoError(t, db0.SetLockTimeout(10*time.Millisecond))
+			assert.Equal(t, WriterBusyError, c.Recover(db0, db1))
+			_, ok := db0.InDoubt()
+			assert.True(t, ok)
+			_, ok = db1.InDoubt()
+			assert.False(t, ok)
+			assert.Equal(t, t1.id, db1.meta().txnid)
+			assert.FileExists(t, c.path)
+
+			assert.NoError(t, syscall.Flock(int(f.Fd()), syscall.LOCK_UN))
+			assert.NoError(t, c.Recover(db0, db1))
+			assert.Equal(t, t0.id, db0.meta().txnid)
+			assert.NoFileExists(t, c.path)
+		})
+	})
+}
+
+// 确保 Recover 在决定日志记录的参与者没有全部恢复之前不删除日志，遗漏的参与者之后仍按决定提交。
+func TestCoordinator_RecoverMissingParticipant(t *testing.T) {
+	WithDB(func(db0 *DB, path0 string) {
+		WithDB(func(db1 *DB, path1 string) {
+			assert.NoError(t, db0.Open(path0, 0666))
+			assert.NoError(t, db1.Open(path1, 0666))
+			c := NewCoordinator(path0 + "-coordinator")
+			t0, _ := db0.Transaction(nil, 0)
+			t1, _ := db1.Transaction(nil, 0)
+			// 在写入决定日志之后、提交之前崩溃。
+			assert.NoError(t, t0.Prepare("g1"))
+			assert.NoError(t, t1.Prepare("g1"))
+			abs0, _ := filepath.Abs(path0)
+			abs1, _ := filepath.Abs(path1)
+			assert.NoError(t, writeFileSync(c.path, []byte("g1\n"+abs0+"\n"+abs1+"\n")))
+			db0.close()
+			db1.close()
+
+			db0, db1 = NewDB(), NewDB()
+			assert.NoError(t, db0.Open(path0, 0666))
+			assert.NoError(t, db1.Open(path1, 0666))
+			defer db0.Close()
+			defer db1.Close()
+
+			assert.Equal(t, ParticipantPendingError, c.Recover(db1))
+			assert.Equal(t, t1.id, db1.meta().txnid)
+			assert.FileExists(t, c.path)
+
+			assert.NoError(t, c.Recover(db0))
+			assert.Equal(t, t0.id, db0.meta().txnid)
+			assert.NoFileExists(t, c.path)
+		})
+	})
+}
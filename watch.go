@@ -0,0 +1,161 @@
+package boltdb_go
+
+import (
+	"bytes"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Op 表示变更事件的操作类型。
+type Op int
+
+const (
+	// OpPut 表示写入了一个键值对。
+	OpPut Op = iota + 1
+	// OpDelete 表示删除了一个键，或 DupSort 存储桶中键的一个重复值。
+	OpDelete
+)
+
+// String 返回操作类型的名称。
+func (op Op) String() string {
+	switch op {
+	case OpPut:
+		return "put"
+	case OpDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// Event 是一次已提交的写操作。
+type Event struct {
+	Path  []string // 存储桶从顶层开始的路径，主存储桶为空
+	Key   []byte   // 被修改的键
+	Op    Op       // 操作类型
+	Value []byte   // 写入的值，WatchOptions.Values 为 false 或使用 Reserve 写入时为 nil；删除单个重复值时为被删除的值
+	TxnID int      // 提交该修改的事务ID
+}
+
+// WatchOptions 定义订阅的范围以及订阅者处理过慢时的行为。
+type WatchOptions struct {
+	Path   []string // 订阅的存储桶路径，为空时订阅主存储桶
+	Prefix []byte   // 只订阅以 Prefix 开头的键
+	Values bool     // 事件中是否包含写入的值
+	Buffer int      // 事件通道的缓冲区大小
+	// Drop 为 true 时，缓冲区已满的事件被丢弃并计入 Dropped；
+	// 为 false 时，提交会等待订阅者取走事件，从而对写事务形成反压。
+	Drop bool
+}
+
+// Watcher 是一个变更订阅，事件按提交顺序从 C 中送达。
+type Watcher struct {
+	C       <-chan Event
+	c       chan Event
+	db      *DB
+	opts    WatchOptions
+	done    chan struct{}
+	once    sync.Once
+	dropped atomic.Uint64
+}
+
+// Watch 订阅已提交的修改，调用方不再需要时必须调用 Close。
+func (db *DB) Watch(opts WatchOptions) *Watcher {
+	c := make(chan Event, opts.Buffer)
+	w := &Watcher{C: c, c: c, db: db, opts: opts, done: make(chan struct{})}
+	db.wmutex.Lock()
+	db.watchers = append(db.watchers, w)
+	db.wmutex.Unlock()
+	return w
+}
+
+// WatchFunc 订阅已提交的修改，并在一个单独的 goroutine 中按提交顺序对每个事件调用 fn。
+// 非 Drop 模式下 fn 不能等待写事务，否则会与等待事件送达的提交相互等待。
+func (db *DB) WatchFunc(opts WatchOptions, fn func(Event)) *Watcher {
+	w := db.Watch(opts)
+	go func() {
+		for e := range w.C {
+			fn(e)
+		}
+	}()
+	return w
+}
+
+// Dropped 返回因订阅者处理过慢而被丢弃的事件数量。
+func (w *Watcher) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Close 取消订阅并关闭事件通道。
+func (w *Watcher) Close() {
+	w.once.Do(func() {
+		// 先通知可能正在等待的提交，再在持有锁的情况下移除订阅，保证关闭通道时没有发送者。
+		close(w.done)
+		w.db.wmutex.Lock()
+		defer w.db.wmutex.Unlock()
+		w.db.watchers = slices.DeleteFunc(w.db.watchers, func(x *Watcher) bool { return x == w })
+		close(w.c)
+	})
+}
+
+// match 返回事件是否在订阅范围内。
+func (w *Watcher) match(e *Event) bool {
+	return slices.Equal(w.opts.Path, e.Path) && bytes.HasPrefix(e.Key, w.opts.Prefix)
+}
+
+// send 将事件发送给订阅者。
+func (w *Watcher) send(e Event) {
+	if !w.opts.Values {
+		e.Value = nil
+	}
+	if w.opts.Drop {
+		select {
+		case w.c <- e:
+		default:
+			w.dropped.Add(1)
+		}
+		return
+	}
+	select {
+	case w.c <- e:
+	case <-w.done:
+	}
+}
+
+// watching 返回当前是否存在订阅者，没有订阅者时事务无需记录修改。
+func (db *DB) watching() bool {
+	db.wmutex.Lock()
+	defer db.wmutex.Unlock()
+	return len(db.watchers) > 0
+}
+
+// publish 将事务中的修改按顺序发送给所有匹配的订阅者。
+// publish 在写锁释放之前调用，因此事件的顺序与提交的顺序一致。
+func (db *DB) publish(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+	db.wmutex.Lock()
+	defer db.wmutex.Unlock()
+	for i := range events {
+		for _, w := range db.watchers {
+			if w.match(&events[i]) {
+				w.send(events[i])
+			}
+		}
+	}
+}
+
+// record 记录写事务中的一次修改，修改在事务提交后发布给订阅者。
+func (t *transaction) record(b *Bucket, op Op, key []byte, value []byte) {
+	if !t.watching || b.id == freeBucket {
+		return
+	}
+	t.events = append(t.events, Event{
+		Path:  b.path(),
+		Key:   bytes.Clone(key),
+		Op:    op,
+		Value: bytes.Clone(value),
+		TxnID: t.id,
+	})
+}
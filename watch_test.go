@@ -0,0 +1,127 @@
+package boltdb_go
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 确保订阅者按提交顺序收到匹配前缀的事件，回滚的修改不会被发布。
+func TestDB_Watch(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		w := db.Watch(WatchOptions{Prefix: []byte("user/"), Values: true, Buffer: 16})
+		defer w.Close()
+
+		txn, _ := db.Transaction(nil, 0)
+		b, _ := txn.Bucket("", 0)
+		assert.NoError(t, b.Put([]byte("user/1"), []byte("alice"), 0))
+		assert.NoError(t, b.Put([]byte("order/1"), []byte("book"), 0))
+		assert.NoError(t, b.Put([]byte("user/2"), []byte("bob"), 0))
+		txn.Abort()
+
+		txn, _ = db.Transaction(nil, 0)
+		b, _ = txn.Bucket("", 0)
+		assert.NoError(t, b.Put([]byte("user/3"), []byte("carol"), 0))
+		assert.NoError(t, b.Put([]byte("user/4"), []byte("dave"), 0))
+		id := txn.id
+		assert.NoError(t, txn.Commit())
+
+		assert.Equal(t, Event{Key: []byte("user/3"), Op: OpPut, Value: []byte("carol"), TxnID: id}, <-w.C)
+		assert.Equal(t, Event{Key: []byte("user/4"), Op: OpPut, Value: []byte("dave"), TxnID: id}, <-w.C)
+		assert.Empty(t, w.C)
+	})
+}
+
+// 确保 Drop 模式下缓冲区已满的事件被丢弃而不阻塞提交。
+func TestDB_WatchDrop(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		w := db.Watch(WatchOptions{Buffer: 1, Drop: true})
+
+		txn, _ := db.Transaction(nil, 0)
+		b, _ := txn.Bucket("", 0)
+		for _, k := range []string{"a", "b", "c"} {
+			assert.NoError(t, b.Put([]byte(k), []byte(k), 0))
+		}
+		assert.NoError(t, txn.Commit())
+
+		e := <-w.C
+		assert.Equal(t, []byte("a"), e.Key)
+		assert.Nil(t, e.Value)
+		assert.Equal(t, uint64(2), w.Dropped())
+
+		w.Close()
+		_, ok := <-w.C
+		assert.False(t, ok)
+	})
+}
+
+// 确保 DupSort 存储桶中每次修改只产生一个事件，按事件重放的结果与读回的数据一致。
+func TestDB_WatchDups(t *testing.T) {
+	WithDB(func(db *DB, path string) {
+		assert.NoError(t, db.Open(path, 0666))
+		txn, _ := db.Transaction(nil, 0)
+		main, _ := txn.Bucket("", 0)
+		_, err := main.CreateBucket("dups", DupSort)
+		assert.NoError(t, err)
+		assert.NoError(t, txn.Commit())
+
+		w := db.Watch(WatchOptions{Path: []string{"dups"}, Values: true, Buffer: 64})
+		defer w.Close()
+		txn, _ = db.Transaction(nil, 0)
+		b, _ := txn.Bucket("dups", 0)
+		for _, kv := range [][2]string{{"a", "1"}, {"a", "2"}, {"a", "3"}, {"a", "2"}, {"b", "1"}, {"c", "1"}, {"c", "2"}} {
+			assert.NoError(t, b.Put([]byte(kv[0]), []byte(kv[1]), 0))
+		}
+		assert.NoError(t, b.Delete([]byte("a"), []byte("1")))
+		assert.NoError(t, b.Delete([]byte("c"), nil))
+		c, _ := b.Cursor()
+		_, _, err = c.Set([]byte("a"))
+		assert.NoError(t, err)
+		assert.NoError(t, c.Del(0))
+		c.Close()
+		assert.NoError(t, txn.Commit())
+
+		var events []string
+		model := map[string][]string{}
+		for range 9 {
+			e := <-w.C
+			assert.Equal(t, []string{"dups"}, e.Path)
+			k, v := string(e.Key), string(e.Value)
+			switch {
+			case e.Op == OpPut:
+				events = append(events, "put "+k+"="+v)
+				model[k] = append(model[k], v)
+				slices.Sort(model[k])
+			case e.Value != nil:
+				events = append(events, "del "+k+"="+v)
+				model[k] = slices.DeleteFunc(model[k], func(s string) bool { return s == v })
+			default:
+				events = append(events, "del "+k)
+				delete(model, k)
+			}
+		}
+		assert.Equal(t, []string{
+			"put a=1", "put a=2", "put a=3", "put b=1", "put c=1", "put c=2",
+			"del a=1", "del c", "del a=2",
+		}, events)
+		assert.Empty(t, w.C)
+
+		txn, _ = db.Transaction(nil, ReadOnly)
+		defer txn.Abort()
+		b, _ = txn.Bucket("dups", 0)
+		got := map[string][]string{}
+		for k, v := range b.Iterator().All() {
+			got[string(k)] = append(got[string(k)], string(v))
+		}
+		for k, vs := range model {
+			if len(vs) == 0 {
+				delete(model, k)
+			}
+		}
+		assert.Equal(t, model, got)
+		assert.Equal(t, map[string][]string{"a": {"3"}, "b": {"1"}}, got)
+	})
+}